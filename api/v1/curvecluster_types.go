@@ -17,6 +17,7 @@ limitations under the License.
 package v1
 
 import (
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
@@ -40,6 +41,15 @@ type CurveClusterSpec struct {
 	Chunkserver *StorageScopeSpec `json:"chunkserver,omitempty"`
 	// +optional
 	SnapShotClone *SnapShotCloneSpec `json:"snapshotclone,omitempty"`
+	// CSI deploys the Curve CSI driver so that the PVCs are provisioned as the volumes of cluster
+	// +optional
+	CSI *CSISpec `json:"csi,omitempty"`
+	// JobResources are the compute resources of the containers of the jobs that the operator runs, such as
+	// formatting the devices, registering the pools and cleaning up the cluster
+	// +optional
+	JobResources corev1.ResourceRequirements `json:"jobResources,omitempty"`
+	// +optional
+	Monitor *MonitorSpec `json:"monitor,omitempty"`
 }
 
 // CurveClusterStatus defines the observed state of CurveCluster
@@ -52,8 +62,19 @@ type CurveClusterStatus struct {
 	// Message shows summary message of cluster from ClusterState
 	// such as 'Curve Cluster Created successfully'
 	Message string `json:"message,omitempty"`
-	// CurveVersion shows curve version info on status field
+	// CurveVersion shows curve version info on status field,
+	// it's the version of the running services and updated only after an upgrade succeeds
 	CurveVersion CurveVersionSpec `json:"curveVersion,omitempty"`
+	// UpgradeHistory records the recent upgrades of the cluster, the last one is the latest
+	UpgradeHistory []UpgradeRecord `json:"upgradeHistory,omitempty"`
+	// UUID is the unique identity of the cluster, it's generated once the cluster is accepted by operator
+	UUID string `json:"uuid,omitempty"`
+	// Progress shows the reconcile step that the operator is waiting for
+	Progress ProgressStatus `json:"progress,omitempty"`
+	// FormatStatus shows the progress to format the chunkfilepool on every device of chunkservers
+	FormatStatus []FormatStatus `json:"formatStatus,omitempty"`
+	// LastModContextSet means that need to modify operatrion context
+	LastModContextSet LastModContextSet `json:"lastModContextSet,omitempty"`
 }
 
 // +kubebuilder:object:root=true
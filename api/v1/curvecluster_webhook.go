@@ -17,7 +17,9 @@ limitations under the License.
 package v1
 
 import (
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation/field"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
@@ -46,7 +48,17 @@ func (r *CurveCluster) Default() {
 		Namespace: r.Namespace,
 	})
 
-	// TODO(user): fill in your defaulting logic.
+	setDefaultCurveVersion(&r.Spec.CurveVersion)
+	setDefaultString(&r.Spec.DataDir, DefaultCurveBSDataDir)
+	setDefaultString(&r.Spec.LogDir, DefaultCurveBSLogDir)
+	setDefaultInt(&r.Spec.Copysets, DefaultCopysets)
+	r.Spec.Etcd = defaultEtcdSpec(r.Spec.Etcd)
+	r.Spec.Mds = defaultMdsSpec(r.Spec.Mds)
+	r.Spec.Chunkserver = defaultChunkserverSpec(r.Spec.Chunkserver)
+	r.Spec.SnapShotClone = defaultSnapShotCloneSpec(r.Spec.SnapShotClone)
+	defaultS3ConfigSpec(&r.Spec.SnapShotClone.S3Config, r.Spec.Nodes, r.Spec.DataDir)
+	defaultCSISpec(r.Spec.CSI, r.Name, DefaultCurveBSCSIImage, DefaultCurveBSCSIDriverName)
+	r.Spec.Monitor = defaultMonitorSpec(r.Spec.Monitor, r.Spec.Nodes, r.Spec.DataDir)
 }
 
 // TODO(user): change verbs to "verbs=create;update;delete" if you want to enable deletion validation.
@@ -56,33 +68,114 @@ var _ webhook.Validator = &CurveCluster{}
 
 // ValidateCreate implements webhook.Validator so a webhook will be registered for the type
 func (r *CurveCluster) ValidateCreate() error {
-	curvefslog.Info("validating creation of CurveCluster", "CurveCluster", client.ObjectKey{
+	curveclusterlog.Info("validating creation of CurveCluster", "CurveCluster", client.ObjectKey{
 		Name:      r.Name,
 		Namespace: r.Namespace,
 	})
 
-	// TODO(user): fill in your validation logic upon object creation.
-	return nil
+	return r.toInvalidError(r.validateSpec())
 }
 
 // ValidateUpdate implements webhook.Validator so a webhook will be registered for the type
 func (r *CurveCluster) ValidateUpdate(old runtime.Object) error {
-	curvefslog.Info("validating update of CurveCluster", "CurveCluster", client.ObjectKey{
+	curveclusterlog.Info("validating update of CurveCluster", "CurveCluster", client.ObjectKey{
 		Name:      r.Name,
 		Namespace: r.Namespace,
 	})
 
-	// TODO(user): fill in your validation logic upon object update.
-	return nil
+	allErrs := r.validateSpec()
+	oldCluster, ok := old.(*CurveCluster)
+	if !ok || !isClusterCreated(oldCluster.Status.Phase) {
+		return r.toInvalidError(allErrs)
+	}
+
+	specPath := field.NewPath("spec")
+	allErrs = append(allErrs, validateNodesUpdate(r.Spec.Nodes, oldCluster.Spec.Nodes, specPath.Child("nodes"))...)
+	allErrs = append(allErrs, validateNodesScale(r.Spec.Nodes, oldCluster.Spec.Nodes, specPath.Child("nodes"))...)
+	allErrs = append(allErrs, validateImmutableDir(r.Spec.DataDir, oldCluster.Spec.DataDir, specPath.Child("dataDir"))...)
+	allErrs = append(allErrs, validateImmutableDir(r.Spec.LogDir, oldCluster.Spec.LogDir, specPath.Child("logDir"))...)
+	if r.Spec.Chunkserver != nil && oldCluster.Spec.Chunkserver != nil {
+		allErrs = append(allErrs, validateChunkserverStorageUpdate(r.Spec.Chunkserver, oldCluster.Spec.Chunkserver,
+			specPath.Child("chunkserver"))...)
+	}
+
+	return r.toInvalidError(allErrs)
 }
 
 // ValidateDelete implements webhook.Validator so a webhook will be registered for the type
 func (r *CurveCluster) ValidateDelete() error {
-	curvefslog.Info("validating deletion of CurveCluster", "CurveCluster", client.ObjectKey{
+	curveclusterlog.Info("validating deletion of CurveCluster", "CurveCluster", client.ObjectKey{
 		Name:      r.Name,
 		Namespace: r.Namespace,
 	})
 
-	// TODO(user): fill in your validation logic upon object deletion.
+	// the cluster is always allowed to be deleted, the finalizer cleans up the hosts.
 	return nil
 }
+
+// validateSpec checks the spec that all services need to be deployed
+func (r *CurveCluster) validateSpec() field.ErrorList {
+	allErrs := field.ErrorList{}
+	specPath := field.NewPath("spec")
+	spec := r.Spec
+
+	allErrs = append(allErrs, validateNodes(spec.Nodes, specPath.Child("nodes"))...)
+	allErrs = append(allErrs, validateCopysets(spec.Copysets, specPath.Child("copysets"))...)
+
+	ports := newHostPorts()
+	if spec.Etcd == nil {
+		allErrs = append(allErrs, field.Required(specPath.Child("etcd"), "etcd must be specified"))
+	} else {
+		ports.addEtcd(spec.Etcd, spec.Nodes, specPath.Child("etcd"))
+		allErrs = append(allErrs, validateResources(spec.Etcd.Resources, specPath.Child("etcd", "resources"))...)
+		allErrs = append(allErrs, validatePlacement(spec.Etcd.Placement, specPath.Child("etcd", "placement"))...)
+	}
+	if spec.Mds == nil {
+		allErrs = append(allErrs, field.Required(specPath.Child("mds"), "mds must be specified"))
+	} else {
+		ports.addMds(spec.Mds, spec.Nodes, specPath.Child("mds"))
+		allErrs = append(allErrs, validateResources(spec.Mds.Resources, specPath.Child("mds", "resources"))...)
+		allErrs = append(allErrs, validatePlacement(spec.Mds.Placement, specPath.Child("mds", "placement"))...)
+	}
+	if spec.Chunkserver == nil {
+		allErrs = append(allErrs, field.Required(specPath.Child("chunkserver"), "chunkserver must be specified"))
+	} else {
+		chunkserverPath := specPath.Child("chunkserver")
+		allErrs = append(allErrs, validateInstances(spec.Chunkserver.Instances, chunkserverPath.Child("instances"))...)
+		allErrs = append(allErrs, validateChunkserverStorage(spec.Chunkserver, chunkserverPath)...)
+		ports.add(spec.Chunkserver.Port, chunkserverInstances(spec.Chunkserver), chunkserverPath.Child("port"))
+		allErrs = append(allErrs, validateResources(spec.Chunkserver.Resources, chunkserverPath.Child("resources"))...)
+		allErrs = append(allErrs, validatePlacement(spec.Chunkserver.Placement, chunkserverPath.Child("placement"))...)
+	}
+	if spec.SnapShotClone != nil && spec.SnapShotClone.Enable {
+		snapPath := specPath.Child("snapshotclone")
+		instances := replicatedRoleInstances(spec.Nodes)
+		ports.add(spec.SnapShotClone.Port, instances, snapPath.Child("port"))
+		ports.add(spec.SnapShotClone.DummyPort, instances, snapPath.Child("dummyPort"))
+		ports.add(spec.SnapShotClone.ProxyPort, instances, snapPath.Child("proxyPort"))
+		allErrs = append(allErrs, validateS3Config(&spec.SnapShotClone.S3Config, snapPath.Child("s3"))...)
+		ports.addEmbeddedS3(&spec.SnapShotClone.S3Config, snapPath.Child("s3"))
+		allErrs = append(allErrs, validateResources(spec.SnapShotClone.Resources, snapPath.Child("resources"))...)
+		allErrs = append(allErrs, validatePlacement(spec.SnapShotClone.Placement, snapPath.Child("placement"))...)
+	}
+	if spec.CSI != nil && spec.CSI.Enable {
+		allErrs = append(allErrs, validateCSI(spec.CSI, specPath.Child("csi"))...)
+	}
+	if spec.Monitor != nil && spec.Monitor.Enable {
+		monitorPath := specPath.Child("monitor")
+		allErrs = append(allErrs, validateMonitor(spec.Monitor, monitorPath)...)
+		ports.addMonitor(spec.Monitor, monitorPath)
+	}
+
+	allErrs = append(allErrs, validateResources(spec.JobResources, specPath.Child("jobResources"))...)
+
+	return append(allErrs, ports.errs...)
+}
+
+// toInvalidError converts the field errors to an Invalid error of CurveCluster
+func (r *CurveCluster) toInvalidError(allErrs field.ErrorList) error {
+	if len(allErrs) == 0 {
+		return nil
+	}
+	return apierrors.NewInvalid(GroupVersion.WithKind("CurveCluster").GroupKind(), r.Name, allErrs)
+}
@@ -17,6 +17,7 @@ limitations under the License.
 package v1
 
 import (
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
@@ -38,6 +39,50 @@ type CurvefsSpec struct {
 	Mds *MdsSpec `json:"mds,omitempty"`
 	// +optional
 	MetaServer *MetaServerSpec `json:"metaserver,omitempty"`
+	// S3 is the S3 service that stores the data of the file systems
+	// +optional
+	S3 *S3ConfigSpec `json:"s3,omitempty"`
+	// Fuse mounts the file systems on the nodes for the workloads to consume
+	// +optional
+	Fuse *FuseSpec `json:"fuse,omitempty"`
+	// CSI deploys the curvefs CSI driver so that the PVCs are provisioned as the file systems of cluster
+	// +optional
+	CSI *CSISpec `json:"csi,omitempty"`
+	// JobResources are the compute resources of the containers of the jobs that the operator runs, such as
+	// formatting the devices, registering the pools and cleaning up the cluster
+	// +optional
+	JobResources corev1.ResourceRequirements `json:"jobResources,omitempty"`
+	// +optional
+	Monitor *MonitorSpec `json:"monitor,omitempty"`
+}
+
+// FuseSpec is the spec of the FUSE clients, the file systems are created in the cluster if they
+// don't exist and mounted on every node by a DaemonSet
+type FuseSpec struct {
+	// +optional
+	Enable bool `json:"enable,omitempty"`
+	// Nodes are the nodes to mount the file systems on, they're the nodes of cluster by default
+	// +optional
+	Nodes []string `json:"nodes,omitempty"`
+	// MountDir is the directory on host, each file system is mounted at <mountDir>/<name>
+	// +optional
+	MountDir string `json:"mountDir,omitempty"`
+	// FileSystems are the names of the file systems to create and mount
+	// +optional
+	FileSystems []string `json:"fileSystems,omitempty"`
+	// Config overrides the items of client.conf, the variables such as ${cluster_mds_addr} are rendered
+	// +optional
+	Config map[string]string `json:"config,omitempty"`
+}
+
+// MountStatus is the health of the mount of a file system on a node
+type MountStatus struct {
+	Node       string `json:"node"`
+	FileSystem string `json:"fileSystem"`
+	Ready      bool   `json:"ready"`
+	// Message is the reason that the mount is not ready
+	// +optional
+	Message string `json:"message,omitempty"`
 }
 
 // CurvefsStatus defines the observed state of Curvefs
@@ -54,12 +99,21 @@ type CurvefsStatus struct {
 	// Message shows summary message of cluster from ClusterState
 	// such as 'Curve Cluster Created successfully'
 	Message string `json:"message,omitempty"`
-	// CurveVersion shows curve version info on status field that judge iff upgrade
+	// CurveVersion shows curve version info on status field that judge iff upgrade,
+	// it's the version of the running services and updated only after an upgrade succeeds
 	CurveVersion CurveVersionSpec `json:"curveVersion,omitempty"`
+	// UpgradeHistory records the recent upgrades of the cluster, the last one is the latest
+	UpgradeHistory []UpgradeRecord `json:"upgradeHistory,omitempty"`
+	// UUID is the unique identity of the cluster, it's generated once the cluster is accepted by operator
+	UUID string `json:"uuid,omitempty"`
+	// Progress shows the reconcile step that the operator is waiting for
+	Progress ProgressStatus `json:"progress,omitempty"`
 	// LastModContextSet means that need to modify operatrion context
 	LastModContextSet LastModContextSet `json:"lastModContextSet,omitempty"`
 	// DataDir and LogDir is to compare and update
 	StorageDir StorageStatusDir `json:"storageStatusDir,omitempty"`
+	// Mounts shows the health of the mounts of the file systems on the nodes
+	Mounts []MountStatus `json:"mounts,omitempty"`
 }
 
 // +kubebuilder:object:root=true
@@ -17,7 +17,9 @@ limitations under the License.
 package v1
 
 import (
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation/field"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
@@ -46,7 +48,17 @@ func (r *Curvefs) Default() {
 		Namespace: r.Namespace,
 	})
 
-	// TODO(user): fill in your defaulting logic.
+	setDefaultCurveVersion(&r.Spec.CurveVersion)
+	setDefaultString(&r.Spec.DataDir, DefaultCurveFSDataDir)
+	setDefaultString(&r.Spec.LogDir, DefaultCurveFSLogDir)
+	setDefaultInt(&r.Spec.Copysets, DefaultCopysets)
+	r.Spec.Etcd = defaultEtcdSpec(r.Spec.Etcd)
+	r.Spec.Mds = defaultMdsSpec(r.Spec.Mds)
+	r.Spec.MetaServer = defaultMetaServerSpec(r.Spec.MetaServer)
+	defaultS3ConfigSpec(r.Spec.S3, r.Spec.Nodes, r.Spec.DataDir)
+	defaultFuseSpec(r.Spec.Fuse, r.Spec.Nodes)
+	defaultCSISpec(r.Spec.CSI, r.Name, DefaultCurveFSCSIImage, DefaultCurveFSCSIDriverName)
+	r.Spec.Monitor = defaultMonitorSpec(r.Spec.Monitor, r.Spec.Nodes, r.Spec.DataDir)
 }
 
 // TODO(user): change verbs to "verbs=create;update;delete" if you want to enable deletion validation.
@@ -61,8 +73,7 @@ func (r *Curvefs) ValidateCreate() error {
 		Namespace: r.Namespace,
 	})
 
-	// TODO(user): fill in your validation logic upon object creation.
-	return nil
+	return r.toInvalidError(r.validateSpec())
 }
 
 // ValidateUpdate implements webhook.Validator so a webhook will be registered for the type
@@ -72,8 +83,24 @@ func (r *Curvefs) ValidateUpdate(old runtime.Object) error {
 		Namespace: r.Namespace,
 	})
 
-	// TODO(user): fill in your validation logic upon object update.
-	return nil
+	allErrs := r.validateSpec()
+	oldCluster, ok := old.(*Curvefs)
+	if !ok || !isClusterCreated(oldCluster.Status.Phase) {
+		return r.toInvalidError(allErrs)
+	}
+
+	specPath := field.NewPath("spec")
+	allErrs = append(allErrs, validateNodesUpdate(r.Spec.Nodes, oldCluster.Spec.Nodes, specPath.Child("nodes"))...)
+	allErrs = append(allErrs, validateNodesScale(r.Spec.Nodes, oldCluster.Spec.Nodes, specPath.Child("nodes"))...)
+	if r.Spec.MetaServer != nil && oldCluster.Spec.MetaServer != nil {
+		allErrs = append(allErrs, validateNoScaleIn(r.Spec.Nodes, oldCluster.Spec.Nodes,
+			r.Spec.MetaServer.Instances, oldCluster.Spec.MetaServer.Instances,
+			specPath.Child("nodes"), specPath.Child("metaserver", "instances"))...)
+	}
+	allErrs = append(allErrs, validateImmutableDir(r.Spec.DataDir, oldCluster.Spec.DataDir, specPath.Child("dataDir"))...)
+	allErrs = append(allErrs, validateImmutableDir(r.Spec.LogDir, oldCluster.Spec.LogDir, specPath.Child("logDir"))...)
+
+	return r.toInvalidError(allErrs)
 }
 
 // ValidateDelete implements webhook.Validator so a webhook will be registered for the type
@@ -83,6 +110,76 @@ func (r *Curvefs) ValidateDelete() error {
 		Namespace: r.Namespace,
 	})
 
-	// TODO(user): fill in your validation logic upon object deletion.
+	// the cluster is always allowed to be deleted, the finalizer cleans up the hosts.
 	return nil
 }
+
+// validateSpec checks the spec that all services need to be deployed
+func (r *Curvefs) validateSpec() field.ErrorList {
+	allErrs := field.ErrorList{}
+	specPath := field.NewPath("spec")
+	spec := r.Spec
+
+	allErrs = append(allErrs, validateNodes(spec.Nodes, specPath.Child("nodes"))...)
+	allErrs = append(allErrs, validateCopysets(spec.Copysets, specPath.Child("copysets"))...)
+
+	ports := newHostPorts()
+	if spec.Etcd == nil {
+		allErrs = append(allErrs, field.Required(specPath.Child("etcd"), "etcd must be specified"))
+	} else {
+		ports.addEtcd(spec.Etcd, spec.Nodes, specPath.Child("etcd"))
+		allErrs = append(allErrs, validateResources(spec.Etcd.Resources, specPath.Child("etcd", "resources"))...)
+		allErrs = append(allErrs, validatePlacement(spec.Etcd.Placement, specPath.Child("etcd", "placement"))...)
+	}
+	if spec.Mds == nil {
+		allErrs = append(allErrs, field.Required(specPath.Child("mds"), "mds must be specified"))
+	} else {
+		ports.addMds(spec.Mds, spec.Nodes, specPath.Child("mds"))
+		allErrs = append(allErrs, validateResources(spec.Mds.Resources, specPath.Child("mds", "resources"))...)
+		allErrs = append(allErrs, validatePlacement(spec.Mds.Placement, specPath.Child("mds", "placement"))...)
+	}
+	if spec.MetaServer == nil {
+		allErrs = append(allErrs, field.Required(specPath.Child("metaserver"), "metaserver must be specified"))
+	} else {
+		metaserverPath := specPath.Child("metaserver")
+		allErrs = append(allErrs, validateInstances(spec.MetaServer.Instances, metaserverPath.Child("instances"))...)
+		allErrs = append(allErrs, validateResources(spec.MetaServer.Resources, metaserverPath.Child("resources"))...)
+		allErrs = append(allErrs, validatePlacement(spec.MetaServer.Placement, metaserverPath.Child("placement"))...)
+		ports.add(spec.MetaServer.Port, spec.MetaServer.Instances, metaserverPath.Child("port"))
+		if spec.MetaServer.ExternalPort != nil && spec.MetaServer.Port != nil &&
+			*spec.MetaServer.ExternalPort != *spec.MetaServer.Port {
+			ports.add(spec.MetaServer.ExternalPort, spec.MetaServer.Instances, metaserverPath.Child("externalPort"))
+		}
+	}
+	if spec.S3 != nil {
+		s3Path := specPath.Child("s3")
+		allErrs = append(allErrs, validateS3Config(spec.S3, s3Path)...)
+		ports.addEmbeddedS3(spec.S3, s3Path)
+	}
+	if spec.Fuse != nil && spec.Fuse.Enable {
+		allErrs = append(allErrs, validateFuse(spec.Fuse, spec.S3, specPath.Child("fuse"))...)
+	}
+	if spec.CSI != nil && spec.CSI.Enable {
+		if spec.S3 == nil {
+			allErrs = append(allErrs, field.Required(specPath.Child("s3"), "S3 service must be specified to provision file systems"))
+		}
+		allErrs = append(allErrs, validateCSI(spec.CSI, specPath.Child("csi"))...)
+	}
+	if spec.Monitor != nil && spec.Monitor.Enable {
+		monitorPath := specPath.Child("monitor")
+		allErrs = append(allErrs, validateMonitor(spec.Monitor, monitorPath)...)
+		ports.addMonitor(spec.Monitor, monitorPath)
+	}
+
+	allErrs = append(allErrs, validateResources(spec.JobResources, specPath.Child("jobResources"))...)
+
+	return append(allErrs, ports.errs...)
+}
+
+// toInvalidError converts the field errors to an Invalid error of Curvefs
+func (r *Curvefs) toInvalidError(allErrs field.ErrorList) error {
+	if len(allErrs) == 0 {
+		return nil
+	}
+	return apierrors.NewInvalid(GroupVersion.WithKind("Curvefs").GroupKind(), r.Name, allErrs)
+}
@@ -0,0 +1,224 @@
+package v1
+
+import (
+	"path"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+const (
+	DefaultEtcdPeerPort           = 2380
+	DefaultEtcdClientPort         = 2379
+	DefaultMdsPort                = 6700
+	DefaultMdsDummyPort           = 7700
+	DefaultChunkserverPort        = 8200
+	DefaultSnapShotClonePort      = 5555
+	DefaultSnapShotCloneDummyPort = 8081
+	DefaultSnapShotCloneProxyPort = 8080
+	DefaultMetaServerPort         = 6800
+	DefaultMetaServerExternalPort = 7800
+	DefaultCopysets               = 100
+	DefaultInstances              = 1
+	DefaultImagePullPolicy        = corev1.PullIfNotPresent
+	DefaultCurveBSDataDir         = "/curvebs/data"
+	DefaultCurveBSLogDir          = "/curvebs/logs"
+	DefaultCurveFSDataDir         = "/curvefs/data"
+	DefaultCurveFSLogDir          = "/curvefs/logs"
+
+	DefaultPrometheusImage         = "prom/prometheus:v2.45.0"
+	DefaultPrometheusPort          = 9090
+	DefaultPrometheusRetentionTime = "15d"
+	DefaultGrafanaImage            = "grafana/grafana:9.5.6"
+	DefaultGrafanaPort             = 3000
+	DefaultGrafanaUserName         = "admin"
+	DefaultNodeExporterImage       = "prom/node-exporter:v1.6.1"
+	DefaultNodeExporterPort        = 9100
+	DefaultMinIOImage              = "minio/minio:RELEASE.2023-03-20T20-16-18Z"
+	DefaultMinIOClientImage        = "minio/mc:RELEASE.2023-03-20T17-17-53Z"
+	DefaultMinIOPort               = 9000
+	DefaultS3BucketName            = "curve"
+	DefaultFuseMountDir            = "/mnt/curvefs"
+	DefaultCurveBSCSIImage         = "curvecsi/curvecsi:v3.0.0"
+	DefaultCurveBSCSIDriverName    = "curve.csi.netease.com"
+	DefaultCurveFSCSIImage         = "curvecsi/curvefscsi:v1.0.0"
+	DefaultCurveFSCSIDriverName    = "csi.curvefs.com"
+	DefaultKubeletDir              = "/var/lib/kubelet"
+	DefaultCSIProvisionerImage     = "registry.k8s.io/sig-storage/csi-provisioner:v2.2.2"
+	DefaultCSIAttacherImage        = "registry.k8s.io/sig-storage/csi-attacher:v3.2.1"
+	DefaultCSIResizerImage         = "registry.k8s.io/sig-storage/csi-resizer:v1.2.0"
+	DefaultCSIRegistrarImage       = "registry.k8s.io/sig-storage/csi-node-driver-registrar:v2.2.0"
+)
+
+func intPtr(i int) *int {
+	return &i
+}
+
+// setDefaultInt sets the value of an unset int pointer
+func setDefaultInt(p **int, value int) {
+	if *p == nil {
+		*p = intPtr(value)
+	}
+}
+
+// setDefaultString sets the value of an empty string
+func setDefaultString(s *string, value string) {
+	if len(*s) == 0 {
+		*s = value
+	}
+}
+
+// setDefaultCurveVersion fills the image pull policy
+func setDefaultCurveVersion(version *CurveVersionSpec) {
+	if len(version.ImagePullPolicy) == 0 {
+		version.ImagePullPolicy = DefaultImagePullPolicy
+	}
+}
+
+// defaultEtcdSpec returns the etcd spec with all ports filled
+func defaultEtcdSpec(etcd *EtcdSpec) *EtcdSpec {
+	if etcd == nil {
+		etcd = &EtcdSpec{}
+	}
+	setDefaultInt(&etcd.PeerPort, DefaultEtcdPeerPort)
+	setDefaultInt(&etcd.ClientPort, DefaultEtcdClientPort)
+	return etcd
+}
+
+// defaultMdsSpec returns the mds spec with all ports filled
+func defaultMdsSpec(mds *MdsSpec) *MdsSpec {
+	if mds == nil {
+		mds = &MdsSpec{}
+	}
+	setDefaultInt(&mds.Port, DefaultMdsPort)
+	setDefaultInt(&mds.DummyPort, DefaultMdsDummyPort)
+	return mds
+}
+
+// defaultChunkserverSpec returns the chunkserver spec with port and instances filled, one chunkserver
+// is deployed on each device if devices are specified.
+func defaultChunkserverSpec(chunkserver *StorageScopeSpec) *StorageScopeSpec {
+	if chunkserver == nil {
+		chunkserver = &StorageScopeSpec{}
+	}
+	setDefaultInt(&chunkserver.Port, DefaultChunkserverPort)
+	if !chunkserver.UseSelectedNodes && len(chunkserver.Devices) > 0 {
+		chunkserver.Instances = len(chunkserver.Devices)
+	}
+	if chunkserver.Instances == 0 {
+		chunkserver.Instances = DefaultInstances
+	}
+	return chunkserver
+}
+
+// defaultSnapShotCloneSpec returns the snapshotclone spec with all ports filled,
+// the ports are filled even if snapshotclone is disabled to enable it by only setting 'enable'.
+func defaultSnapShotCloneSpec(snapshotclone *SnapShotCloneSpec) *SnapShotCloneSpec {
+	if snapshotclone == nil {
+		snapshotclone = &SnapShotCloneSpec{}
+	}
+	setDefaultInt(&snapshotclone.Port, DefaultSnapShotClonePort)
+	setDefaultInt(&snapshotclone.DummyPort, DefaultSnapShotCloneDummyPort)
+	setDefaultInt(&snapshotclone.ProxyPort, DefaultSnapShotCloneProxyPort)
+	return snapshotclone
+}
+
+// defaultMetaServerSpec returns the metaserver spec with ports and instances filled
+func defaultMetaServerSpec(metaserver *MetaServerSpec) *MetaServerSpec {
+	if metaserver == nil {
+		metaserver = &MetaServerSpec{}
+	}
+	setDefaultInt(&metaserver.Port, DefaultMetaServerPort)
+	setDefaultInt(&metaserver.ExternalPort, DefaultMetaServerExternalPort)
+	if metaserver.Instances == 0 {
+		metaserver.Instances = DefaultInstances
+	}
+	return metaserver
+}
+
+// defaultMonitorSpec returns the monitor spec with images and ports filled, the data of Prometheus
+// and Grafana is stored under the data directory of cluster and the first node is the monitor host
+// by default.
+func defaultMonitorSpec(monitor *MonitorSpec, nodes []string, dataDir string) *MonitorSpec {
+	if monitor == nil {
+		monitor = &MonitorSpec{}
+	}
+	if len(nodes) > 0 {
+		setDefaultString(&monitor.MonitorHost, nodes[0])
+	}
+
+	prometheus := &monitor.Prometheus
+	setDefaultString(&prometheus.ContainerImage, DefaultPrometheusImage)
+	setDefaultString(&prometheus.DataDir, path.Join(dataDir, "monitor", "prometheus"))
+	setDefaultString(&prometheus.RetentionTime, DefaultPrometheusRetentionTime)
+	if prometheus.ListenPort == 0 {
+		prometheus.ListenPort = DefaultPrometheusPort
+	}
+
+	grafana := &monitor.Grafana
+	setDefaultString(&grafana.ContainerImage, DefaultGrafanaImage)
+	setDefaultString(&grafana.DataDir, path.Join(dataDir, "monitor", "grafana"))
+	setDefaultString(&grafana.UserName, DefaultGrafanaUserName)
+	if grafana.ListenPort == 0 {
+		grafana.ListenPort = DefaultGrafanaPort
+	}
+
+	nodeExporter := &monitor.NodeExporter
+	setDefaultString(&nodeExporter.ContainerImage, DefaultNodeExporterImage)
+	if nodeExporter.ListenPort == 0 {
+		nodeExporter.ListenPort = DefaultNodeExporterPort
+	}
+	return monitor
+}
+
+// defaultS3ConfigSpec fills the embedded MinIO if it's enabled, it's deployed on the first node
+// and stores the objects under the data directory of cluster by default.
+func defaultS3ConfigSpec(s3 *S3ConfigSpec, nodes []string, dataDir string) {
+	if s3 == nil || s3.Embedded == nil || !s3.Embedded.Enable {
+		return
+	}
+
+	setDefaultString(&s3.SnapShotBucketName, DefaultS3BucketName)
+	embedded := s3.Embedded
+	if len(nodes) > 0 {
+		setDefaultString(&embedded.Host, nodes[0])
+	}
+	setDefaultString(&embedded.ContainerImage, DefaultMinIOImage)
+	setDefaultString(&embedded.ClientImage, DefaultMinIOClientImage)
+	setDefaultString(&embedded.DataDir, path.Join(dataDir, "minio"))
+	if embedded.ListenPort == 0 {
+		embedded.ListenPort = DefaultMinIOPort
+	}
+}
+
+// defaultFuseSpec mounts the file systems on all the nodes of cluster under /mnt/curvefs by default
+func defaultFuseSpec(fuse *FuseSpec, nodes []string) {
+	if fuse == nil || !fuse.Enable {
+		return
+	}
+	if len(fuse.Nodes) == 0 {
+		fuse.Nodes = append([]string{}, nodes...)
+	}
+	setDefaultString(&fuse.MountDir, DefaultFuseMountDir)
+}
+
+// defaultCSISpec fills the CSI driver if it's enabled with the image and the driver name of the kind
+// of cluster, the StorageClass is named after the cluster and deletes the volumes by default.
+func defaultCSISpec(csi *CSISpec, clusterName, image, driverName string) {
+	if csi == nil || !csi.Enable {
+		return
+	}
+
+	setDefaultString(&csi.ContainerImage, image)
+	setDefaultString(&csi.DriverName, driverName)
+	setDefaultString(&csi.KubeletDir, DefaultKubeletDir)
+	setDefaultString(&csi.StorageClass.Name, clusterName)
+	if len(csi.StorageClass.ReclaimPolicy) == 0 {
+		csi.StorageClass.ReclaimPolicy = corev1.PersistentVolumeReclaimDelete
+	}
+
+	sidecars := &csi.Sidecars
+	setDefaultString(&sidecars.ProvisionerImage, DefaultCSIProvisionerImage)
+	setDefaultString(&sidecars.AttacherImage, DefaultCSIAttacherImage)
+	setDefaultString(&sidecars.ResizerImage, DefaultCSIResizerImage)
+	setDefaultString(&sidecars.RegistrarImage, DefaultCSIRegistrarImage)
+}
@@ -1,6 +1,9 @@
 package v1
 
-import metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
 
 const (
 	PORT          = "port"
@@ -24,6 +27,8 @@ const (
 	ClusterUpdating ClusterPhase = "Updating"
 	// ClusterUpgrading indicates the cluster is to upgrade becasue 'Image' filed changed of 'CurveVersion'
 	ClusterUpgrading ClusterPhase = "Upgrading"
+	// ClusterRollingBack indicates the cluster is to roll back to the previous 'CurveVersion' of a failed upgrade
+	ClusterRollingBack ClusterPhase = "RollingBack"
 	// ClusterScaling indicates the cluster is to scale becasue some server config change for chunkserver/metaserver replicas.
 	ClusterScaling ClusterPhase = "Scaling"
 	// ClusterPhaseDeleting indicates the cluster is running to delete.
@@ -44,6 +49,21 @@ const (
 	ConditionDeleting ConditionType = "Deleting"
 	// ConditionFailure indicates it's failed
 	ConditionFailure ConditionType = "Failed"
+
+	// ConditionEtcdReady indicates all etcd services are started
+	ConditionEtcdReady ConditionType = "EtcdReady"
+	// ConditionMdsReady indicates all mds services are started
+	ConditionMdsReady ConditionType = "MdsReady"
+	// ConditionChunkserversReady indicates all chunkserver services are started
+	ConditionChunkserversReady ConditionType = "ChunkserversReady"
+	// ConditionSnapShotCloneReady indicates all snapshotclone services are started
+	ConditionSnapShotCloneReady ConditionType = "SnapShotCloneReady"
+	// ConditionMetaserversReady indicates all metaserver services are started
+	ConditionMetaserversReady ConditionType = "MetaserversReady"
+	// ConditionPhysicalPoolCreated indicates the physical pool of curvebs is created
+	ConditionPhysicalPoolCreated ConditionType = "PhysicalPoolCreated"
+	// ConditionLogicalPoolCreated indicates the logical pool is created
+	ConditionLogicalPoolCreated ConditionType = "LogicalPoolCreated"
 )
 
 type ConditionStatus string
@@ -56,11 +76,27 @@ const (
 
 type ConditionReason string
 
+// Annotations of the cluster custom resource to control the operations of operator
 const (
-	ConditionDeletingClusterReason ConditionReason = "Deleting"
-	ConditionReconcileStarted      ConditionReason = "ReconcileStarted"
-	ConditionReconcileSucceeded    ConditionReason = "ReconcileSucceeded"
-	ConditionReconcileFailed       ConditionReason = "ReconcileFailed"
+	// AnnotationPauseUpgrade pauses the upgrade of cluster before the next step when it's "true"
+	AnnotationPauseUpgrade = "operator.curve.io/pause-upgrade"
+	// AnnotationRollback rolls back the upgrade in progress to the previous version when it's "true",
+	// the annotation is removed by operator once the rollback starts.
+	AnnotationRollback = "operator.curve.io/rollback"
+)
+
+const (
+	ConditionDeletingClusterReason  ConditionReason = "Deleting"
+	ConditionReconcileStarted       ConditionReason = "ReconcileStarted"
+	ConditionReconcileSucceeded     ConditionReason = "ReconcileSucceeded"
+	ConditionReconcileFailed        ConditionReason = "ReconcileFailed"
+	ConditionWaitingForReady        ConditionReason = "WaitingForReady"
+	ConditionCreatingClusterReason  ConditionReason = "Creating"
+	ConditionUpdatingClusterReason  ConditionReason = "Updating"
+	ConditionUpgradingClusterReason ConditionReason = "Upgrading"
+	ConditionScalingClusterReason   ConditionReason = "Scaling"
+	ConditionRollingBackReason      ConditionReason = "RollingBack"
+	ConditionRoleDisabled           ConditionReason = "Disabled"
 )
 
 type ClusterCondition struct {
@@ -82,6 +118,8 @@ type ClusterCondition struct {
 type CurveVersionSpec struct {
 	// +optional
 	Image string `json:"image,omitempty"`
+	// +optional
+	ImagePullPolicy corev1.PullPolicy `json:"imagePullPolicy,omitempty"`
 }
 
 // EtcdSpec is the spec of etcd
@@ -92,6 +130,12 @@ type EtcdSpec struct {
 	ClientPort *int `json:"clientPort,omitempty"`
 	// +optional
 	Config map[string]string `json:"config,omitempty"`
+	// Resources are the compute resources of the containers of the service
+	// +optional
+	Resources corev1.ResourceRequirements `json:"resources,omitempty"`
+	// Placement is where the pods of the service are scheduled besides their hosts
+	// +optional
+	Placement PlacementSpec `json:"placement,omitempty"`
 }
 
 // MdsSpec is the spec of mds
@@ -102,6 +146,27 @@ type MdsSpec struct {
 	DummyPort *int `json:"dummyPort,omitempty"`
 	// +optional
 	Config map[string]string `json:"config,omitempty"`
+	// Resources are the compute resources of the containers of the service
+	// +optional
+	Resources corev1.ResourceRequirements `json:"resources,omitempty"`
+	// Placement is where the pods of the service are scheduled besides their hosts
+	// +optional
+	Placement PlacementSpec `json:"placement,omitempty"`
+}
+
+// PlacementSpec is the placement of the pods of a role, the pods are still pinned on their hosts by the
+// node affinity of hostname so that the scheduler respects the taints and the priorities
+type PlacementSpec struct {
+	// Tolerations allow the pods to be scheduled on the tainted nodes such as the dedicated storage nodes
+	// +optional
+	Tolerations []corev1.Toleration `json:"tolerations,omitempty"`
+	// NodeAffinity is ANDed with the hostname of the host, its required terms restrict the hosts that the
+	// pods can run on
+	// +optional
+	NodeAffinity *corev1.NodeAffinity `json:"nodeAffinity,omitempty"`
+	// PriorityClassName is the priority class of the pods
+	// +optional
+	PriorityClassName string `json:"priorityClassName,omitempty"`
 }
 
 // StorageScopeSpec is the spec of storage scope
@@ -110,8 +175,48 @@ type StorageScopeSpec struct {
 	Port *int `json:"port,omitempty"`
 	// +optional
 	Instances int `json:"instances,omitempty"`
+	// Devices are the block devices that are formatted and mounted on every node, one chunkserver
+	// is deployed on each device and instances is set to the number of devices.
+	// +optional
+	Devices []DeviceSpec `json:"devices,omitempty"`
+	// UseSelectedNodes deploys chunkservers on the selected nodes with their own devices
+	// rather than on all nodes with the same devices.
+	// +optional
+	UseSelectedNodes bool `json:"useSelectedNodes,omitempty"`
+	// SelectedNodes are the nodes to deploy chunkservers and the devices of each node,
+	// it's only used if useSelectedNodes is true.
+	// +optional
+	SelectedNodes []NodeDevices `json:"selectedNodes,omitempty"`
 	// +optional
 	Config map[string]string `json:"config,omitempty"`
+	// Resources are the compute resources of the containers of each chunkserver, set the requests
+	// equal to the limits to make chunkservers Guaranteed
+	// +optional
+	Resources corev1.ResourceRequirements `json:"resources,omitempty"`
+	// Placement is where the pods of the service are scheduled besides their hosts
+	// +optional
+	Placement PlacementSpec `json:"placement,omitempty"`
+}
+
+// NodeDevices is a node to deploy chunkservers and the devices of the node, one chunkserver
+// is deployed on each device.
+type NodeDevices struct {
+	// Node is the name of the node
+	Node string `json:"node"`
+	// Devices are the block devices that are formatted and mounted on the node
+	Devices []DeviceSpec `json:"devices"`
+}
+
+// DeviceSpec is the spec of a block device that a chunkserver stores data in
+type DeviceSpec struct {
+	// Name is the path of the device on node such as "/dev/sdb"
+	Name string `json:"name"`
+	// MountPath is the directory on node that the device is mounted to
+	MountPath string `json:"mountPath"`
+	// Percentage is the percentage of the device capacity preallocated for chunkfilepool
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=100
+	Percentage int `json:"percentage"`
 }
 
 // SnapShotCloneSpec is the spec of snapshot clone
@@ -128,14 +233,56 @@ type SnapShotCloneSpec struct {
 	S3Config S3ConfigSpec `json:"s3,omitempty"`
 	// +optional
 	Config map[string]string `json:"config,omitempty"`
+	// Resources are the compute resources of the containers of the service
+	// +optional
+	Resources corev1.ResourceRequirements `json:"resources,omitempty"`
+	// Placement is where the pods of the service are scheduled besides their hosts
+	// +optional
+	Placement PlacementSpec `json:"placement,omitempty"`
 }
 
-// S3ConfigSpec is the spec of s3 config
+// S3ConfigSpec is the spec of s3 config, the credentials are read from the Secrets in the
+// namespace of cluster and rendered into s3.conf of curvebs or the configs of curvefs
 type S3ConfigSpec struct {
-	AK                 string `json:"ak,omitempty"`
-	SK                 string `json:"sk,omitempty"`
-	NosAddress         string `json:"nosAddress,omitempty"`
+	// AccessKeySecretRef selects the access key of the S3 service in a Secret
+	// +optional
+	AccessKeySecretRef *corev1.SecretKeySelector `json:"accessKeySecretRef,omitempty"`
+	// SecretKeySecretRef selects the secret key of the S3 service in a Secret
+	// +optional
+	SecretKeySecretRef *corev1.SecretKeySelector `json:"secretKeySecretRef,omitempty"`
+	// NosAddress is the address of the S3 service such as 10.0.0.1:9000
+	// +optional
+	NosAddress string `json:"nosAddress,omitempty"`
+	// SnapShotBucketName is the bucket to store the snapshots of curvebs or the data of curvefs
+	// +optional
 	SnapShotBucketName string `json:"bucketName,omitempty"`
+	// Embedded deploys a MinIO as the S3 service for testing, the address and the credentials
+	// are provided by it and the bucket is created in it
+	// +optional
+	Embedded *EmbeddedS3Spec `json:"embedded,omitempty"`
+}
+
+// EmbeddedS3Spec is the spec of the MinIO that is deployed by operator as a stand-in of S3 service,
+// it stores objects on one node without redundancy and must not be used in production
+type EmbeddedS3Spec struct {
+	// +optional
+	Enable bool `json:"enable,omitempty"`
+	// +optional
+	ContainerImage string `json:"containerImage,omitempty"`
+	// ClientImage is the image of MinIO client to create the bucket
+	// +optional
+	ClientImage string `json:"clientImage,omitempty"`
+	// Host is the node that MinIO is deployed on, it's the first node of cluster by default
+	// +optional
+	Host string `json:"host,omitempty"`
+	// DataDir is the directory on host to store the objects
+	// +optional
+	DataDir string `json:"dataDir,omitempty"`
+	// +optional
+	ListenPort int `json:"listenPort,omitempty"`
+	// Placement is where MinIO is scheduled besides its host
+	// +optional
+	Placement PlacementSpec `json:"placement,omitempty"`
 }
 
 // MdsSpec is the spec of mds
@@ -148,10 +295,19 @@ type MetaServerSpec struct {
 	Instances int `json:"instances,omitempty"`
 	// +optional
 	Config map[string]string `json:"config,omitempty"`
+	// Resources are the compute resources of the containers of the service
+	// +optional
+	Resources corev1.ResourceRequirements `json:"resources,omitempty"`
+	// Placement is where the pods of the service are scheduled besides their hosts
+	// +optional
+	Placement PlacementSpec `json:"placement,omitempty"`
 }
 
+// MonitorSpec is the spec of the monitor stack that scrapes the metrics of the cluster
 type MonitorSpec struct {
+	// +optional
 	Enable bool `json:"enable,omitempty"`
+	// MonitorHost is the node that Prometheus and Grafana are deployed on, it's the first node of cluster by default
 	// +optional
 	MonitorHost string `json:"monitorHost,omitempty"`
 	// +optional
@@ -160,34 +316,63 @@ type MonitorSpec struct {
 	Grafana GrafanaSpec `json:"grafana,omitempty"`
 	// +optional
 	NodeExporter NodeExporterSpec `json:"nodeExporter,omitempty"`
+	// ServiceMonitor creates ServiceMonitors for an existing Prometheus Operator, it's an alternative
+	// to the bundled Prometheus that is deployed if 'enable' is set
+	// +optional
+	ServiceMonitor *ServiceMonitorSpec `json:"serviceMonitor,omitempty"`
+	// Placement is where Prometheus and Grafana are scheduled besides the monitor host
+	// +optional
+	Placement PlacementSpec `json:"placement,omitempty"`
 }
 
+// ServiceMonitorSpec is the spec to scrape the metrics of cluster by an existing Prometheus Operator,
+// a headless Service is created for every service and a ServiceMonitor for every role.
+type ServiceMonitorSpec struct {
+	// +optional
+	Enable bool `json:"enable,omitempty"`
+	// Labels are added to the ServiceMonitors to be selected by the serviceMonitorSelector of Prometheus
+	// +optional
+	Labels map[string]string `json:"labels,omitempty"`
+	// Interval is the interval to scrape the metrics, the global one of Prometheus is used if empty
+	// +optional
+	Interval string `json:"interval,omitempty"`
+}
+
+// PrometheusSpec is the spec of Prometheus
 type PrometheusSpec struct {
 	// +optional
 	ContainerImage string `json:"containerImage,omitempty"`
+	// DataDir is the directory on monitor host to store the time series
 	// +optional
 	DataDir string `json:"dataDir,omitempty"`
 	// +optional
 	ListenPort int `json:"listenPort,omitempty"`
+	// RetentionTime is how long to retain the samples, such as 15d
 	// +optional
 	RetentionTime string `json:"retentionTime,omitempty"`
+	// RetentionSize is the maximum size of the samples to retain, such as 256GB, it's unlimited if empty
 	// +optional
 	RetentionSize string `json:"retentionSize,omitempty"`
 }
 
+// GrafanaSpec is the spec of Grafana
 type GrafanaSpec struct {
 	// +optional
 	ContainerImage string `json:"containerImage,omitempty"`
+	// DataDir is the directory on monitor host to store the data of Grafana
 	// +optional
 	DataDir string `json:"dataDir,omitempty"`
 	// +optional
 	ListenPort int `json:"listenPort,omitempty"`
 	// +optional
 	UserName string `json:"userName,omitempty"`
+	// PasswordSecretRef selects the password of the admin user in a Secret, the default
+	// password of Grafana is used if it's not set
 	// +optional
-	PassWord string `json:"passWord,omitempty"`
+	PasswordSecretRef *corev1.SecretKeySelector `json:"passwordSecretRef,omitempty"`
 }
 
+// NodeExporterSpec is the spec of node-exporter that runs on every node of cluster
 type NodeExporterSpec struct {
 	// +optional
 	ContainerImage string `json:"containerImage,omitempty"`
@@ -209,6 +394,127 @@ type ModContext struct {
 	Parameters map[string]string `json:"parameters,omitempty"`
 }
 
+// ProgressStatus records the progress of the reconcile steps
+type ProgressStatus struct {
+	// Step is the name of the step that is in progress
+	Step string `json:"step,omitempty"`
+	// CompletedSteps is the names of the steps that have been completed in the current phase
+	CompletedSteps []string `json:"completedSteps,omitempty"`
+	// Message shows what the step is waiting for
+	Message string `json:"message,omitempty"`
+}
+
+// UpgradeState is the state of an upgrade
+type UpgradeState string
+
+const (
+	UpgradeStateUpgrading   UpgradeState = "Upgrading"
+	UpgradeStateSucceeded   UpgradeState = "Succeeded"
+	UpgradeStateRollingBack UpgradeState = "RollingBack"
+	UpgradeStateRolledBack  UpgradeState = "RolledBack"
+)
+
+// UpgradeRecord records an upgrade of the cluster from one image to another
+type UpgradeRecord struct {
+	// FromImage is the image before the upgrade, it's the image to roll back to
+	FromImage string `json:"fromImage,omitempty"`
+	// ToImage is the image to upgrade to
+	ToImage string `json:"toImage,omitempty"`
+	// State is the state of the upgrade
+	State UpgradeState `json:"state,omitempty"`
+	// StartedAt is the time the upgrade started
+	StartedAt metav1.Time `json:"startedAt,omitempty"`
+	// FinishedAt is the time the upgrade succeeded or was rolled back
+	// +optional
+	FinishedAt *metav1.Time `json:"finishedAt,omitempty"`
+	// Services shows the image that each service is running
+	Services []ServiceVersion `json:"services,omitempty"`
+}
+
+// ServiceVersion records the image that a service is running
+type ServiceVersion struct {
+	// Name is the name of the service such as "chunkserver-host1-0"
+	Name string `json:"name,omitempty"`
+	// Image is the image of the service
+	Image string `json:"image,omitempty"`
+}
+
+// FormatPhase is the phase to format the chunkfilepool on a device
+type FormatPhase string
+
+const (
+	FormatPhasePending    FormatPhase = "Pending"
+	FormatPhaseFormatting FormatPhase = "Formatting"
+	FormatPhaseFormatted  FormatPhase = "Formatted"
+	FormatPhaseFailed     FormatPhase = "Failed"
+)
+
+// FormatStatus shows the progress to preallocate the chunkfilepool on a device of a node
+type FormatStatus struct {
+	// Node is the name of the node
+	Node string `json:"node,omitempty"`
+	// Device is the path of the device on node
+	Device string `json:"device,omitempty"`
+	// MountPath is the directory on node that the device is mounted to
+	MountPath string `json:"mountPath,omitempty"`
+	// Phase is one of Pending, Formatting, Formatted and Failed
+	Phase FormatPhase `json:"phase,omitempty"`
+	// Progress is the percentage of the chunkfilepool that has been preallocated
+	Progress int `json:"progress"`
+	// Message shows why the format failed
+	// +optional
+	Message string `json:"message,omitempty"`
+}
+
 type LastModContextSet struct {
 	ModContextSet []ModContext `json:"modContextSet,omitempty"`
 }
+
+// CSISpec is the spec of the CSI driver that provisions the PVCs from the cluster, the controller and
+// the node plugins are deployed in the namespace of cluster and a StorageClass refers to the driver
+type CSISpec struct {
+	// +optional
+	Enable bool `json:"enable,omitempty"`
+	// ContainerImage is the image of the CSI plugin
+	// +optional
+	ContainerImage string `json:"containerImage,omitempty"`
+	// DriverName is the name that the driver is registered as, it must be unique among the clusters
+	// that deploy the CSI driver
+	// +optional
+	DriverName string `json:"driverName,omitempty"`
+	// KubeletDir is the root directory of kubelet on the nodes
+	// +optional
+	KubeletDir string `json:"kubeletDir,omitempty"`
+	// NodeSelector selects the nodes to run the node plugins, all the nodes are selected if it's empty
+	// +optional
+	NodeSelector map[string]string `json:"nodeSelector,omitempty"`
+	// +optional
+	StorageClass StorageClassSpec `json:"storageClass,omitempty"`
+	// +optional
+	Sidecars CSISidecarsSpec `json:"sidecars,omitempty"`
+}
+
+// StorageClassSpec is the spec of the StorageClass that provisions the PVCs by the CSI driver
+type StorageClassSpec struct {
+	// Name is the name of the StorageClass, it's the name of cluster by default
+	// +optional
+	Name string `json:"name,omitempty"`
+	// +kubebuilder:validation:Enum=Delete;Retain
+	// +optional
+	ReclaimPolicy corev1.PersistentVolumeReclaimPolicy `json:"reclaimPolicy,omitempty"`
+	// Parameters are added to the parameters of the StorageClass that are generated from the cluster
+	// +optional
+	Parameters map[string]string `json:"parameters,omitempty"`
+}
+
+// CSISidecarsSpec is the images of the Kubernetes CSI sidecars
+type CSISidecarsSpec struct {
+	// +optional
+	ProvisionerImage string `json:"provisionerImage,omitempty"`
+	// +optional
+	AttacherImage string `json:"attacherImage,omitempty"`
+	// +optional
+	ResizerImage string `json:"resizerImage,omitempty"`
+	// +optional
+	RegistrarImage string `json:"registrarImage,omitempty"`
+}
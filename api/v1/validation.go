@@ -0,0 +1,504 @@
+package v1
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+const (
+	// standAloneNodes is the number of nodes of a stand-alone deployment,
+	// etcd and mds run three instances on the same node in this mode.
+	standAloneNodes = 1
+	// minReplicatedNodes is the least number of nodes to hold three etcd and mds replicas.
+	minReplicatedNodes = 3
+	// standAloneInstances is the number of etcd, mds and snapshotclone instances on a stand-alone node.
+	standAloneInstances = 3
+
+	maxPort = 65535
+	// maxCSIDriverNameLength is the maximum length of the name of CSI driver
+	maxCSIDriverNameLength = 63
+)
+
+// isStandAlone returns true if all services are deployed on one node
+func isStandAlone(nodes []string) bool {
+	return len(nodes) == standAloneNodes
+}
+
+// replicatedRoleInstances returns the instances of etcd, mds or snapshotclone on each node
+func replicatedRoleInstances(nodes []string) int {
+	if isStandAlone(nodes) {
+		return standAloneInstances
+	}
+	return 1
+}
+
+// validateNodes checks that nodes can hold a stand-alone or a three replicas cluster
+func validateNodes(nodes []string, fldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+	if len(nodes) == 0 {
+		return append(allErrs, field.Required(fldPath, "at least one node must be specified"))
+	}
+	if !isStandAlone(nodes) && len(nodes) < minReplicatedNodes {
+		allErrs = append(allErrs, field.Invalid(fldPath, nodes,
+			fmt.Sprintf("specify one node for stand-alone deployment or at least %d nodes", minReplicatedNodes)))
+	}
+
+	seen := map[string]bool{}
+	for i, node := range nodes {
+		if len(node) == 0 {
+			allErrs = append(allErrs, field.Required(fldPath.Index(i), "node name must not be empty"))
+			continue
+		}
+		if seen[node] {
+			allErrs = append(allErrs, field.Duplicate(fldPath.Index(i), node))
+		}
+		seen[node] = true
+	}
+
+	return allErrs
+}
+
+// validateCopysets checks the copysets of every chunkserver or metaserver
+func validateCopysets(copysets *int, fldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+	if copysets == nil {
+		return append(allErrs, field.Required(fldPath, "copysets must be specified"))
+	}
+	if *copysets <= 0 {
+		allErrs = append(allErrs, field.Invalid(fldPath, *copysets, "must be greater than 0"))
+	}
+	return allErrs
+}
+
+// validateInstances checks the instances of chunkserver or metaserver on each node
+func validateInstances(instances int, fldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+	if instances < 0 {
+		allErrs = append(allErrs, field.Invalid(fldPath, instances, "must not be negative"))
+	}
+	return allErrs
+}
+
+// validateDevices checks the devices of chunkservers on a node, every device is used by one chunkserver
+func validateDevices(devices []DeviceSpec, fldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+	names, mountPaths := map[string]bool{}, map[string]bool{}
+	for i, device := range devices {
+		idxPath := fldPath.Index(i)
+		if !path.IsAbs(device.Name) {
+			allErrs = append(allErrs, field.Invalid(idxPath.Child("name"), device.Name, "must be an absolute path"))
+		} else if names[device.Name] {
+			allErrs = append(allErrs, field.Duplicate(idxPath.Child("name"), device.Name))
+		}
+		if !path.IsAbs(device.MountPath) {
+			allErrs = append(allErrs, field.Invalid(idxPath.Child("mountPath"), device.MountPath, "must be an absolute path"))
+		} else if mountPaths[path.Clean(device.MountPath)] {
+			allErrs = append(allErrs, field.Duplicate(idxPath.Child("mountPath"), device.MountPath))
+		}
+		if device.Percentage <= 0 || device.Percentage > 100 {
+			allErrs = append(allErrs, field.Invalid(idxPath.Child("percentage"), device.Percentage, "must be in range (0, 100]"))
+		}
+		names[device.Name] = true
+		mountPaths[path.Clean(device.MountPath)] = true
+	}
+	return allErrs
+}
+
+// validateChunkserverStorage checks the devices of chunkservers, the chunkservers are deployed either
+// on all nodes with the same devices or on the selected nodes with their own devices.
+func validateChunkserverStorage(chunkserver *StorageScopeSpec, fldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+	if !chunkserver.UseSelectedNodes {
+		allErrs = append(allErrs, validateDevices(chunkserver.Devices, fldPath.Child("devices"))...)
+		if len(chunkserver.Devices) > 0 && chunkserver.Instances != len(chunkserver.Devices) {
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("instances"), chunkserver.Instances,
+				fmt.Sprintf("must be the number of devices %d", len(chunkserver.Devices))))
+		}
+		return allErrs
+	}
+
+	if len(chunkserver.Devices) > 0 {
+		allErrs = append(allErrs, field.Forbidden(fldPath.Child("devices"),
+			"must not be specified if useSelectedNodes is true, specify the devices of every selected node"))
+	}
+	selectedPath := fldPath.Child("selectedNodes")
+	if len(chunkserver.SelectedNodes) == 0 {
+		return append(allErrs, field.Required(selectedPath, "at least one node must be selected if useSelectedNodes is true"))
+	}
+	seen := map[string]bool{}
+	for i, selected := range chunkserver.SelectedNodes {
+		idxPath := selectedPath.Index(i)
+		if len(selected.Node) == 0 {
+			allErrs = append(allErrs, field.Required(idxPath.Child("node"), "node name must not be empty"))
+		} else if seen[selected.Node] {
+			allErrs = append(allErrs, field.Duplicate(idxPath.Child("node"), selected.Node))
+		}
+		seen[selected.Node] = true
+		if len(selected.Devices) == 0 {
+			allErrs = append(allErrs, field.Required(idxPath.Child("devices"), "at least one device must be specified"))
+		}
+		allErrs = append(allErrs, validateDevices(selected.Devices, idxPath.Child("devices"))...)
+	}
+	return allErrs
+}
+
+// chunkserverInstances returns the most chunkservers deployed on one node
+func chunkserverInstances(chunkserver *StorageScopeSpec) int {
+	if !chunkserver.UseSelectedNodes {
+		return chunkserver.Instances
+	}
+	instances := 0
+	for _, selected := range chunkserver.SelectedNodes {
+		if len(selected.Devices) > instances {
+			instances = len(selected.Devices)
+		}
+	}
+	return instances
+}
+
+// validateDevicesUpdate forbids to change the devices that are in use, devices can only be
+// appended or removed at the tail like nodes.
+func validateDevicesUpdate(newDevices, oldDevices []DeviceSpec, fldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+	if (len(newDevices) == 0) != (len(oldDevices) == 0) {
+		return append(allErrs, field.Forbidden(fldPath,
+			"chunkservers can not be moved between devices and dataDir once the cluster has been created"))
+	}
+	for i := 0; i < len(newDevices) && i < len(oldDevices); i++ {
+		if newDevices[i] != oldDevices[i] {
+			allErrs = append(allErrs, field.Forbidden(fldPath.Index(i),
+				"devices in use can not be changed, devices can only be appended or removed at the tail"))
+		}
+	}
+	return allErrs
+}
+
+// validateChunkserverStorageUpdate forbids to change the nodes and devices of the running chunkservers,
+// the selected nodes and their devices can only be appended or removed at the tail.
+func validateChunkserverStorageUpdate(newChunkserver, oldChunkserver *StorageScopeSpec, fldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+	if newChunkserver.UseSelectedNodes != oldChunkserver.UseSelectedNodes {
+		return append(allErrs, field.Forbidden(fldPath.Child("useSelectedNodes"),
+			"can not be changed once the cluster has been created"))
+	}
+	if !newChunkserver.UseSelectedNodes {
+		return validateDevicesUpdate(newChunkserver.Devices, oldChunkserver.Devices, fldPath.Child("devices"))
+	}
+
+	selectedPath := fldPath.Child("selectedNodes")
+	newNodes, oldNodes := []string{}, []string{}
+	for _, selected := range newChunkserver.SelectedNodes {
+		newNodes = append(newNodes, selected.Node)
+	}
+	for _, selected := range oldChunkserver.SelectedNodes {
+		oldNodes = append(oldNodes, selected.Node)
+	}
+	allErrs = append(allErrs, validateNodesScale(newNodes, oldNodes, selectedPath)...)
+	if len(allErrs) > 0 {
+		return allErrs
+	}
+	for i := 0; i < len(newNodes) && i < len(oldNodes); i++ {
+		allErrs = append(allErrs, validateDevicesUpdate(newChunkserver.SelectedNodes[i].Devices,
+			oldChunkserver.SelectedNodes[i].Devices, selectedPath.Index(i).Child("devices"))...)
+	}
+	return allErrs
+}
+
+// hostPorts records the ports of all roles, every role is deployed on the same nodes with
+// hostNetwork so that the ports of any two roles must not collide.
+type hostPorts struct {
+	owners map[int]*field.Path
+	errs   field.ErrorList
+}
+
+func newHostPorts() *hostPorts {
+	return &hostPorts{owners: map[int]*field.Path{}}
+}
+
+// add claims the ports from port to port+instances-1 for the field, one for each instance
+func (h *hostPorts) add(port *int, instances int, fldPath *field.Path) {
+	if port == nil {
+		h.errs = append(h.errs, field.Required(fldPath, "port must be specified"))
+		return
+	}
+	if *port <= 0 || *port+instances-1 > maxPort {
+		h.errs = append(h.errs, field.Invalid(fldPath, *port, fmt.Sprintf("must be between 1 and %d", maxPort)))
+		return
+	}
+
+	for i := 0; i < instances; i++ {
+		p := *port + i
+		if owner, ok := h.owners[p]; ok {
+			h.errs = append(h.errs, field.Invalid(fldPath, *port,
+				fmt.Sprintf("port %d collides with %s on the same host", p, owner.String())))
+			return
+		}
+		h.owners[p] = fldPath
+	}
+}
+
+// addEtcd claims the ports of etcd
+func (h *hostPorts) addEtcd(etcd *EtcdSpec, nodes []string, fldPath *field.Path) {
+	instances := replicatedRoleInstances(nodes)
+	h.add(etcd.PeerPort, instances, fldPath.Child("peerPort"))
+	h.add(etcd.ClientPort, instances, fldPath.Child("clientPort"))
+}
+
+// addMds claims the ports of mds
+func (h *hostPorts) addMds(mds *MdsSpec, nodes []string, fldPath *field.Path) {
+	instances := replicatedRoleInstances(nodes)
+	h.add(mds.Port, instances, fldPath.Child("port"))
+	h.add(mds.DummyPort, instances, fldPath.Child("dummyPort"))
+}
+
+// addMonitor claims the ports of the monitor stack, node-exporter runs on every node and
+// Prometheus and Grafana on the monitor host which is usually one of the nodes.
+func (h *hostPorts) addMonitor(monitor *MonitorSpec, fldPath *field.Path) {
+	h.add(&monitor.Prometheus.ListenPort, 1, fldPath.Child("prometheus", "listenPort"))
+	h.add(&monitor.Grafana.ListenPort, 1, fldPath.Child("grafana", "listenPort"))
+	h.add(&monitor.NodeExporter.ListenPort, 1, fldPath.Child("nodeExporter", "listenPort"))
+}
+
+// addEmbeddedS3 claims the port of the embedded MinIO if it's enabled
+func (h *hostPorts) addEmbeddedS3(s3 *S3ConfigSpec, fldPath *field.Path) {
+	if s3 == nil || s3.Embedded == nil || !s3.Embedded.Enable {
+		return
+	}
+	h.add(&s3.Embedded.ListenPort, 1, fldPath.Child("embedded", "listenPort"))
+}
+
+// validateMonitor checks the monitor stack that is enabled
+func validateMonitor(monitor *MonitorSpec, fldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+	if len(monitor.MonitorHost) == 0 {
+		allErrs = append(allErrs, field.Required(fldPath.Child("monitorHost"), "monitor host must be specified"))
+	}
+	if len(monitor.Prometheus.DataDir) == 0 {
+		allErrs = append(allErrs, field.Required(fldPath.Child("prometheus", "dataDir"), "data directory must be specified"))
+	}
+	if len(monitor.Grafana.DataDir) == 0 {
+		allErrs = append(allErrs, field.Required(fldPath.Child("grafana", "dataDir"), "data directory must be specified"))
+	}
+	allErrs = append(allErrs, validateSecretKeySelector(monitor.Grafana.PasswordSecretRef,
+		fldPath.Child("grafana", "passwordSecretRef"))...)
+	allErrs = append(allErrs, validatePlacement(monitor.Placement, fldPath.Child("placement"))...)
+	return allErrs
+}
+
+// validateS3Config checks the S3 config that is used, all of the config must be specified unless
+// the embedded MinIO provides the address and the credentials.
+func validateS3Config(s3 *S3ConfigSpec, fldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+	if s3.Embedded != nil && s3.Embedded.Enable {
+		return append(allErrs, validateEmbeddedS3(s3, fldPath)...)
+	}
+	if len(s3.NosAddress) == 0 {
+		allErrs = append(allErrs, field.Required(fldPath.Child("nosAddress"), "address of S3 service must be specified"))
+	}
+	if len(s3.SnapShotBucketName) == 0 {
+		allErrs = append(allErrs, field.Required(fldPath.Child("bucketName"), "bucket to store snapshots must be specified"))
+	}
+	if s3.AccessKeySecretRef == nil {
+		allErrs = append(allErrs, field.Required(fldPath.Child("accessKeySecretRef"), "access key of S3 service must be specified"))
+	}
+	if s3.SecretKeySecretRef == nil {
+		allErrs = append(allErrs, field.Required(fldPath.Child("secretKeySecretRef"), "secret key of S3 service must be specified"))
+	}
+	allErrs = append(allErrs, validateSecretKeySelector(s3.AccessKeySecretRef, fldPath.Child("accessKeySecretRef"))...)
+	allErrs = append(allErrs, validateSecretKeySelector(s3.SecretKeySecretRef, fldPath.Child("secretKeySecretRef"))...)
+	return allErrs
+}
+
+// validateEmbeddedS3 checks the embedded MinIO
+func validateEmbeddedS3(s3 *S3ConfigSpec, fldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+	embeddedPath := fldPath.Child("embedded")
+	if len(s3.SnapShotBucketName) == 0 {
+		allErrs = append(allErrs, field.Required(fldPath.Child("bucketName"), "bucket must be specified"))
+	}
+	if len(s3.Embedded.Host) == 0 {
+		allErrs = append(allErrs, field.Required(embeddedPath.Child("host"), "host must be specified"))
+	}
+	if len(s3.Embedded.DataDir) == 0 {
+		allErrs = append(allErrs, field.Required(embeddedPath.Child("dataDir"), "data directory must be specified"))
+	}
+	allErrs = append(allErrs, validatePlacement(s3.Embedded.Placement, embeddedPath.Child("placement"))...)
+	return allErrs
+}
+
+// validateFuse checks the FUSE clients that are enabled, the file systems are stored in the S3 service
+// and their names are the names of the containers that mount them.
+func validateFuse(fuse *FuseSpec, s3 *S3ConfigSpec, fldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+	if s3 == nil {
+		allErrs = append(allErrs, field.Required(field.NewPath("spec", "s3"), "S3 service must be specified to mount file systems"))
+	}
+	if len(fuse.Nodes) == 0 {
+		allErrs = append(allErrs, field.Required(fldPath.Child("nodes"), "at least one node must be specified"))
+	}
+	if !path.IsAbs(fuse.MountDir) {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("mountDir"), fuse.MountDir, "mount directory must be an absolute path"))
+	}
+	if len(fuse.FileSystems) == 0 {
+		allErrs = append(allErrs, field.Required(fldPath.Child("fileSystems"), "at least one file system must be specified"))
+	}
+
+	seen := map[string]bool{}
+	for i, name := range fuse.FileSystems {
+		if errs := validation.IsDNS1123Label(name); len(errs) > 0 {
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("fileSystems").Index(i), name, strings.Join(errs, "; ")))
+		}
+		if seen[name] {
+			allErrs = append(allErrs, field.Duplicate(fldPath.Child("fileSystems").Index(i), name))
+		}
+		seen[name] = true
+	}
+	return allErrs
+}
+
+// validateCSI checks the CSI driver that is enabled
+func validateCSI(csi *CSISpec, fldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+	if errs := validation.IsDNS1123Subdomain(csi.DriverName); len(errs) > 0 {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("driverName"), csi.DriverName, strings.Join(errs, "; ")))
+	} else if len(csi.DriverName) > maxCSIDriverNameLength {
+		allErrs = append(allErrs, field.TooLong(fldPath.Child("driverName"), csi.DriverName, maxCSIDriverNameLength))
+	}
+	if !path.IsAbs(csi.KubeletDir) {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("kubeletDir"), csi.KubeletDir, "kubelet directory must be an absolute path"))
+	}
+	if errs := validation.IsDNS1123Subdomain(csi.StorageClass.Name); len(errs) > 0 {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("storageClass", "name"), csi.StorageClass.Name, strings.Join(errs, "; ")))
+	}
+	return allErrs
+}
+
+// validateResources checks that the requests of the compute resources don't exceed their limits
+func validateResources(resources corev1.ResourceRequirements, fldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+	for name, request := range resources.Requests {
+		limit, ok := resources.Limits[name]
+		if ok && request.Cmp(limit) > 0 {
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("requests").Key(string(name)), request.String(),
+				fmt.Sprintf("must be less than or equal to %s limit %s", name, limit.String())))
+		}
+	}
+	return allErrs
+}
+
+// validatePlacement checks the tolerations and the priority class of the placement of a role
+func validatePlacement(placement PlacementSpec, fldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+	for i, toleration := range placement.Tolerations {
+		idxPath := fldPath.Child("tolerations").Index(i)
+		if len(toleration.Key) == 0 && toleration.Operator != corev1.TolerationOpExists {
+			allErrs = append(allErrs, field.Invalid(idxPath.Child("operator"), toleration.Operator,
+				"operator must be Exists when key is empty"))
+		}
+		if toleration.Operator == corev1.TolerationOpExists && len(toleration.Value) > 0 {
+			allErrs = append(allErrs, field.Invalid(idxPath.Child("value"), toleration.Value,
+				"value must be empty when operator is Exists"))
+		}
+	}
+	if len(placement.PriorityClassName) > 0 {
+		if errs := validation.IsDNS1123Subdomain(placement.PriorityClassName); len(errs) > 0 {
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("priorityClassName"), placement.PriorityClassName, strings.Join(errs, "; ")))
+		}
+	}
+	return allErrs
+}
+
+// validateSecretKeySelector checks the reference to the key of a Secret if it's set
+func validateSecretKeySelector(ref *corev1.SecretKeySelector, fldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+	if ref == nil {
+		return allErrs
+	}
+	if len(ref.Name) == 0 {
+		allErrs = append(allErrs, field.Required(fldPath.Child("name"), "name of Secret must be specified"))
+	}
+	if len(ref.Key) == 0 {
+		allErrs = append(allErrs, field.Required(fldPath.Child("key"), "key of Secret must be specified"))
+	}
+	return allErrs
+}
+
+// etcdMembers returns the etcd members deployed on nodes, the first three nodes
+// hold one member each, a stand-alone node holds all three members.
+func etcdMembers(nodes []string) []string {
+	members := []string{}
+	for hostSequence, host := range nodes {
+		if hostSequence >= minReplicatedNodes {
+			break
+		}
+		for i := 0; i < replicatedRoleInstances(nodes); i++ {
+			members = append(members, fmt.Sprintf("%s/%d", host, i))
+		}
+	}
+	return members
+}
+
+// validateNodesUpdate forbids any change of nodes that changes the members of the running etcd quorum
+func validateNodesUpdate(newNodes, oldNodes []string, fldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+	newMembers, oldMembers := etcdMembers(newNodes), etcdMembers(oldNodes)
+	if len(newMembers) != len(oldMembers) {
+		return append(allErrs, field.Forbidden(fldPath,
+			"can not remove the nodes running etcd or switch between stand-alone and replicated deployment"))
+	}
+	for i := range newMembers {
+		if newMembers[i] != oldMembers[i] {
+			allErrs = append(allErrs, field.Forbidden(fldPath.Index(i/replicatedRoleInstances(oldNodes)),
+				"can not replace or reorder the nodes running etcd"))
+			break
+		}
+	}
+	return allErrs
+}
+
+// validateNodesScale allows to scale the nodes only by appending or removing nodes at the tail,
+// since the services are named by the sequence of their nodes.
+func validateNodesScale(newNodes, oldNodes []string, fldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+	for i := 0; i < len(newNodes) && i < len(oldNodes); i++ {
+		if newNodes[i] != oldNodes[i] {
+			return append(allErrs, field.Forbidden(fldPath.Index(i),
+				"nodes can only be appended or removed at the tail"))
+		}
+	}
+	return allErrs
+}
+
+// validateNoScaleIn forbids to remove the services of a role that can't be drained such as metaserver
+func validateNoScaleIn(newNodes, oldNodes []string, newInstances, oldInstances int,
+	nodesPath, instancesPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+	if len(newNodes) < len(oldNodes) {
+		allErrs = append(allErrs, field.Forbidden(nodesPath, "can not remove nodes running metaserver"))
+	}
+	if newInstances < oldInstances {
+		allErrs = append(allErrs, field.Forbidden(instancesPath,
+			fmt.Sprintf("can not be decreased from %d", oldInstances)))
+	}
+	return allErrs
+}
+
+// validateImmutableDir forbids to change a host directory once the cluster has been created
+func validateImmutableDir(newDir, oldDir string, fldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+	if newDir != oldDir {
+		allErrs = append(allErrs, field.Forbidden(fldPath,
+			fmt.Sprintf("can not be changed from %q once the cluster has been created", oldDir)))
+	}
+	return allErrs
+}
+
+// isClusterCreated returns true if the operator has accepted the cluster and started to deploy it
+func isClusterCreated(phase ClusterPhase) bool {
+	return phase != ""
+}
@@ -22,9 +22,49 @@ limitations under the License.
 package v1
 
 import (
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 )
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CSISidecarsSpec) DeepCopyInto(out *CSISidecarsSpec) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CSISidecarsSpec.
+func (in *CSISidecarsSpec) DeepCopy() *CSISidecarsSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(CSISidecarsSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CSISpec) DeepCopyInto(out *CSISpec) {
+	*out = *in
+	if in.NodeSelector != nil {
+		in, out := &in.NodeSelector, &out.NodeSelector
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	in.StorageClass.DeepCopyInto(&out.StorageClass)
+	out.Sidecars = in.Sidecars
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CSISpec.
+func (in *CSISpec) DeepCopy() *CSISpec {
+	if in == nil {
+		return nil
+	}
+	out := new(CSISpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ClusterCondition) DeepCopyInto(out *ClusterCondition) {
 	*out = *in
@@ -134,6 +174,17 @@ func (in *CurveClusterSpec) DeepCopyInto(out *CurveClusterSpec) {
 		*out = new(SnapShotCloneSpec)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.CSI != nil {
+		in, out := &in.CSI, &out.CSI
+		*out = new(CSISpec)
+		(*in).DeepCopyInto(*out)
+	}
+	in.JobResources.DeepCopyInto(&out.JobResources)
+	if in.Monitor != nil {
+		in, out := &in.Monitor, &out.Monitor
+		*out = new(MonitorSpec)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CurveClusterSpec.
@@ -157,6 +208,20 @@ func (in *CurveClusterStatus) DeepCopyInto(out *CurveClusterStatus) {
 		}
 	}
 	out.CurveVersion = in.CurveVersion
+	if in.UpgradeHistory != nil {
+		in, out := &in.UpgradeHistory, &out.UpgradeHistory
+		*out = make([]UpgradeRecord, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	in.Progress.DeepCopyInto(&out.Progress)
+	if in.FormatStatus != nil {
+		in, out := &in.FormatStatus, &out.FormatStatus
+		*out = make([]FormatStatus, len(*in))
+		copy(*out, *in)
+	}
+	in.LastModContextSet.DeepCopyInto(&out.LastModContextSet)
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CurveClusterStatus.
@@ -272,6 +337,27 @@ func (in *CurvefsSpec) DeepCopyInto(out *CurvefsSpec) {
 		*out = new(MetaServerSpec)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.S3 != nil {
+		in, out := &in.S3, &out.S3
+		*out = new(S3ConfigSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Fuse != nil {
+		in, out := &in.Fuse, &out.Fuse
+		*out = new(FuseSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.CSI != nil {
+		in, out := &in.CSI, &out.CSI
+		*out = new(CSISpec)
+		(*in).DeepCopyInto(*out)
+	}
+	in.JobResources.DeepCopyInto(&out.JobResources)
+	if in.Monitor != nil {
+		in, out := &in.Monitor, &out.Monitor
+		*out = new(MonitorSpec)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CurvefsSpec.
@@ -295,8 +381,21 @@ func (in *CurvefsStatus) DeepCopyInto(out *CurvefsStatus) {
 		}
 	}
 	out.CurveVersion = in.CurveVersion
+	if in.UpgradeHistory != nil {
+		in, out := &in.UpgradeHistory, &out.UpgradeHistory
+		*out = make([]UpgradeRecord, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	in.Progress.DeepCopyInto(&out.Progress)
 	in.LastModContextSet.DeepCopyInto(&out.LastModContextSet)
 	out.StorageDir = in.StorageDir
+	if in.Mounts != nil {
+		in, out := &in.Mounts, &out.Mounts
+		*out = make([]MountStatus, len(*in))
+		copy(*out, *in)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CurvefsStatus.
@@ -309,6 +408,37 @@ func (in *CurvefsStatus) DeepCopy() *CurvefsStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DeviceSpec) DeepCopyInto(out *DeviceSpec) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DeviceSpec.
+func (in *DeviceSpec) DeepCopy() *DeviceSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(DeviceSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *EmbeddedS3Spec) DeepCopyInto(out *EmbeddedS3Spec) {
+	*out = *in
+	in.Placement.DeepCopyInto(&out.Placement)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new EmbeddedS3Spec.
+func (in *EmbeddedS3Spec) DeepCopy() *EmbeddedS3Spec {
+	if in == nil {
+		return nil
+	}
+	out := new(EmbeddedS3Spec)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *EtcdSpec) DeepCopyInto(out *EtcdSpec) {
 	*out = *in
@@ -329,6 +459,8 @@ func (in *EtcdSpec) DeepCopyInto(out *EtcdSpec) {
 			(*out)[key] = val
 		}
 	}
+	in.Resources.DeepCopyInto(&out.Resources)
+	in.Placement.DeepCopyInto(&out.Placement)
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new EtcdSpec.
@@ -341,9 +473,61 @@ func (in *EtcdSpec) DeepCopy() *EtcdSpec {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *FormatStatus) DeepCopyInto(out *FormatStatus) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new FormatStatus.
+func (in *FormatStatus) DeepCopy() *FormatStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(FormatStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *FuseSpec) DeepCopyInto(out *FuseSpec) {
+	*out = *in
+	if in.Nodes != nil {
+		in, out := &in.Nodes, &out.Nodes
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.FileSystems != nil {
+		in, out := &in.FileSystems, &out.FileSystems
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Config != nil {
+		in, out := &in.Config, &out.Config
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new FuseSpec.
+func (in *FuseSpec) DeepCopy() *FuseSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(FuseSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *GrafanaSpec) DeepCopyInto(out *GrafanaSpec) {
 	*out = *in
+	if in.PasswordSecretRef != nil {
+		in, out := &in.PasswordSecretRef, &out.PasswordSecretRef
+		*out = new(corev1.SecretKeySelector)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GrafanaSpec.
@@ -398,6 +582,8 @@ func (in *MdsSpec) DeepCopyInto(out *MdsSpec) {
 			(*out)[key] = val
 		}
 	}
+	in.Resources.DeepCopyInto(&out.Resources)
+	in.Placement.DeepCopyInto(&out.Placement)
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MdsSpec.
@@ -430,6 +616,8 @@ func (in *MetaServerSpec) DeepCopyInto(out *MetaServerSpec) {
 			(*out)[key] = val
 		}
 	}
+	in.Resources.DeepCopyInto(&out.Resources)
+	in.Placement.DeepCopyInto(&out.Placement)
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MetaServerSpec.
@@ -468,8 +656,14 @@ func (in *ModContext) DeepCopy() *ModContext {
 func (in *MonitorSpec) DeepCopyInto(out *MonitorSpec) {
 	*out = *in
 	out.Prometheus = in.Prometheus
-	out.Grafana = in.Grafana
+	in.Grafana.DeepCopyInto(&out.Grafana)
 	out.NodeExporter = in.NodeExporter
+	if in.ServiceMonitor != nil {
+		in, out := &in.ServiceMonitor, &out.ServiceMonitor
+		*out = new(ServiceMonitorSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	in.Placement.DeepCopyInto(&out.Placement)
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MonitorSpec.
@@ -482,6 +676,41 @@ func (in *MonitorSpec) DeepCopy() *MonitorSpec {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MountStatus) DeepCopyInto(out *MountStatus) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MountStatus.
+func (in *MountStatus) DeepCopy() *MountStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(MountStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NodeDevices) DeepCopyInto(out *NodeDevices) {
+	*out = *in
+	if in.Devices != nil {
+		in, out := &in.Devices, &out.Devices
+		*out = make([]DeviceSpec, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NodeDevices.
+func (in *NodeDevices) DeepCopy() *NodeDevices {
+	if in == nil {
+		return nil
+	}
+	out := new(NodeDevices)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *NodeExporterSpec) DeepCopyInto(out *NodeExporterSpec) {
 	*out = *in
@@ -497,6 +726,53 @@ func (in *NodeExporterSpec) DeepCopy() *NodeExporterSpec {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PlacementSpec) DeepCopyInto(out *PlacementSpec) {
+	*out = *in
+	if in.Tolerations != nil {
+		in, out := &in.Tolerations, &out.Tolerations
+		*out = make([]corev1.Toleration, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.NodeAffinity != nil {
+		in, out := &in.NodeAffinity, &out.NodeAffinity
+		*out = new(corev1.NodeAffinity)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PlacementSpec.
+func (in *PlacementSpec) DeepCopy() *PlacementSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(PlacementSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ProgressStatus) DeepCopyInto(out *ProgressStatus) {
+	*out = *in
+	if in.CompletedSteps != nil {
+		in, out := &in.CompletedSteps, &out.CompletedSteps
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ProgressStatus.
+func (in *ProgressStatus) DeepCopy() *ProgressStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ProgressStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *PrometheusSpec) DeepCopyInto(out *PrometheusSpec) {
 	*out = *in
@@ -515,6 +791,21 @@ func (in *PrometheusSpec) DeepCopy() *PrometheusSpec {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *S3ConfigSpec) DeepCopyInto(out *S3ConfigSpec) {
 	*out = *in
+	if in.AccessKeySecretRef != nil {
+		in, out := &in.AccessKeySecretRef, &out.AccessKeySecretRef
+		*out = new(corev1.SecretKeySelector)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.SecretKeySecretRef != nil {
+		in, out := &in.SecretKeySecretRef, &out.SecretKeySecretRef
+		*out = new(corev1.SecretKeySelector)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Embedded != nil {
+		in, out := &in.Embedded, &out.Embedded
+		*out = new(EmbeddedS3Spec)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new S3ConfigSpec.
@@ -527,6 +818,43 @@ func (in *S3ConfigSpec) DeepCopy() *S3ConfigSpec {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ServiceMonitorSpec) DeepCopyInto(out *ServiceMonitorSpec) {
+	*out = *in
+	if in.Labels != nil {
+		in, out := &in.Labels, &out.Labels
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ServiceMonitorSpec.
+func (in *ServiceMonitorSpec) DeepCopy() *ServiceMonitorSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ServiceMonitorSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ServiceVersion) DeepCopyInto(out *ServiceVersion) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ServiceVersion.
+func (in *ServiceVersion) DeepCopy() *ServiceVersion {
+	if in == nil {
+		return nil
+	}
+	out := new(ServiceVersion)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *SnapShotCloneSpec) DeepCopyInto(out *SnapShotCloneSpec) {
 	*out = *in
@@ -545,7 +873,7 @@ func (in *SnapShotCloneSpec) DeepCopyInto(out *SnapShotCloneSpec) {
 		*out = new(int)
 		**out = **in
 	}
-	out.S3Config = in.S3Config
+	in.S3Config.DeepCopyInto(&out.S3Config)
 	if in.Config != nil {
 		in, out := &in.Config, &out.Config
 		*out = make(map[string]string, len(*in))
@@ -553,6 +881,8 @@ func (in *SnapShotCloneSpec) DeepCopyInto(out *SnapShotCloneSpec) {
 			(*out)[key] = val
 		}
 	}
+	in.Resources.DeepCopyInto(&out.Resources)
+	in.Placement.DeepCopyInto(&out.Placement)
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SnapShotCloneSpec.
@@ -565,6 +895,28 @@ func (in *SnapShotCloneSpec) DeepCopy() *SnapShotCloneSpec {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *StorageClassSpec) DeepCopyInto(out *StorageClassSpec) {
+	*out = *in
+	if in.Parameters != nil {
+		in, out := &in.Parameters, &out.Parameters
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new StorageClassSpec.
+func (in *StorageClassSpec) DeepCopy() *StorageClassSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(StorageClassSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *StorageScopeSpec) DeepCopyInto(out *StorageScopeSpec) {
 	*out = *in
@@ -573,6 +925,18 @@ func (in *StorageScopeSpec) DeepCopyInto(out *StorageScopeSpec) {
 		*out = new(int)
 		**out = **in
 	}
+	if in.Devices != nil {
+		in, out := &in.Devices, &out.Devices
+		*out = make([]DeviceSpec, len(*in))
+		copy(*out, *in)
+	}
+	if in.SelectedNodes != nil {
+		in, out := &in.SelectedNodes, &out.SelectedNodes
+		*out = make([]NodeDevices, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 	if in.Config != nil {
 		in, out := &in.Config, &out.Config
 		*out = make(map[string]string, len(*in))
@@ -580,6 +944,8 @@ func (in *StorageScopeSpec) DeepCopyInto(out *StorageScopeSpec) {
 			(*out)[key] = val
 		}
 	}
+	in.Resources.DeepCopyInto(&out.Resources)
+	in.Placement.DeepCopyInto(&out.Placement)
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new StorageScopeSpec.
@@ -606,3 +972,28 @@ func (in *StorageStatusDir) DeepCopy() *StorageStatusDir {
 	in.DeepCopyInto(out)
 	return out
 }
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *UpgradeRecord) DeepCopyInto(out *UpgradeRecord) {
+	*out = *in
+	in.StartedAt.DeepCopyInto(&out.StartedAt)
+	if in.FinishedAt != nil {
+		in, out := &in.FinishedAt, &out.FinishedAt
+		*out = (*in).DeepCopy()
+	}
+	if in.Services != nil {
+		in, out := &in.Services, &out.Services
+		*out = make([]ServiceVersion, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new UpgradeRecord.
+func (in *UpgradeRecord) DeepCopy() *UpgradeRecord {
+	if in == nil {
+		return nil
+	}
+	out := new(UpgradeRecord)
+	in.DeepCopyInto(out)
+	return out
+}
@@ -115,6 +115,7 @@ func (opts *CurveOptions) Run() error {
 		mgr.GetClient(),
 		ctrl.Log.WithName("controllers").WithName("CurveCluster"),
 		mgr.GetScheme(),
+		mgr.GetEventRecorderFor("curvecluster-controller"),
 		context,
 	)).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "CurveCluster")
@@ -124,6 +125,7 @@ func (opts *CurveOptions) Run() error {
 		mgr.GetClient(),
 		ctrl.Log.WithName("controllers").WithName("Curvefs"),
 		mgr.GetScheme(),
+		mgr.GetEventRecorderFor("curvefs-controller"),
 		context,
 	)).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "CurvefsCluster")
@@ -3,6 +3,8 @@ package clusterd
 import (
 	"github.com/go-logr/logr"
 	curvev1 "github.com/opencurve/curve-operator/api/v1"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
 )
 
 var _ Clusterer = &BsClusterManager{}
@@ -12,7 +14,6 @@ type BsClusterManager struct {
 	Cluster *curvev1.CurveCluster
 	Logger  logr.Logger
 
-	UUID      string
 	Kind      string
 	OwnerInfo *OwnerInfo
 }
@@ -20,13 +21,13 @@ type BsClusterManager struct {
 func (c *BsClusterManager) GetContext() Context            { return c.Context }
 func (c *BsClusterManager) GetName() string                { return c.Cluster.Name }
 func (c *BsClusterManager) GetNameSpace() string           { return c.Cluster.Namespace }
-func (c *BsClusterManager) GetUUID() string                { return c.UUID }
+func (c *BsClusterManager) GetUUID() string                { return c.Cluster.Status.UUID }
 func (c *BsClusterManager) GetKind() string                { return c.Kind }
 func (c *BsClusterManager) GetOwnerInfo() *OwnerInfo       { return c.OwnerInfo }
 func (c *BsClusterManager) GetNodes() []string             { return c.Cluster.Spec.Nodes }
 func (c *BsClusterManager) GetDataDir() string             { return c.Cluster.Spec.DataDir }
 func (c *BsClusterManager) GetLogDir() string              { return c.Cluster.Spec.LogDir }
-func (c *BsClusterManager) GetContainerImage() string      { return c.Cluster.Spec.CurveVersion.Image }
+func (c *BsClusterManager) GetContainerImage() string      { return c.getRolloutVersion().Image }
 func (c *BsClusterManager) GetCopysets() int               { return *c.Cluster.Spec.Copysets }
 func (c *BsClusterManager) GetEtcdSpec() *curvev1.EtcdSpec { return c.Cluster.Spec.Etcd }
 func (c *BsClusterManager) GetMdsSpec() *curvev1.MdsSpec   { return c.Cluster.Spec.Mds }
@@ -37,8 +38,49 @@ func (c *BsClusterManager) GetMetaserverSpec() *curvev1.MetaServerSpec { return
 func (c *BsClusterManager) GetSnapShotSpec() *curvev1.SnapShotCloneSpec {
 	return c.Cluster.Spec.SnapShotClone
 }
+
+// GetS3Spec returns the S3 config of snapshotclone, it's nil if snapshotclone is disabled
+func (c *BsClusterManager) GetS3Spec() *curvev1.S3ConfigSpec {
+	if c.Cluster.Spec.SnapShotClone == nil || !c.Cluster.Spec.SnapShotClone.Enable {
+		return nil
+	}
+	return &c.Cluster.Spec.SnapShotClone.S3Config
+}
+func (c *BsClusterManager) GetCSISpec() *curvev1.CSISpec         { return c.Cluster.Spec.CSI }
+func (c *BsClusterManager) GetMonitorSpec() *curvev1.MonitorSpec { return c.Cluster.Spec.Monitor }
+func (c *BsClusterManager) GetJobResources() v1.ResourceRequirements {
+	return c.Cluster.Spec.JobResources
+}
+func (c *BsClusterManager) GetImagePullPolicy() v1.PullPolicy {
+	return c.getRolloutVersion().ImagePullPolicy
+}
+func (c *BsClusterManager) GetObject() runtime.Object { return c.Cluster }
+func (c *BsClusterManager) GetProgress() *curvev1.ProgressStatus {
+	return &c.Cluster.Status.Progress
+}
+func (c *BsClusterManager) GetPhase() curvev1.ClusterPhase { return c.Cluster.Status.Phase }
+func (c *BsClusterManager) GetVersion() *curvev1.CurveVersionSpec {
+	return &c.Cluster.Status.CurveVersion
+}
+func (c *BsClusterManager) GetUpgradeHistory() *[]curvev1.UpgradeRecord {
+	return &c.Cluster.Status.UpgradeHistory
+}
+func (c *BsClusterManager) GetFormatStatus() *[]curvev1.FormatStatus {
+	return &c.Cluster.Status.FormatStatus
+}
+
+// getRolloutVersion returns the version that the services are deployed with
+func (c *BsClusterManager) getRolloutVersion() curvev1.CurveVersionSpec {
+	return rolloutVersion(c.GetPhase(), c.Cluster.Spec.CurveVersion, c.Cluster.Status.CurveVersion)
+}
 func (c *BsClusterManager) GetRoleInstances(role string) int {
 	switch role {
+	case ROLE_SNAPSHOTCLONE:
+		// snapshotclone is deployed like mds once it's enabled
+		if c.Cluster.Spec.SnapShotClone == nil || !c.Cluster.Spec.SnapShotClone.Enable {
+			return 0
+		}
+		fallthrough
 	case ROLE_ETCD, ROLE_MDS:
 		if len(c.GetNodes()) == 1 { // stand alone
 			return 3
@@ -58,6 +100,8 @@ func (c *BsClusterManager) GetRolePort(role string) int {
 		return *c.Cluster.Spec.Mds.Port
 	case ROLE_CHUNKSERVER:
 		return *c.Cluster.Spec.Chunkserver.Port
+	case ROLE_SNAPSHOTCLONE:
+		return *c.Cluster.Spec.SnapShotClone.Port
 	default:
 		return 0
 	}
@@ -101,7 +145,7 @@ func (c *BsClusterManager) GetRoleConfigs(role string) map[string]string {
 		return c.Cluster.Spec.Etcd.Config
 	case ROLE_MDS:
 		return c.Cluster.Spec.Mds.Config
-	case ROLE_METASERVER:
+	case ROLE_CHUNKSERVER:
 		return c.Cluster.Spec.Chunkserver.Config
 	case ROLE_SNAPSHOTCLONE:
 		return c.Cluster.Spec.SnapShotClone.Config
@@ -1,6 +1,9 @@
 package clusterd
 
 import (
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+
 	curvev1 "github.com/opencurve/curve-operator/api/v1"
 )
 
@@ -12,8 +15,15 @@ type Clusterer interface {
 	GetUUID() string
 	GetKind() string
 	GetOwnerInfo() *OwnerInfo
+	GetObject() runtime.Object
+	GetProgress() *curvev1.ProgressStatus
+	GetPhase() curvev1.ClusterPhase
+	GetVersion() *curvev1.CurveVersionSpec
+	GetUpgradeHistory() *[]curvev1.UpgradeRecord
+	GetFormatStatus() *[]curvev1.FormatStatus
 
 	GetContainerImage() string
+	GetImagePullPolicy() v1.PullPolicy
 	GetNodes() []string
 	GetDataDir() string
 	GetLogDir() string
@@ -23,6 +33,10 @@ type Clusterer interface {
 	GetChunkserverSpec() *curvev1.StorageScopeSpec
 	GetMetaserverSpec() *curvev1.MetaServerSpec
 	GetSnapShotSpec() *curvev1.SnapShotCloneSpec
+	GetS3Spec() *curvev1.S3ConfigSpec
+	GetCSISpec() *curvev1.CSISpec
+	GetMonitorSpec() *curvev1.MonitorSpec
+	GetJobResources() v1.ResourceRequirements
 
 	GetRoleInstances(role string) int
 	GetRolePort(role string) int
@@ -3,6 +3,7 @@ package clusterd
 import (
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/record"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
@@ -15,4 +16,7 @@ type Context struct {
 
 	// Represents the Client provided by the controller-runtime package to interact with Kubernetes objects
 	Client client.Client
+
+	// Recorder emits the events of reconcile steps on the cluster custom resource
+	Recorder record.EventRecorder
 }
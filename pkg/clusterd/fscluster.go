@@ -3,6 +3,8 @@ package clusterd
 import (
 	"github.com/go-logr/logr"
 	curvev1 "github.com/opencurve/curve-operator/api/v1"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
 )
 
 var _ Clusterer = &FsClusterManager{}
@@ -12,7 +14,6 @@ type FsClusterManager struct {
 	Cluster *curvev1.Curvefs
 	Logger  logr.Logger
 
-	UUID      string
 	Kind      string
 	OwnerInfo *OwnerInfo
 }
@@ -20,13 +21,13 @@ type FsClusterManager struct {
 func (c *FsClusterManager) GetContext() Context                           { return c.Context }
 func (c *FsClusterManager) GetName() string                               { return c.Cluster.Name }
 func (c *FsClusterManager) GetNameSpace() string                          { return c.Cluster.Namespace }
-func (c *FsClusterManager) GetUUID() string                               { return c.UUID }
+func (c *FsClusterManager) GetUUID() string                               { return c.Cluster.Status.UUID }
 func (c *FsClusterManager) GetKind() string                               { return c.Kind }
 func (c *FsClusterManager) GetOwnerInfo() *OwnerInfo                      { return c.OwnerInfo }
 func (c *FsClusterManager) GetNodes() []string                            { return c.Cluster.Spec.Nodes }
 func (c *FsClusterManager) GetDataDir() string                            { return c.Cluster.Spec.DataDir }
 func (c *FsClusterManager) GetLogDir() string                             { return c.Cluster.Spec.LogDir }
-func (c *FsClusterManager) GetContainerImage() string                     { return c.Cluster.Spec.CurveVersion.Image }
+func (c *FsClusterManager) GetContainerImage() string                     { return c.getRolloutVersion().Image }
 func (c *FsClusterManager) GetCopysets() int                              { return *c.Cluster.Spec.Copysets }
 func (c *FsClusterManager) GetEtcdSpec() *curvev1.EtcdSpec                { return c.Cluster.Spec.Etcd }
 func (c *FsClusterManager) GetMdsSpec() *curvev1.MdsSpec                  { return c.Cluster.Spec.Mds }
@@ -34,7 +35,33 @@ func (c *FsClusterManager) GetChunkserverSpec() *curvev1.StorageScopeSpec { retu
 func (c *FsClusterManager) GetMetaserverSpec() *curvev1.MetaServerSpec {
 	return c.Cluster.Spec.MetaServer
 }
+func (c *FsClusterManager) GetImagePullPolicy() v1.PullPolicy {
+	return c.getRolloutVersion().ImagePullPolicy
+}
+func (c *FsClusterManager) GetObject() runtime.Object                   { return c.Cluster }
 func (c *FsClusterManager) GetSnapShotSpec() *curvev1.SnapShotCloneSpec { return nil }
+func (c *FsClusterManager) GetS3Spec() *curvev1.S3ConfigSpec            { return c.Cluster.Spec.S3 }
+func (c *FsClusterManager) GetCSISpec() *curvev1.CSISpec                { return c.Cluster.Spec.CSI }
+func (c *FsClusterManager) GetMonitorSpec() *curvev1.MonitorSpec        { return c.Cluster.Spec.Monitor }
+func (c *FsClusterManager) GetJobResources() v1.ResourceRequirements {
+	return c.Cluster.Spec.JobResources
+}
+func (c *FsClusterManager) GetProgress() *curvev1.ProgressStatus {
+	return &c.Cluster.Status.Progress
+}
+func (c *FsClusterManager) GetPhase() curvev1.ClusterPhase { return c.Cluster.Status.Phase }
+func (c *FsClusterManager) GetVersion() *curvev1.CurveVersionSpec {
+	return &c.Cluster.Status.CurveVersion
+}
+func (c *FsClusterManager) GetUpgradeHistory() *[]curvev1.UpgradeRecord {
+	return &c.Cluster.Status.UpgradeHistory
+}
+func (c *FsClusterManager) GetFormatStatus() *[]curvev1.FormatStatus { return nil }
+
+// getRolloutVersion returns the version that the services are deployed with
+func (c *FsClusterManager) getRolloutVersion() curvev1.CurveVersionSpec {
+	return rolloutVersion(c.GetPhase(), c.Cluster.Spec.CurveVersion, c.Cluster.Status.CurveVersion)
+}
 func (c *FsClusterManager) GetRoleInstances(role string) int {
 	switch role {
 	case ROLE_ETCD, ROLE_MDS:
@@ -1,5 +1,11 @@
 package clusterd
 
+import (
+	"fmt"
+
+	curvev1 "github.com/opencurve/curve-operator/api/v1"
+)
+
 const (
 	KIND_CURVEBS = "curvebs"
 	KIND_CURVEFS = "curvefs"
@@ -12,3 +18,19 @@ const (
 	ROLE_SNAPSHOTCLONE = "snapshotclone"
 	ROLE_METASERVER    = "metaserver"
 )
+
+// ResourceName returns the name of a resource generated for the cluster. The kind and the name
+// of cluster are prefixed so that the clusters in the same namespace don't overwrite each other.
+func ResourceName(c Clusterer, suffix string) string {
+	return fmt.Sprintf("%s-%s-%s", c.GetKind(), c.GetName(), suffix)
+}
+
+// rolloutVersion returns the version that the services are deployed with. The version in spec is
+// only rolled out in Upgrading phase, otherwise the version in status is kept so that a rolled back
+// or not yet started upgrade isn't deployed by other phases.
+func rolloutVersion(phase curvev1.ClusterPhase, spec, status curvev1.CurveVersionSpec) curvev1.CurveVersionSpec {
+	if phase == curvev1.ClusterUpgrading || len(status.Image) == 0 {
+		return spec
+	}
+	return status
+}
@@ -1,46 +1,88 @@
 package controllers
 
 import (
+	"fmt"
+	"strings"
+	"time"
+
 	"github.com/coreos/pkg/capnslog"
+	"github.com/go-logr/logr"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 
+	curvev1 "github.com/opencurve/curve-operator/api/v1"
 	"github.com/opencurve/curve-operator/pkg/clusterd"
+	"github.com/opencurve/curve-operator/pkg/k8sutil"
 	"github.com/opencurve/curve-operator/pkg/service"
 	"github.com/opencurve/curve-operator/pkg/topology"
+	"github.com/opencurve/curve-operator/pkg/utils"
 )
 
 var logger = capnslog.NewPackageLogger("github.com/opencurve/curve-operator", "controller")
 
 const (
 	REGEX_KV_SPLIT = "^(([^%s]+)%s\\s*)([^\\s#]*)"
+
+	STEP_CONFIG_TEMPLATE = "config-template"
+	STEP_START_ROLE      = "start-%s"
+	STEP_CREATE_POOL     = "create-%s"
+
+	// waitingRequeueInterval is the interval to check again the resources that are not ready yet
+	waitingRequeueInterval = 10 * time.Second
 )
 
-func newFsClusterManager(uuid, kind string) *clusterd.FsClusterManager {
+// stepFunc runs a reconcile step, it returns true if the step is completed or false
+// if it's waiting for some resources to be ready and should be run again.
+type stepFunc func(cluster clusterd.Clusterer, dcs []*topology.DeployConfig) (bool, error)
+
+// reconcileStep is a named step of reconcile recorded in the progress of the cluster status
+type reconcileStep struct {
+	name string
+	run  stepFunc
+}
+
+// roleConditionTypes maps the role to the condition that records whether its services are started
+var roleConditionTypes = map[string]curvev1.ConditionType{
+	topology.ROLE_ETCD:          curvev1.ConditionEtcdReady,
+	topology.ROLE_MDS:           curvev1.ConditionMdsReady,
+	topology.ROLE_CHUNKSERVER:   curvev1.ConditionChunkserversReady,
+	topology.ROLE_SNAPSHOTCLONE: curvev1.ConditionSnapShotCloneReady,
+	topology.ROLE_METASERVER:    curvev1.ConditionMetaserversReady,
+}
+
+func newFsClusterManager(context clusterd.Context, cluster *curvev1.Curvefs,
+	ownerInfo *clusterd.OwnerInfo, logger logr.Logger) *clusterd.FsClusterManager {
 	return &clusterd.FsClusterManager{
-		UUID: uuid,
-		Kind: kind,
+		Context:   context,
+		Cluster:   cluster,
+		Logger:    logger,
+		Kind:      clusterd.KIND_CURVEFS,
+		OwnerInfo: ownerInfo,
 	}
 }
 
-func newBsClusterManager(uuid, kind string) *clusterd.BsClusterManager {
+func newBsClusterManager(context clusterd.Context, cluster *curvev1.CurveCluster,
+	ownerInfo *clusterd.OwnerInfo, logger logr.Logger) *clusterd.BsClusterManager {
 	return &clusterd.BsClusterManager{
-		UUID: uuid,
-		Kind: kind,
+		Context:   context,
+		Cluster:   cluster,
+		Logger:    logger,
+		Kind:      clusterd.KIND_CURVEBS,
+		OwnerInfo: ownerInfo,
 	}
 }
 
-// initCluster initialize a new cluster
-func initCluster(cluster clusterd.Clusterer, dcs []*topology.DeployConfig) error {
+// initCluster initialize a new cluster, it returns false if some resources are not ready yet
+// and the reconcile should be requeued to check them again.
+func initCluster(cluster clusterd.Clusterer, dcs []*topology.DeployConfig) (bool, error) {
 	err := preClusterStartValidation(cluster)
 	if err != nil {
-		return err
+		return false, err
 	}
 
-	err = reconcileCluster(cluster, dcs)
-	if err != nil {
-		return err
-	}
-
-	return nil
+	return runSteps(cluster, dcs, getCreateSteps(cluster))
 }
 
 // preClusterStartValidation cluster Spec validation
@@ -48,55 +90,268 @@ func preClusterStartValidation(cluster clusterd.Clusterer) error {
 	return nil
 }
 
-func reconcileCluster(cluster clusterd.Clusterer, dcs []*topology.DeployConfig) error {
-	if err := constructConfigMap(cluster, dcs); err != nil {
-		return err
+// getCreateSteps returns the steps to create the cluster in order: extract the config
+// templates, then start the services of each role and create the pools after their role.
+// The monitor is started at last if the bundled monitor or the ServiceMonitors are enabled.
+func getCreateSteps(cluster clusterd.Clusterer) []reconcileStep {
+	steps := []reconcileStep{{name: STEP_CONFIG_TEMPLATE, run: constructConfigMap}}
+	if service.IsEmbeddedS3Enabled(cluster) {
+		steps = append(steps, reconcileStep{name: STEP_START_S3, run: startEmbeddedS3Step})
 	}
-	if err := reconcileCurveDaemons(cluster, dcs); err != nil {
-		return err
+	for _, role := range getClusterRoles(cluster) {
+		steps = append(steps, reconcileStep{name: fmt.Sprintf(STEP_START_ROLE, role), run: startRoleStep(role)})
+
+		poolType, conditionType := getRolePoolType(cluster, role)
+		if len(poolType) == 0 {
+			continue
+		}
+		steps = append(steps, reconcileStep{
+			name: fmt.Sprintf(STEP_CREATE_POOL, poolType),
+			run:  createPoolStep(role, poolType, conditionType),
+		})
+	}
+	if isMonitorEnabled(cluster) || isServiceMonitorEnabled(cluster) {
+		steps = append(steps, reconcileStep{name: STEP_START_MONITOR, run: startMonitorStep})
+	}
+	return steps
+}
+
+// runSteps runs the steps in order and skips the completed ones, it stops at the first step
+// that is not completed and records it in the progress of the cluster status.
+func runSteps(cluster clusterd.Clusterer, dcs []*topology.DeployConfig, steps []reconcileStep) (bool, error) {
+	progress := cluster.GetProgress()
+	completed := utils.Slice2Map(progress.CompletedSteps)
+	for _, step := range steps {
+		if completed[step.name] {
+			continue
+		}
+
+		progress.Step = step.name
+		done, err := step.run(cluster, dcs)
+		if err != nil {
+			return false, err
+		}
+		if !done {
+			logger.Infof("cluster %q is waiting for step %q: %s", cluster.GetName(), step.name, progress.Message)
+			return false, nil
+		}
+
+		progress.CompletedSteps = append(progress.CompletedSteps, step.name)
+		progress.Message = ""
+	}
+
+	progress.Step = ""
+	return true, nil
+}
+
+// startRoleStep returns the step that renders the configs and starts the services of one role,
+// the step is completed when all the Deployments of the role are ready.
+func startRoleStep(role string) stepFunc {
+	return func(cluster clusterd.Clusterer, dcs []*topology.DeployConfig) (bool, error) {
+		roleDcs := topology.FilterDeployConfigByRole(dcs, role)
+		if len(roleDcs) == 0 {
+			return true, nil
+		}
+
+		conditionType := roleConditionTypes[role]
+		var ready bool
+		var err error
+		if role == topology.ROLE_CHUNKSERVER && useDevices(cluster) {
+			// the chunkservers are started one by one once their devices are formatted
+			ready, err = startFormattedChunkservers(cluster, roleDcs)
+		} else {
+			ready, err = startRoleDaemons(cluster, roleDcs)
+		}
+		if err != nil {
+			updateRoleCondition(cluster, conditionType, curvev1.ConditionStatusFalse,
+				curvev1.ConditionReconcileFailed, err.Error())
+			return false, err
+		}
+		if !ready {
+			updateRoleCondition(cluster, conditionType, curvev1.ConditionStatusFalse,
+				curvev1.ConditionWaitingForReady, cluster.GetProgress().Message)
+			return false, nil
+		}
+
+		updateRoleCondition(cluster, conditionType, curvev1.ConditionStatusTrue,
+			curvev1.ConditionReconcileSucceeded, fmt.Sprintf("all %d %s services are ready", len(roleDcs), role))
+		return true, nil
+	}
+}
+
+// createPoolStep returns the step that runs the job to create the pool after the services of
+// role are started, the step is completed when the job is completed.
+func createPoolStep(role, poolType string, conditionType curvev1.ConditionType) stepFunc {
+	return func(cluster clusterd.Clusterer, dcs []*topology.DeployConfig) (bool, error) {
+		roleDcs := topology.FilterDeployConfigByRole(dcs, role)
+		if len(roleDcs) == 0 {
+			return true, nil
+		}
+
+		job, err := service.StartJobCreatePool(cluster, roleDcs[0], dcs, poolType)
+		if err != nil {
+			updateRoleCondition(cluster, conditionType, curvev1.ConditionStatusFalse,
+				curvev1.ConditionReconcileFailed, err.Error())
+			return false, err
+		}
+
+		switch {
+		case k8sutil.IsJobCompleted(job):
+			updateRoleCondition(cluster, conditionType, curvev1.ConditionStatusTrue,
+				curvev1.ConditionReconcileSucceeded, fmt.Sprintf("%s is created", poolType))
+			return true, nil
+		case k8sutil.IsJobFailed(job):
+			incPoolJobFailures(cluster, poolType)
+			// delete the failed job so that it's created again by the next reconcile
+			if err := k8sutil.DeleteBatchJob(cluster.GetContext().Clientset, job.Namespace, job.Name, false); err != nil {
+				logger.Errorf("failed to delete the failed job %q. %v", job.Name, err)
+			}
+			err := errors.Errorf("job %q to create %s failed", job.Name, poolType)
+			updateRoleCondition(cluster, conditionType, curvev1.ConditionStatusFalse,
+				curvev1.ConditionReconcileFailed, err.Error())
+			return false, err
+		}
+
+		cluster.GetProgress().Message = fmt.Sprintf("waiting for job %q to create %s", job.Name, poolType)
+		updateRoleCondition(cluster, conditionType, curvev1.ConditionStatusUnknown,
+			curvev1.ConditionWaitingForReady, cluster.GetProgress().Message)
+		return false, nil
 	}
-	return nil
 }
 
-// reconcileCurveDaemons start all daemon progress of Curve of specified type
-func reconcileCurveDaemons(cluster clusterd.Clusterer, dcs []*topology.DeployConfig) error {
+// requeueForWaiting persists the progress of the cluster and requeues the reconcile
+// to check the resources that are not ready yet again.
+func requeueForWaiting(cluster clusterd.Clusterer) (ctrl.Result, error) {
+	if err := k8sutil.UpdateClusterStatus(cluster); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+	return ctrl.Result{RequeueAfter: waitingRequeueInterval}, nil
+}
+
+// getRolePoolType returns the pool to create after the services of role are started
+func getRolePoolType(cluster clusterd.Clusterer, role string) (string, curvev1.ConditionType) {
+	switch {
+	case cluster.GetKind() == topology.KIND_CURVEBS && role == topology.ROLE_MDS:
+		// 创建物理池
+		return service.POOL_TYPE_PHYSICAL, curvev1.ConditionPhysicalPoolCreated
+	case cluster.GetKind() == topology.KIND_CURVEBS && role == topology.ROLE_CHUNKSERVER:
+		// 创建逻辑池
+		return service.POOL_TYPE_LOGICAL, curvev1.ConditionLogicalPoolCreated
+	case cluster.GetKind() == topology.KIND_CURVEFS && role == topology.ROLE_MDS:
+		// 创建逻辑池
+		return service.POOL_TYPE_LOGICAL, curvev1.ConditionLogicalPoolCreated
+	}
+	return "", ""
+}
+
+// startRoleDaemons renders the configs and starts the services of one role,
+// it returns false if some of the services are not ready yet.
+func startRoleDaemons(cluster clusterd.Clusterer, dcs []*topology.DeployConfig) (bool, error) {
 	for _, dc := range dcs {
 		serviceConfigs := dc.GetProjectLayout().ServiceConfFiles
 		for _, conf := range serviceConfigs {
 			err := mutateConfig(cluster, dc, conf.Name)
 			if err != nil {
-				return err
+				return false, err
 			}
 		}
 		// mutate tools.conf in configmp
 		if err := mutateConfig(cluster, dc, topology.LAYOUT_TOOLS_NAME); err != nil {
-			return err
+			return false, err
 		}
+	}
 
-		// start specified service
-		if err := service.StartService(cluster, dc); err != nil {
-			return err
-		}
+	return startServices(cluster, dcs)
+}
 
-		if dc.GetKind() == topology.KIND_CURVEBS && dc.GetRole() == topology.ROLE_MDS {
-			// 创建物理池
-			if err := service.StartJobCreatePool(cluster, dc, dcs, service.POOL_TYPE_PHYSICAL); err != nil {
-				return err
-			}
-		} else if dc.GetKind() == topology.KIND_CURVEBS && dc.GetRole() == topology.ROLE_CHUNKSERVER {
-			// 创建逻辑池
-			if err := service.StartJobCreatePool(cluster, dc, dcs, service.POOL_TYPE_LOGICAL); err != nil {
-				return err
-			}
-		} else if dc.GetKind() == topology.KIND_CURVEFS && dc.GetRole() == topology.ROLE_MDS {
-			// 创建逻辑池
-			if err := service.StartJobCreatePool(cluster, dc, dcs, service.POOL_TYPE_LOGICAL); err != nil {
-				return err
-			}
+// startServices creates or updates the Deployments of the services and checks their readiness,
+// it returns false and records the services that are not ready in the progress if any.
+func startServices(cluster clusterd.Clusterer, dcs []*topology.DeployConfig) (bool, error) {
+	notReady := []string{}
+	for _, dc := range dcs {
+		d, err := service.StartService(cluster, dc)
+		if err != nil {
+			return false, err
+		}
+		if k8sutil.IsDeploymentProgressDeadlineExceeded(d) {
+			return false, errors.Errorf("Deployment %q of %s service failed to progress", d.Name, dc.GetRole())
+		}
+		setServiceReadyMetric(cluster, dc, d)
+		if !k8sutil.IsDeploymentReady(d) {
+			notReady = append(notReady, d.Name)
 		}
 	}
 
-	return nil
+	if len(notReady) > 0 {
+		cluster.GetProgress().Message = fmt.Sprintf("waiting for %d of %d services to be ready: %s",
+			len(notReady), len(dcs), strings.Join(notReady, ", "))
+		return false, nil
+	}
+	return true, nil
+}
+
+// getClusterRoles returns the roles of the cluster in the order to start
+func getClusterRoles(cluster clusterd.Clusterer) []string {
+	if cluster.GetKind() == topology.KIND_CURVEFS {
+		return topology.CURVEFS_ROLES
+	}
+	return topology.CURVEBS_ROLES
+}
+
+// updateRoleCondition records a per-role condition of the cluster, the failure to record it
+// is only logged to not interrupt the reconcile.
+func updateRoleCondition(cluster clusterd.Clusterer, conditionType curvev1.ConditionType,
+	status curvev1.ConditionStatus, reason curvev1.ConditionReason, message string) {
+	err := k8sutil.UpdateClusterSubCondition(cluster, curvev1.ClusterCondition{
+		Type:    conditionType,
+		Status:  status,
+		Reason:  reason,
+		Message: message,
+	})
+	if err != nil {
+		logger.Errorf("failed to update condition %q of cluster %q. %v", conditionType, cluster.GetName(), err)
+	}
+}
+
+// updateClusterProgressing moves the cluster to phase and records the Progressing condition,
+// the progress of the previous phase is reset.
+func updateClusterProgressing(cluster clusterd.Clusterer, phase curvev1.ClusterPhase,
+	reason curvev1.ConditionReason, message string) error {
+	*cluster.GetProgress() = curvev1.ProgressStatus{}
+	return k8sutil.UpdateClusterCondition(cluster, phase, curvev1.ClusterCondition{
+		Type:    curvev1.ConditionProgressing,
+		Status:  curvev1.ConditionStatusTrue,
+		Reason:  reason,
+		Message: message,
+	})
+}
+
+// updateClusterReady moves the cluster to 'Running' and records the Ready condition,
+// the progress of the previous phase is reset.
+func updateClusterReady(cluster clusterd.Clusterer, message string) error {
+	*cluster.GetProgress() = curvev1.ProgressStatus{}
+	return k8sutil.UpdateClusterCondition(cluster, curvev1.ClusterRunning, curvev1.ClusterCondition{
+		Type:    curvev1.ConditionClusterReady,
+		Status:  curvev1.ConditionStatusTrue,
+		Reason:  curvev1.ConditionReconcileSucceeded,
+		Message: message,
+	})
+}
+
+// updateClusterFailed records the Failed condition with the error and keeps the cluster
+// in phase so that the failed step is retried.
+func updateClusterFailed(cluster clusterd.Clusterer, phase curvev1.ClusterPhase, reconcileErr error) {
+	err := k8sutil.UpdateClusterCondition(cluster, phase, curvev1.ClusterCondition{
+		Type:    curvev1.ConditionFailure,
+		Status:  curvev1.ConditionStatusTrue,
+		Reason:  curvev1.ConditionReconcileFailed,
+		Message: reconcileErr.Error(),
+	})
+	if err != nil {
+		logger.Errorf("failed to update failure condition of cluster %q. %v", cluster.GetName(), err)
+	}
+	k8sutil.RecordEvent(cluster, corev1.EventTypeWarning, k8sutil.ReasonReconcileFailed,
+		"failed to reconcile cluster in phase %q: %v", phase, reconcileErr)
 }
 
 // // reconcileCurveDaemons start all daemon progress of Curve
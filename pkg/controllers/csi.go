@@ -0,0 +1,137 @@
+package controllers
+
+import (
+	"fmt"
+	"strings"
+
+	v1 "k8s.io/api/core/v1"
+
+	"github.com/opencurve/curve-operator/pkg/clusterd"
+	"github.com/opencurve/curve-operator/pkg/csi"
+	"github.com/opencurve/curve-operator/pkg/k8sutil"
+	"github.com/opencurve/curve-operator/pkg/service"
+	"github.com/opencurve/curve-operator/pkg/topology"
+)
+
+const (
+	// CLIENT_MDS_LISTEN_ADDR is the item of client.conf of curvebs that is the address of mds
+	CLIENT_MDS_LISTEN_ADDR = "mds.listen.addr"
+
+	// CURVEBS_CSI_CONFIG_DIR is the directory that curve-csi reads client.conf from
+	CURVEBS_CSI_CONFIG_DIR = "/etc/curve"
+
+	// CURVEFS_CSI_FS_TYPE is the type of the file systems that curvefs-csi creates for the PVCs
+	CURVEFS_CSI_FS_TYPE = "s3"
+)
+
+// isCSIEnabled returns true if the CSI driver of cluster is deployed
+func isCSIEnabled(cluster clusterd.Clusterer) bool {
+	csiSpec := cluster.GetCSISpec()
+	return csiSpec != nil && csiSpec.Enable
+}
+
+// renderCSIClientConfig renders client.conf of the CSI plugins that points at the mds of cluster
+func renderCSIClientConfig(cluster clusterd.Clusterer, dc *topology.DeployConfig, specConfig map[string]string) (string, error) {
+	templateCM, err := k8sutil.GetConfigMapByName(cluster.GetContext().Clientset, cluster.GetNameSpace(), getConfigTemplateName(cluster))
+	if err != nil {
+		return "", err
+	}
+	return renderConfig(dc, templateCM.Data[topology.LAYOUT_CLIENT_NAME], topology.LAYOUT_CLIENT_NAME, specConfig)
+}
+
+// getCurveBsCSIDriver returns the driver of curve-csi that maps the volumes of curvebs by NBD
+func getCurveBsCSIDriver(cluster clusterd.Clusterer, dc *topology.DeployConfig) (*csi.Driver, error) {
+	clientConf, err := renderCSIClientConfig(cluster, dc, map[string]string{
+		strings.ToLower(CLIENT_MDS_LISTEN_ADDR): "${cluster_mds_addr}",
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	args := []string{
+		fmt.Sprintf("--drivername=%s", cluster.GetCSISpec().DriverName),
+		"--nodeid=$(NODE_ID)",
+		"--logtostderr=true",
+	}
+	devVol, devMount := csi.NewHostPathVolume("host-dev", "/dev", v1.HostPathDirectory)
+	sysVol, sysMount := csi.NewHostPathVolume("host-sys", "/sys", v1.HostPathDirectory)
+	modulesVol, modulesMount := csi.NewHostPathVolume("lib-modules", "/lib/modules", v1.HostPathDirectory)
+	modulesMount.ReadOnly = true
+	return &csi.Driver{
+		Config:    map[string]string{topology.LAYOUT_CLIENT_NAME: clientConf},
+		ConfigDir: CURVEBS_CSI_CONFIG_DIR,
+		Parameters: map[string]string{
+			"user":      "k8s",
+			"cloneLazy": "true",
+		},
+		AllowVolumeExpansion: true,
+		ControllerArgs:       append(args, "--controller-server=true"),
+		NodeArgs:             append(args, "--node-server=true"),
+		NodeVolumes:          []v1.Volume{devVol, sysVol, modulesVol},
+		NodeVolumeMounts:     []v1.VolumeMount{devMount, sysMount, modulesMount},
+	}, nil
+}
+
+// getCurveFsCSIDriver returns the driver of curvefs-csi that creates a file system for each PVC and mounts
+// it by FUSE. curvefs-csi has no config file, the mds and the S3 service are passed to it by the parameters
+// of StorageClass, so the credentials of S3 are visible to the ones that can read the StorageClass.
+func getCurveFsCSIDriver(cluster clusterd.Clusterer, dc *topology.DeployConfig) (*csi.Driver, error) {
+	mdsAddr, err := dc.GetVariables().Get("cluster_mds_addr")
+	if err != nil {
+		return nil, err
+	}
+	s3Config, err := service.GetS3Config(cluster)
+	if err != nil {
+		return nil, err
+	}
+
+	args := []string{
+		"--nodeid=$(NODE_ID)",
+		"--alsologtostderr",
+		"--v=2",
+	}
+	// /dev/fuse is used to mount the file systems
+	devVol, devMount := csi.NewHostPathVolume("host-dev", "/dev", v1.HostPathDirectory)
+	return &csi.Driver{
+		Parameters: map[string]string{
+			"mdsAddr":     mdsAddr,
+			"fsType":      CURVEFS_CSI_FS_TYPE,
+			"s3Endpoint":  s3Config[topology.CONFIG_S3_ENDPOINT.Key()],
+			"s3AccessKey": s3Config[topology.CONFIG_S3_ACCESS_KEY.Key()],
+			"s3SecretKey": s3Config[topology.CONFIG_S3_SECRET_KEY.Key()],
+			"s3Bucket":    s3Config[topology.CONFIG_S3_FS_BUCKET_NAME.Key()],
+		},
+		ControllerArgs:   args,
+		NodeArgs:         args,
+		NodeVolumes:      []v1.Volume{devVol},
+		NodeVolumeMounts: []v1.VolumeMount{devMount},
+	}, nil
+}
+
+// reconcileCSI deploys the CSI driver and the StorageClass of a running cluster once it's enabled, and
+// removes them once it's disabled. The config and the parameters are generated on every reconcile so that
+// the plugins follow the addresses of mds and the S3 service.
+func reconcileCSI(cluster clusterd.Clusterer, dcs []*topology.DeployConfig) error {
+	if !isCSIEnabled(cluster) {
+		return csi.Stop(cluster)
+	}
+
+	mdsDc := topology.FilterDeployConfigByRole(dcs, topology.ROLE_MDS)[0]
+	if cluster.GetKind() == topology.KIND_CURVEFS {
+		driver, err := getCurveFsCSIDriver(cluster, mdsDc)
+		if err != nil {
+			return err
+		}
+		return csi.Start(cluster, driver)
+	}
+
+	done, err := extractMissingTemplates(cluster, dcs, []string{topology.LAYOUT_CLIENT_NAME})
+	if err != nil || !done {
+		return err
+	}
+	driver, err := getCurveBsCSIDriver(cluster, mdsDc)
+	if err != nil {
+		return err
+	}
+	return csi.Start(cluster, driver)
+}
@@ -0,0 +1,118 @@
+package controllers
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	curvev1 "github.com/opencurve/curve-operator/api/v1"
+	"github.com/opencurve/curve-operator/pkg/clusterd"
+	"github.com/opencurve/curve-operator/pkg/k8sutil"
+	"github.com/opencurve/curve-operator/pkg/service"
+	"github.com/opencurve/curve-operator/pkg/topology"
+)
+
+// useDevices returns true if the chunkservers of cluster store data in devices
+func useDevices(cluster clusterd.Clusterer) bool {
+	chunkserver := cluster.GetChunkserverSpec()
+	if cluster.GetKind() != topology.KIND_CURVEBS || chunkserver == nil {
+		return false
+	}
+	return chunkserver.UseSelectedNodes || len(chunkserver.Devices) > 0
+}
+
+// startFormattedChunkservers formats the device of every chunkserver and starts the chunkservers
+// whose devices are formatted, so that a slow device doesn't block the chunkservers on other
+// devices. It returns true once all the devices are formatted and all the chunkservers are ready.
+func startFormattedChunkservers(cluster clusterd.Clusterer, dcs []*topology.DeployConfig) (bool, error) {
+	formatted, formatting, formatErr := formatDevices(cluster, dcs)
+
+	ready := true
+	if len(formatted) > 0 {
+		var err error
+		ready, err = startRoleDaemons(cluster, formatted)
+		if err != nil {
+			return false, err
+		}
+	}
+
+	// the failure of format is returned after the formatted chunkservers are started
+	if formatErr != nil {
+		return false, formatErr
+	}
+	if len(formatting) > 0 {
+		cluster.GetProgress().Message = fmt.Sprintf("waiting for %d of %d devices to be formatted: %s",
+			len(formatting), len(dcs), strings.Join(formatting, ", "))
+		return false, nil
+	}
+	return ready, nil
+}
+
+// formatDevices runs the job to format the device of every chunkserver and records the progress
+// in status.formatStatus. It returns the chunkservers whose devices are formatted and the jobs
+// that are still formatting, the failed jobs are deleted to be run again by the next reconcile.
+func formatDevices(cluster clusterd.Clusterer, dcs []*topology.DeployConfig) ([]*topology.DeployConfig, []string, error) {
+	formatStatus := cluster.GetFormatStatus()
+	previous := map[string]curvev1.FormatStatus{}
+	for _, status := range *formatStatus {
+		previous[status.Node+":"+status.Device] = status
+	}
+
+	// the status is rebuilt in the order of chunkservers to drop the devices that are removed
+	statuses := []curvev1.FormatStatus{}
+	formatted, formatting, failed := []*topology.DeployConfig{}, []string{}, []string{}
+	for _, dc := range dcs {
+		device := dc.GetDevice()
+		if device == nil {
+			// the chunkserver stores data in the data directory of node
+			formatted = append(formatted, dc)
+			continue
+		}
+
+		job, err := service.StartJobFormatDevice(cluster, dc)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		status, ok := previous[dc.GetHost()+":"+device.Name]
+		if !ok {
+			status = curvev1.FormatStatus{Node: dc.GetHost(), Device: device.Name, Phase: curvev1.FormatPhasePending}
+		}
+		status.MountPath = device.MountPath
+
+		switch {
+		case k8sutil.IsJobCompleted(job):
+			status.Phase, status.Progress, status.Message = curvev1.FormatPhaseFormatted, 100, ""
+			formatted = append(formatted, dc)
+		case k8sutil.IsJobFailed(job):
+			status.Phase = curvev1.FormatPhaseFailed
+			status.Message = fmt.Sprintf("job %q to format the device failed", job.Name)
+			// delete the failed job so that it's created again by the next reconcile
+			if err := k8sutil.DeleteBatchJob(cluster.GetContext().Clientset, job.Namespace, job.Name, false); err != nil {
+				logger.Errorf("failed to delete the failed job %q. %v", job.Name, err)
+			}
+			failed = append(failed, fmt.Sprintf("%s on node %s", device.Name, dc.GetHost()))
+		default:
+			status.Phase, status.Message = curvev1.FormatPhasePending, ""
+			if job.Status.Active > 0 {
+				status.Phase = curvev1.FormatPhaseFormatting
+			}
+			// the progress is only informative, the failure to read it doesn't block the reconcile
+			progress, err := service.GetFormatProgress(cluster, job)
+			if err != nil {
+				logger.Warningf("failed to get the progress of job %q. %v", job.Name, err)
+			} else {
+				status.Progress = progress
+			}
+			formatting = append(formatting, job.Name)
+		}
+		statuses = append(statuses, status)
+	}
+	*formatStatus = statuses
+
+	if len(failed) > 0 {
+		return formatted, formatting, errors.Errorf("failed to format devices: %s", strings.Join(failed, ", "))
+	}
+	return formatted, formatting, nil
+}
@@ -0,0 +1,123 @@
+package controllers
+
+import (
+	"strings"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/opencurve/curve-operator/pkg/clusterd"
+	"github.com/opencurve/curve-operator/pkg/k8sutil"
+	"github.com/opencurve/curve-operator/pkg/service"
+	"github.com/opencurve/curve-operator/pkg/topology"
+	"github.com/opencurve/curve-operator/pkg/utils"
+)
+
+// correctDrift corrects the resources of a running cluster that are changed out of band,
+// the config is re-rendered and the missing Deployments are recreated. It returns false if
+// the config templates are missing and the dummy Deployment to extract them is not ready yet.
+func correctDrift(cluster clusterd.Clusterer, dcs []*topology.DeployConfig) (bool, error) {
+	done, err := correctConfigDrift(cluster, dcs)
+	if err != nil || !done {
+		return false, err
+	}
+
+	return true, correctDeploymentDrift(cluster, dcs)
+}
+
+// correctConfigDrift renders the config of all services again and updates the after-mutate
+// ConfigMap if any of them differs from the rendered one.
+func correctConfigDrift(cluster clusterd.Clusterer, dcs []*topology.DeployConfig) (bool, error) {
+	clientset := cluster.GetContext().Clientset
+	templateCM, err := k8sutil.GetConfigMapByName(clientset, cluster.GetNameSpace(), getConfigTemplateName(cluster))
+	if err != nil {
+		if !apierrors.IsNotFound(err) {
+			return false, err
+		}
+		// the config templates are extracted again from the dummy Deployment
+		if done, err := constructConfigMap(cluster, dcs); err != nil || !done {
+			return false, err
+		}
+		k8sutil.RecordEvent(cluster, corev1.EventTypeWarning, k8sutil.ReasonConfigReRendered,
+			"ConfigMap %q is missing and recreated", getConfigTemplateName(cluster))
+		if templateCM, err = k8sutil.GetConfigMapByName(clientset, cluster.GetNameSpace(), getConfigTemplateName(cluster)); err != nil {
+			return false, err
+		}
+	}
+
+	afterMutateName := clusterd.ResourceName(cluster, utils.AFTER_MUTATE_CONF)
+	afterMutateCM, err := k8sutil.GetConfigMapByName(clientset, cluster.GetNameSpace(), afterMutateName)
+	if err != nil {
+		if !apierrors.IsNotFound(err) {
+			return false, err
+		}
+		if afterMutateCM, err = makeMutateConfigMap(cluster); err != nil {
+			return false, err
+		}
+	}
+	if afterMutateCM.Data == nil {
+		afterMutateCM.Data = map[string]string{}
+	}
+
+	specConfig, err := service.GetS3Config(cluster)
+	if err != nil {
+		return false, err
+	}
+
+	drifted := []string{}
+	for _, dc := range dcs {
+		names := []string{topology.LAYOUT_TOOLS_NAME}
+		for _, conf := range dc.GetProjectLayout().ServiceConfFiles {
+			names = append(names, conf.Name)
+		}
+		for _, name := range names {
+			content, err := renderConfig(dc, templateCM.Data[name], name, specConfig)
+			if err != nil {
+				return false, err
+			}
+			key := getMutateConfigKey(dc, name)
+			if current, ok := afterMutateCM.Data[key]; ok && current == content {
+				continue
+			}
+			afterMutateCM.Data[key] = content
+			drifted = append(drifted, key)
+		}
+	}
+	if len(drifted) == 0 {
+		return true, nil
+	}
+
+	if _, err := k8sutil.UpdateConfigMap(clientset, afterMutateCM); err != nil {
+		return false, err
+	}
+	logger.Infof("config %v of cluster %q is changed out of band and re-rendered", drifted, cluster.GetName())
+	k8sutil.RecordEvent(cluster, corev1.EventTypeWarning, k8sutil.ReasonConfigReRendered,
+		"config %s in ConfigMap %q is changed out of band and re-rendered", strings.Join(drifted, ", "), afterMutateName)
+	return true, nil
+}
+
+// correctDeploymentDrift recreates the Deployments of the services that are deleted out of band
+func correctDeploymentDrift(cluster clusterd.Clusterer, dcs []*topology.DeployConfig) error {
+	clientset := cluster.GetContext().Clientset
+	for _, dc := range dcs {
+		name := clusterd.ResourceName(cluster, dc.GetName())
+		_, err := clientset.AppsV1().Deployments(cluster.GetNameSpace()).Get(name, metav1.GetOptions{})
+		if err == nil {
+			continue
+		}
+		if !apierrors.IsNotFound(err) {
+			return errors.Wrapf(err, "failed to get Deployment %s", name)
+		}
+
+		if _, err := service.StartService(cluster, dc); err != nil {
+			return err
+		}
+		logger.Infof("Deployment %s of cluster %q is missing and recreated", name, cluster.GetName())
+		k8sutil.RecordEvent(cluster, corev1.EventTypeWarning, k8sutil.ReasonDeploymentRecreated,
+			"Deployment %q of %s service is missing and recreated", name, dc.GetRole())
+	}
+
+	return nil
+}
@@ -7,19 +7,21 @@ import (
 
 	apps "k8s.io/api/apps/v1"
 	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/kubernetes/scheme"
 	"k8s.io/client-go/tools/remotecommand"
 
 	"github.com/opencurve/curve-operator/pkg/clusterd"
 	"github.com/opencurve/curve-operator/pkg/k8sutil"
+	"github.com/opencurve/curve-operator/pkg/service"
 	"github.com/opencurve/curve-operator/pkg/topology"
 	"github.com/pkg/errors"
 )
 
 const (
 	CURVE_DUMMY_SERVICE   = "curve-dummy-service"
-	CURVE_CONFIG_TEMPLATE = "curve-config-template"
+	CURVE_CONFIG_TEMPLATE = "config-template"
 )
 
 func getDummyServiceLabels() map[string]string {
@@ -28,8 +30,18 @@ func getDummyServiceLabels() map[string]string {
 	return labels
 }
 
-// createSyncDeployment create a deployment for read config file
-func makeDummyDeployment(c clusterd.Clusterer, dcs []*topology.DeployConfig) error {
+// getDummyServiceName returns the name of the dummy Deployment of the cluster
+func getDummyServiceName(c clusterd.Clusterer) string {
+	return clusterd.ResourceName(c, "dummy-service")
+}
+
+// getConfigTemplateName returns the name of the ConfigMap that stores the config templates of the cluster
+func getConfigTemplateName(c clusterd.Clusterer) string {
+	return clusterd.ResourceName(c, CURVE_CONFIG_TEMPLATE)
+}
+
+// makeDummyDeployment create a deployment for read config file, it returns true if the deployment is ready
+func makeDummyDeployment(c clusterd.Clusterer, dcs []*topology.DeployConfig) (bool, error) {
 	container := v1.Container{
 		Name: CURVE_DUMMY_SERVICE,
 		Command: []string{
@@ -40,14 +52,14 @@ func makeDummyDeployment(c clusterd.Clusterer, dcs []*topology.DeployConfig) err
 			"while true; do echo sync pod to read various config file from it; sleep 10;done",
 		},
 		Image:           dcs[0].GetContainerImage(),
-		ImagePullPolicy: v1.PullIfNotPresent,
+		ImagePullPolicy: c.GetImagePullPolicy(),
 		Env:             []v1.EnvVar{{Name: "TZ", Value: "Asia/Hangzhou"}},
 	}
 
 	podSpec := v1.PodTemplateSpec{
 		ObjectMeta: metav1.ObjectMeta{
-			Name:   CURVE_DUMMY_SERVICE,
-			Labels: getDummyServiceLabels(),
+			Name:   getDummyServiceName(c),
+			Labels: k8sutil.ClusterLabels(c, getDummyServiceLabels()),
 		},
 		Spec: v1.PodSpec{
 			Containers: []v1.Container{
@@ -55,21 +67,24 @@ func makeDummyDeployment(c clusterd.Clusterer, dcs []*topology.DeployConfig) err
 			},
 			RestartPolicy: v1.RestartPolicyAlways,
 			HostNetwork:   true,
-			NodeName:      dcs[0].GetHost(),
 			DNSPolicy:     v1.DNSClusterFirstWithHostNet,
 		},
 	}
+	err := service.ApplyPlacement(c, &podSpec.Spec, dcs[0].GetHost(), service.GetServicePlacement(c, dcs[0]))
+	if err != nil {
+		return false, err
+	}
 
 	replicas := int32(1)
 	d := &apps.Deployment{
 		ObjectMeta: metav1.ObjectMeta{
-			Name:      CURVE_DUMMY_SERVICE,
+			Name:      getDummyServiceName(c),
 			Namespace: c.GetNameSpace(),
-			Labels:    getDummyServiceLabels(),
+			Labels:    k8sutil.ClusterLabels(c, getDummyServiceLabels()),
 		},
 		Spec: apps.DeploymentSpec{
 			Selector: &metav1.LabelSelector{
-				MatchLabels: getDummyServiceLabels(),
+				MatchLabels: k8sutil.ClusterSelectorLabels(c, getDummyServiceLabels()),
 			},
 			Template: podSpec,
 			Replicas: &replicas,
@@ -80,15 +95,19 @@ func makeDummyDeployment(c clusterd.Clusterer, dcs []*topology.DeployConfig) err
 	}
 
 	if err := c.GetOwnerInfo().SetControllerReference(d); err != nil {
-		return err
+		return false, err
 	}
 
-	if _, err := k8sutil.CreateOrUpdateDeploymentAndWaitStart(c.GetContext().Clientset, d); err != nil {
-		return err
+	newDeploy, err := k8sutil.CreateOrUpdateDeployment(c.GetContext().Clientset, d)
+	if err != nil {
+		return false, err
 	}
 
-	// update condition type and phase etc.
-	return nil
+	if !k8sutil.IsDeploymentReady(newDeploy) {
+		c.GetProgress().Message = fmt.Sprintf("waiting for Deployment %q to be ready", newDeploy.Name)
+		return false, nil
+	}
+	return true, nil
 }
 
 // makeTemplateConfigMap make a configmap store all config file with template value
@@ -100,8 +119,9 @@ func makeTemplateConfigMap(c clusterd.Clusterer, dcs []*topology.DeployConfig) e
 
 	cm := &v1.ConfigMap{
 		ObjectMeta: metav1.ObjectMeta{
-			Name:      CURVE_CONFIG_TEMPLATE,
+			Name:      getConfigTemplateName(c),
 			Namespace: c.GetNameSpace(),
+			Labels:    k8sutil.ClusterLabels(c, nil),
 		},
 		Data: configMapData,
 	}
@@ -116,13 +136,45 @@ func makeTemplateConfigMap(c clusterd.Clusterer, dcs []*topology.DeployConfig) e
 		return err
 	}
 
-	logger.Infof("create configmap %s successed", CURVE_CONFIG_TEMPLATE)
+	logger.Infof("create configmap %s successed", cm.GetName())
 	return nil
 }
 
+// extractMissingTemplates extracts the config templates again if any of the names is missing since the
+// cluster is created before they're extracted, such as the ones of the roles that are enabled later.
+// It returns false if the dummy Deployment to extract them is not ready yet.
+func extractMissingTemplates(cluster clusterd.Clusterer, dcs []*topology.DeployConfig, names []string) (bool, error) {
+	templateCM, err := k8sutil.GetConfigMapByName(cluster.GetContext().Clientset, cluster.GetNameSpace(), getConfigTemplateName(cluster))
+	if err != nil && !apierrors.IsNotFound(err) {
+		return false, err
+	}
+	if err == nil {
+		missing := false
+		for _, name := range names {
+			if _, ok := templateCM.Data[name]; !ok {
+				missing = true
+			}
+		}
+		if !missing {
+			return true, nil
+		}
+	}
+
+	ready, err := makeDummyDeployment(cluster, dcs)
+	if err != nil || !ready {
+		return false, err
+	}
+	if err := makeTemplateConfigMap(cluster, dcs); err != nil {
+		return false, err
+	}
+	k8sutil.RecordEvent(cluster, v1.EventTypeNormal, k8sutil.ReasonConfigTemplateExtracted,
+		"config templates are extracted from image %q into ConfigMap %q", cluster.GetContainerImage(), getConfigTemplateName(cluster))
+	return true, nil
+}
+
 // getDefaultConfigMapData read all config files with template value
 func getDefaultConfigMapData(c clusterd.Clusterer, dcs []*topology.DeployConfig) (map[string]string, error) {
-	labels := getDummyServiceLabels()
+	labels := k8sutil.ClusterSelectorLabels(c, getDummyServiceLabels())
 	selector := k8sutil.GetLabelSelector(labels)
 	pods, err := k8sutil.GetPodsByLabelSelector(c.GetContext().Clientset, c.GetNameSpace(), selector)
 	if err != nil {
@@ -130,19 +182,24 @@ func getDefaultConfigMapData(c clusterd.Clusterer, dcs []*topology.DeployConfig)
 	}
 
 	if len(pods.Items) != 1 {
-		return nil, errors.New("app=sync-config label matches no pods")
+		return nil, errors.Errorf("label %q matches %d pods, expect one dummy pod", selector, len(pods.Items))
 	}
 	pod := pods.Items[0]
 
 	role2Configs := map[string][]string{}
-	// distinct the same config
-	for _, dc := range dcs {
-		role2Configs[dc.GetRole()] = topology.ServiceConfigs[dc.GetRole()]
+	// distinct the same config, the configs of all roles are extracted so that the optional
+	// roles such as snapshotclone can be enabled later
+	for _, role := range getClusterRoles(c) {
+		role2Configs[role] = topology.ServiceConfigs[role]
 	}
 	// for tool.conf
 	role2Configs["tools.conf"] = []string{
 		topology.LAYOUT_TOOLS_NAME,
 	}
+	// for client.conf of the FUSE clients and the CSI plugins
+	role2Configs["client.conf"] = []string{
+		topology.LAYOUT_CLIENT_NAME,
+	}
 
 	confSrcDir := dcs[0].GetProjectLayout().ServiceConfSrcDir // /curvefs/conf
 
@@ -18,18 +18,25 @@ package controllers
 
 import (
 	"context"
+	"fmt"
 
 	"github.com/go-logr/logr"
 	"github.com/google/uuid"
-	"github.com/pkg/errors"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
 
 	curvev1 "github.com/opencurve/curve-operator/api/v1"
 	"github.com/opencurve/curve-operator/pkg/clusterd"
+	"github.com/opencurve/curve-operator/pkg/csi"
 	"github.com/opencurve/curve-operator/pkg/k8sutil"
 	"github.com/opencurve/curve-operator/pkg/service"
 	"github.com/opencurve/curve-operator/pkg/topology"
@@ -38,27 +45,28 @@ import (
 // CurvefsReconciler reconciles a Curvefs object
 type CurvefsReconciler struct {
 	client.Client
-	Log    logr.Logger
-	Scheme *runtime.Scheme
+	Log      logr.Logger
+	Scheme   *runtime.Scheme
+	Recorder record.EventRecorder
 
-	context    clusterd.Context
-	clusterMap map[string]*clusterd.FsClusterManager
+	context clusterd.Context
 }
 
 func NewCurvefsReconciler(
 	client client.Client,
 	log logr.Logger,
 	scheme *runtime.Scheme,
+	recorder record.EventRecorder,
 
 	context clusterd.Context,
 ) *CurvefsReconciler {
 	return &CurvefsReconciler{
-		Client: client,
-		Log:    log,
-		Scheme: scheme,
+		Client:   client,
+		Log:      log,
+		Scheme:   scheme,
+		Recorder: recorder,
 
-		context:    context,
-		clusterMap: make(map[string]*clusterd.FsClusterManager),
+		context: context,
 	}
 }
 
@@ -66,10 +74,25 @@ func NewCurvefsReconciler(
 // +kubebuilder:rbac:groups=operator.curve.io,resources=curvefs/status,verbs=get;update;patch
 // +kubebuilder:rbac:groups=core,resources=pods,verbs=get;list;watch;delete
 // +kubebuilder:rbac:groups=core,resources=pods/exec,verbs=create;update;get;list;watch;delete
+// +kubebuilder:rbac:groups=core,resources=pods/log,verbs=get
 // +kubebuilder:rbac:groups=core,resources=nodes,verbs=get;list;watch;update;patch
 // +kubebuilder:rbac:groups=core,resources=configmaps,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=core,resources=events,verbs=get;list;watch;create;update;patch
+// +kubebuilder:rbac:groups=core,resources=services,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=core,resources=secrets,verbs=get;list;watch;create
+// +kubebuilder:rbac:groups=core,resources=serviceaccounts,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=core,resources=persistentvolumes,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=core,resources=persistentvolumeclaims,verbs=get;list;watch;update;patch
+// +kubebuilder:rbac:groups=core,resources=persistentvolumeclaims/status,verbs=update;patch
+// +kubebuilder:rbac:groups=storage.k8s.io,resources=storageclasses,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=storage.k8s.io,resources=csinodes,verbs=get;list;watch
+// +kubebuilder:rbac:groups=storage.k8s.io,resources=volumeattachments,verbs=get;list;watch;update;patch
+// +kubebuilder:rbac:groups=storage.k8s.io,resources=volumeattachments/status,verbs=patch
+// +kubebuilder:rbac:groups=coordination.k8s.io,resources=leases,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=rbac.authorization.k8s.io,resources=clusterroles;clusterrolebindings,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=monitoring.coreos.com,resources=servicemonitors,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=apps,resources=deployments,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=apps,resources=daemonsets,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=batch,resources=jobs,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=batch,resources=cronjobs,verbs=get;list;watch;create;update;patch;delete
 
@@ -78,6 +101,7 @@ func (r *CurvefsReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 	logger.Info("reconcileing CurvefsCluster")
 
 	r.context.Client = r.Client
+	r.context.Recorder = r.Recorder
 	ctx := context.Background()
 
 	curvefsCluster := &curvev1.Curvefs{}
@@ -91,50 +115,44 @@ func (r *CurvefsReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 		return reconcile.Result{}, err
 	}
 
-	// k8sutil.UpdateCondition(context.TODO(),
-	// 	r.Client,
-	// 	clusterd.KIND_CURVEFS,
-	// 	req.NamespacedName,
-	// 	curvev1.ClusterPending,
-	// 	curvev1.ClusterCondition{
-	// 		Type:    curvev1.ConditionProgressing,
-	// 		Status:  curvev1.ConditionStatusTrue,
-	// 		Reason:  curvev1.ConditionReconcileStarted,
-	// 		Message: "start to reconcile curve fs cluster",
-	// 	},
-	// )
+	// The cluster manager is rebuilt from the CR on every reconcile, the state that must
+	// survive the restart of operator such as the cluster UUID is persisted in CR status.
+	ownerInfo := clusterd.NewOwnerInfo(curvefsCluster, r.Scheme)
+	m := newFsClusterManager(r.context, curvefsCluster, ownerInfo, r.Log)
 
 	// The CR was deleted
 	if !curvefsCluster.GetDeletionTimestamp().IsZero() {
-		return reconcile.Result{}, r.reconcileCurvefsDelete(curvefsCluster)
+		return reconcile.Result{}, r.reconcileCurvefsDelete(m)
 	}
 
-	ownerInfo := clusterd.NewOwnerInfo(curvefsCluster, r.Scheme)
-	return r.reconcileCurvefsCluster(curvefsCluster, ownerInfo)
+	return r.reconcileCurvefsCluster(m)
 }
 
 // reconcileCurvefsDelete
-func (r *CurvefsReconciler) reconcileCurvefsDelete(clusterObj *curvev1.Curvefs) error {
-	// get currnet cluster and delete it
-	cluster, ok := r.clusterMap[clusterObj.GetNamespace()]
-	if !ok {
-		logger.Errorf("failed to find the cluster %q", clusterObj.GetName())
-		return errors.New("internal error")
+func (r *CurvefsReconciler) reconcileCurvefsDelete(cluster *clusterd.FsClusterManager) error {
+	clusterObj := cluster.Cluster
+	if err := updateClusterProgressing(cluster, curvev1.ClusterDeleting,
+		curvev1.ConditionDeletingClusterReason, "start to delete cluster"); err != nil {
+		return err
 	}
-
 	dcs, err := topology.ParseTopology(cluster)
 	if err != nil {
+		updateClusterFailed(cluster, curvev1.ClusterDeleting, err)
 		return err
 	}
 
 	err = service.StartClusterCleanUpJob(cluster, dcs)
 	if err != nil {
+		updateClusterFailed(cluster, curvev1.ClusterDeleting, err)
 		return err
 	}
 
-	// delete it from clusterMap
-	if _, ok := r.clusterMap[cluster.GetNameSpace()]; ok {
-		delete(r.clusterMap, cluster.GetNameSpace())
+	deleteClusterMetrics(cluster, dcs)
+
+	// the RBAC and StorageClass of the CSI driver are cluster-scoped and not garbage collected
+	if err := csi.Stop(cluster); err != nil {
+		updateClusterFailed(cluster, curvev1.ClusterDeleting, err)
+		return err
 	}
 
 	// remove finalizers
@@ -144,25 +162,25 @@ func (r *CurvefsReconciler) reconcileCurvefsDelete(clusterObj *curvev1.Curvefs)
 		clusterObj)
 
 	logger.Infof("curve cluster %v has been deleted successed", clusterObj.GetName())
+	k8sutil.RecordEvent(cluster, corev1.EventTypeNormal, k8sutil.ReasonClusterDeleted, "cluster is deleted successfully")
 
 	return nil
 }
 
 // reconcileCurvefsCluster start reconcile a CurveFS cluster
-func (r *CurvefsReconciler) reconcileCurvefsCluster(clusterObj *curvev1.Curvefs, ownerInfo *clusterd.OwnerInfo) (reconcile.Result, error) {
-	m, ok := r.clusterMap[clusterObj.Namespace]
-	if !ok {
-		newUUID := uuid.New().String()
-		m = newFsClusterManager(newUUID, clusterd.KIND_CURVEFS)
+func (r *CurvefsReconciler) reconcileCurvefsCluster(m *clusterd.FsClusterManager) (reconcile.Result, error) {
+	// report the phase that the cluster is left in by this reconcile
+	defer setClusterPhaseMetric(m)
+
+	// generate the UUID once the cluster is accepted and persist it in status
+	if len(m.Cluster.Status.UUID) == 0 {
+		m.Cluster.Status.UUID = uuid.New().String()
+		if err := r.Client.Status().Update(context.TODO(), m.Cluster); err != nil {
+			m.Logger.Error(err, "failed to persist cluster UUID")
+			return ctrl.Result{}, client.IgnoreNotFound(err)
+		}
 	}
 
-	// construct cluster object
-	m.Context = r.context
-	m.Cluster = clusterObj
-	m.Logger = r.Log
-	m.OwnerInfo = ownerInfo
-
-	r.clusterMap[m.GetNameSpace()] = m
 	m.Logger.Info("reconcileing Curve FS Cluster in namespace %q", m.GetNameSpace())
 
 	dcs, err := topology.ParseTopology(m)
@@ -170,6 +188,18 @@ func (r *CurvefsReconciler) reconcileCurvefsCluster(clusterObj *curvev1.Curvefs,
 		return reconcile.Result{}, err
 	}
 
+	// the services of a cluster created by the previous operator are started again with the new names
+	if len(m.Cluster.Status.Phase) > 0 {
+		done, err := migrateLegacyResources(m)
+		if err != nil {
+			m.Logger.Error(err, "failed to migrate the legacy resources of cluster")
+			return ctrl.Result{}, err
+		}
+		if !done {
+			return requeueForWaiting(m)
+		}
+	}
+
 	switch m.Cluster.Status.Phase {
 	case "":
 		// Update the cluster status to 'Creating'
@@ -184,11 +214,11 @@ func (r *CurvefsReconciler) reconcileCurvefsCluster(clusterObj *curvev1.Curvefs,
 			return ctrl.Result{}, client.IgnoreNotFound(err)
 		}
 
-		m.Cluster.Status.Phase = curvev1.ClusterCreating
 		m.Cluster.Status.CurveVersion = m.Cluster.Spec.CurveVersion
 		m.Cluster.Status.StorageDir.DataDir = m.Cluster.Spec.DataDir
 		m.Cluster.Status.StorageDir.LogDir = m.Cluster.Spec.LogDir
-		if err := r.Status().Update(context.TODO(), m.Cluster); err != nil {
+		if err := updateClusterProgressing(m, curvev1.ClusterCreating,
+			curvev1.ConditionCreatingClusterReason, "start to create cluster"); err != nil {
 			m.Logger.Error(err, "unable to update Curvefs")
 			return ctrl.Result{}, client.IgnoreNotFound(err)
 		}
@@ -196,14 +226,22 @@ func (r *CurvefsReconciler) reconcileCurvefsCluster(clusterObj *curvev1.Curvefs,
 		return ctrl.Result{}, nil
 	case curvev1.ClusterCreating:
 		// Create a new cluster and update cluster status to 'Running'
-		initCluster(m, dcs)
+		done, err := initCluster(m, dcs)
+		if err != nil {
+			m.Logger.Error(err, "failed to create cluster")
+			updateClusterFailed(m, curvev1.ClusterCreating, err)
+			return ctrl.Result{}, err
+		}
+		if !done {
+			return requeueForWaiting(m)
+		}
 		m.Logger.Info("Curvefs accepted by operator", "curvefs", client.ObjectKey{
 			Name:      m.GetName(),
 			Namespace: m.GetNameSpace(),
 		})
 
-		m.Cluster.Status.Phase = curvev1.ClusterRunning
-		if err := r.Status().Update(context.TODO(), m.Cluster); err != nil {
+		k8sutil.RecordEvent(m, corev1.EventTypeNormal, k8sutil.ReasonClusterCreated, "cluster is created successfully")
+		if err := updateClusterReady(m, "cluster is created successfully"); err != nil {
 			m.Logger.Error(err, "unable to update Curvefs")
 			return ctrl.Result{}, client.IgnoreNotFound(err)
 		}
@@ -214,10 +252,14 @@ func (r *CurvefsReconciler) reconcileCurvefsCluster(clusterObj *curvev1.Curvefs,
 		// Upgrading、Updating、Scaling
 
 		// 1. check for upgrade
-		if m.Cluster.Spec.CurveVersion.Image != m.Cluster.Status.CurveVersion.Image {
+		phase, reason, message := curvev1.ClusterRunning, curvev1.ConditionReason(""), ""
+		// the version in status is updated after the upgrade succeeds, and a rolled back image isn't upgraded to again
+		image := m.Cluster.Spec.CurveVersion.Image
+		if image != m.Cluster.Status.CurveVersion.Image && !isUpgradeRolledBack(m, image) {
 			m.Logger.Info("Check curvefs cluster image not match, need upgrade")
-			m.Cluster.Status.Phase = curvev1.ClusterUpgrading
-			m.Cluster.Status.CurveVersion = m.Cluster.Spec.CurveVersion
+			phase, reason = curvev1.ClusterUpgrading, curvev1.ConditionUpgradingClusterReason
+			message = fmt.Sprintf("start to upgrade cluster from %q to %q", m.Cluster.Status.CurveVersion.Image, image)
+			startUpgradeRecord(m, dcs, image)
 		}
 
 		// TODO: 2. compare DataDir and LogDir - not implement
@@ -228,38 +270,120 @@ func (r *CurvefsReconciler) reconcileCurvefsCluster(clusterObj *curvev1.Curvefs,
 		// 	m.Cluster.Status.StorageDir.LogDir = m.Cluster.Spec.LogDir
 		// }
 
-		// 3. compare etcd and mds and metaserver config
+		// 3. check for scaling, the instances of services is not compared as config
+		if phase == curvev1.ClusterRunning {
+			scaleMessage, err := getScalingMessage(m, dcs)
+			if err != nil {
+				m.Logger.Error(err, "failed to check the scaling of cluster")
+				return ctrl.Result{}, err
+			}
+			if len(scaleMessage) > 0 {
+				phase, reason, message = curvev1.ClusterScaling, curvev1.ConditionScalingClusterReason, scaleMessage
+			}
+		}
+
+		// 4. compare etcd and mds and metaserver config
 		specParameters, _ := parseSpecParameters(m)
 		statusParameters, err := getDataFromRecordConfigMap(m)
 		if err != nil {
 			m.Logger.Error(err, "failed to read record config from record-configmap")
 			return ctrl.Result{}, nil
 		}
-		statusModified := false
+		modContextSet := []curvev1.ModContext{}
 		for role, specRolePara := range specParameters {
 			roleParaVar := map[string]string{}
 			for specPK, specPV := range specRolePara {
+				if specPK == curvev1.INSTANCES {
+					delete(statusParameters[role], specPK)
+					continue
+				}
 				paraStatusVal, paraExists := statusParameters[role][specPK]
 				if !paraExists || paraStatusVal != specPV {
 					roleParaVar[specPK] = specPV
-					statusModified = true
 				}
 				delete(statusParameters[role], specPK)
 			}
 			// delete some parameters
-			if len(statusParameters[role]) > 0 {
-				statusModified = true
+			if len(roleParaVar) == 0 && len(statusParameters[role]) == 0 {
+				continue
 			}
-			m.Cluster.Status.LastModContextSet.ModContextSet = append(m.Cluster.Status.LastModContextSet.ModContextSet, curvev1.ModContext{
+			modContextSet = append(modContextSet, curvev1.ModContext{
 				Role:       role,
 				Parameters: roleParaVar,
 			})
 		}
-		if statusModified {
-			m.Cluster.Status.Phase = curvev1.ClusterUpdating
+		// the config is updated after the other operations are finished
+		if len(modContextSet) > 0 && phase == curvev1.ClusterRunning {
+			m.Cluster.Status.LastModContextSet.ModContextSet = modContextSet
+			phase, reason, message = curvev1.ClusterUpdating, curvev1.ConditionUpdatingClusterReason, "start to update cluster config"
+		}
+		// the services that don't follow the spec or the Secrets are restarted one by one
+		if phase == curvev1.ClusterRunning {
+			restartMessage, err := getRestartMessage(m, dcs)
+			if err != nil {
+				m.Logger.Error(err, "failed to check the services to restart")
+				return ctrl.Result{}, err
+			}
+			if restartMessage != "" {
+				phase, reason, message = curvev1.ClusterUpdating, curvev1.ConditionUpdatingClusterReason, restartMessage
+			}
 		}
 
-		if err := r.Status().Update(context.TODO(), m.Cluster); err != nil {
+		switch phase {
+		case curvev1.ClusterUpgrading:
+			k8sutil.RecordEvent(m, corev1.EventTypeNormal, k8sutil.ReasonUpgrading, "%s", message)
+		case curvev1.ClusterUpdating:
+			if len(m.Cluster.Status.LastModContextSet.ModContextSet) > 0 {
+				k8sutil.RecordEvent(m, corev1.EventTypeNormal, k8sutil.ReasonConfigUpdating, "%s", message)
+			} else {
+				k8sutil.RecordEvent(m, corev1.EventTypeNormal, k8sutil.ReasonServicesRestarting, "%s", message)
+			}
+		case curvev1.ClusterScaling:
+			k8sutil.RecordEvent(m, corev1.EventTypeNormal, k8sutil.ReasonScaling, "%s", message)
+		}
+		if phase == curvev1.ClusterRunning {
+			// 5. start or remove the embedded S3 once it's enabled or disabled
+			var done bool
+			done, err = reconcileEmbeddedS3(m)
+			if err != nil {
+				m.Logger.Error(err, "failed to reconcile the embedded S3 of cluster")
+				return ctrl.Result{}, err
+			}
+			if !done {
+				return requeueForWaiting(m)
+			}
+			// 6. correct the resources that are changed out of band
+			done, err = correctDrift(m, dcs)
+			if err != nil {
+				m.Logger.Error(err, "failed to correct the drift of cluster")
+				return ctrl.Result{}, err
+			}
+			if !done {
+				return requeueForWaiting(m)
+			}
+			// 7. apply the monitor that follows the services
+			if err := reconcileMonitor(m, dcs); err != nil {
+				m.Logger.Error(err, "failed to reconcile the monitor of cluster")
+				return ctrl.Result{}, err
+			}
+			// 8. create and mount the file systems for the workloads
+			if err := reconcileFuse(m, dcs); err != nil {
+				m.Logger.Error(err, "failed to reconcile the FUSE clients of cluster")
+				return ctrl.Result{}, err
+			}
+			// 9. deploy the CSI driver that provisions the file systems for the workloads
+			if err := reconcileCSI(m, dcs); err != nil {
+				m.Logger.Error(err, "failed to reconcile the CSI driver of cluster")
+				return ctrl.Result{}, err
+			}
+			if err := updateServiceReadyMetrics(m, dcs); err != nil {
+				logger.Warningf("failed to update the readiness metrics of services. %v", err)
+			}
+			err = r.Status().Update(context.TODO(), m.Cluster)
+		} else {
+			err = updateClusterProgressing(m, phase, reason, message)
+		}
+		if err != nil {
 			m.Logger.Error(err, "unable to update Curvefs")
 			return ctrl.Result{}, client.IgnoreNotFound(err)
 		}
@@ -273,7 +397,28 @@ func (r *CurvefsReconciler) reconcileCurvefsCluster(clusterObj *curvev1.Curvefs,
 		})
 		mcs := m.Cluster.Status.LastModContextSet.ModContextSet
 		if len(mcs) <= 0 {
-			m.Logger.Info("No Config need to update, ignore the event")
+			// no config to update, the services that don't follow the spec are restarted by the same steps of upgrade
+			steps, err := getRestartSteps(m, dcs)
+			if err != nil {
+				m.Logger.Error(err, "failed to get the restart steps")
+				updateClusterFailed(m, curvev1.ClusterUpdating, err)
+				return ctrl.Result{}, err
+			}
+			done, err := runSteps(m, dcs, steps)
+			if err != nil {
+				m.Logger.Error(err, "failed to restart services")
+				updateClusterFailed(m, curvev1.ClusterUpdating, err)
+				return ctrl.Result{}, err
+			}
+			if !done {
+				return requeueForWaiting(m)
+			}
+
+			k8sutil.RecordEvent(m, corev1.EventTypeNormal, k8sutil.ReasonServicesRestarted, "services are restarted successfully")
+			if err := updateClusterReady(m, "services are restarted successfully"); err != nil {
+				m.Logger.Error(err, "failed to update Curvefs")
+				return ctrl.Result{}, client.IgnoreNotFound(err)
+			}
 			return ctrl.Result{}, nil
 		}
 
@@ -295,26 +440,33 @@ func (r *CurvefsReconciler) reconcileCurvefsCluster(clusterObj *curvev1.Curvefs,
 					err := mutateConfig(m, dc, conf.Name)
 					if err != nil {
 						m.Logger.Error(err, "failed to render configmap again")
+						updateClusterFailed(m, curvev1.ClusterUpdating, err)
 						return ctrl.Result{}, err
 					}
 				}
 			}
 
 		}
-		// 2. rebuild the Pods under the Deployment corresponding to the role, upgrade one by one.
-		//  And wait for all Pods under the Deployment (only one) to be in the Ready state.
+		// 2. rebuild the Pods under the Deployment corresponding to the role,
+		//  and requeue until all Pods under the Deployment (only one) are in the Ready state.
+		roleDcs := []*topology.DeployConfig{}
 		for role := range roles2Modfing {
-			for _, dc := range topology.FilterDeployConfigByRole(dcs, role) {
-				if err := service.StartService(m, dc); err != nil {
-					m.Logger.Error(err, "failed to update Deployment Service")
-					return ctrl.Result{}, err
-				}
-			}
+			roleDcs = append(roleDcs, topology.FilterDeployConfigByRole(dcs, role)...)
+		}
+		ready, err := startServices(m, roleDcs)
+		if err != nil {
+			m.Logger.Error(err, "failed to update Deployment Service")
+			updateClusterFailed(m, curvev1.ClusterUpdating, err)
+			return ctrl.Result{}, err
+		}
+		if !ready {
+			return requeueForWaiting(m)
 		}
 
-		m.Cluster.Status.Phase = curvev1.ClusterRunning
 		m.Cluster.Status.LastModContextSet.ModContextSet = nil
-		if err := r.Status().Update(context.TODO(), m.Cluster); err != nil {
+		k8sutil.RecordEvent(m, corev1.EventTypeNormal, k8sutil.ReasonConfigUpdated,
+			"config of %d roles is updated successfully", len(roles2Modfing))
+		if err := updateClusterReady(m, "cluster config is updated successfully"); err != nil {
 			m.Logger.Error(err, "failed to update Curvefs")
 			return ctrl.Result{}, client.IgnoreNotFound(err)
 		}
@@ -327,15 +479,98 @@ func (r *CurvefsReconciler) reconcileCurvefsCluster(clusterObj *curvev1.Curvefs,
 			Namespace: m.GetNameSpace(),
 		})
 
-		for _, dc := range dcs {
-			if err := service.StartService(m, dc); err != nil {
-				m.Logger.Error(err, "failed to upgrade service ", dc.GetName())
-				return ctrl.Result{}, err
+		// the upgrade starts over if the image is changed again before it's finished
+		if record := getUpgradeRecord(m); record == nil || record.ToImage != m.Cluster.Spec.CurveVersion.Image {
+			message := fmt.Sprintf("start to upgrade cluster from %q to %q",
+				m.Cluster.Status.CurveVersion.Image, m.Cluster.Spec.CurveVersion.Image)
+			startUpgradeRecord(m, dcs, m.Cluster.Spec.CurveVersion.Image)
+			k8sutil.RecordEvent(m, corev1.EventTypeNormal, k8sutil.ReasonUpgrading, "%s", message)
+			if err := updateClusterProgressing(m, curvev1.ClusterUpgrading,
+				curvev1.ConditionUpgradingClusterReason, message); err != nil {
+				m.Logger.Error(err, "unable to update Curvefs")
+				return ctrl.Result{}, client.IgnoreNotFound(err)
+			}
+			return ctrl.Result{}, nil
+		}
+
+		if isRollbackRequested(m.Cluster.Annotations) {
+			if err := startRollback(m); err != nil {
+				m.Logger.Error(err, "failed to roll back the upgrade")
+				return ctrl.Result{}, client.IgnoreNotFound(err)
+			}
+			return ctrl.Result{}, nil
+		}
+
+		// the completed steps are kept in status, so a paused or failed upgrade resumes from where it stopped
+		if isUpgradePaused(m.Cluster.Annotations) {
+			if err := pauseUpgrade(m); err != nil {
+				m.Logger.Error(err, "failed to pause the upgrade")
+				return ctrl.Result{}, client.IgnoreNotFound(err)
+			}
+			return ctrl.Result{}, nil
+		}
+
+		steps, err := getUpgradeSteps(m, dcs)
+		if err != nil {
+			m.Logger.Error(err, "failed to get the upgrade steps")
+			updateClusterFailed(m, curvev1.ClusterUpgrading, err)
+			return ctrl.Result{}, err
+		}
+		done, err := runSteps(m, dcs, steps)
+		if err != nil {
+			m.Logger.Error(err, "failed to upgrade services")
+			updateClusterFailed(m, curvev1.ClusterUpgrading, err)
+			return ctrl.Result{}, err
+		}
+		if !done {
+			return requeueForWaiting(m)
+		}
+
+		m.Cluster.Status.CurveVersion = m.Cluster.Spec.CurveVersion
+		finishUpgradeRecord(m, curvev1.UpgradeStateSucceeded)
+		k8sutil.RecordEvent(m, corev1.EventTypeNormal, k8sutil.ReasonUpgraded,
+			"cluster is upgraded to %q successfully", m.GetContainerImage())
+		if err := updateClusterReady(m, "cluster is upgraded successfully"); err != nil {
+			m.Logger.Error(err, "failed to update Curvefs")
+			return ctrl.Result{}, client.IgnoreNotFound(err)
+		}
+
+		return ctrl.Result{}, nil
+	case curvev1.ClusterRollingBack:
+		// Roll back the services to the version in status by the same steps of upgrade.
+		m.Logger.Info("Curvefs running to roll back", "curvefs", client.ObjectKey{
+			Name:      m.GetName(),
+			Namespace: m.GetNameSpace(),
+		})
+
+		if isUpgradePaused(m.Cluster.Annotations) {
+			if err := pauseUpgrade(m); err != nil {
+				m.Logger.Error(err, "failed to pause the rollback")
+				return ctrl.Result{}, client.IgnoreNotFound(err)
 			}
+			return ctrl.Result{}, nil
+		}
+
+		steps, err := getUpgradeSteps(m, dcs)
+		if err != nil {
+			m.Logger.Error(err, "failed to get the rollback steps")
+			updateClusterFailed(m, curvev1.ClusterRollingBack, err)
+			return ctrl.Result{}, err
+		}
+		done, err := runSteps(m, dcs, steps)
+		if err != nil {
+			m.Logger.Error(err, "failed to roll back services")
+			updateClusterFailed(m, curvev1.ClusterRollingBack, err)
+			return ctrl.Result{}, err
+		}
+		if !done {
+			return requeueForWaiting(m)
 		}
 
-		m.Cluster.Status.Phase = curvev1.ClusterRunning
-		if err := r.Status().Update(context.TODO(), m.Cluster); err != nil {
+		finishUpgradeRecord(m, curvev1.UpgradeStateRolledBack)
+		k8sutil.RecordEvent(m, corev1.EventTypeNormal, k8sutil.ReasonRolledBack,
+			"cluster is rolled back to %q successfully", m.GetContainerImage())
+		if err := updateClusterReady(m, "cluster is rolled back successfully"); err != nil {
 			m.Logger.Error(err, "failed to update Curvefs")
 			return ctrl.Result{}, client.IgnoreNotFound(err)
 		}
@@ -344,8 +579,23 @@ func (r *CurvefsReconciler) reconcileCurvefsCluster(clusterObj *curvev1.Curvefs,
 	case curvev1.ClusterScaling:
 		// Perform the scale operation.
 		// The target status is Running, and continue to listen to other events.
-		m.Cluster.Status.Phase = curvev1.ClusterRunning
-		if err := r.Status().Update(context.TODO(), m.Cluster); err != nil {
+		removed, err := service.GetScaledInDeployments(m, dcs)
+		if err != nil {
+			m.Logger.Error(err, "failed to get the removed services")
+			return ctrl.Result{}, err
+		}
+		done, err := runSteps(m, dcs, getScaleSteps(m, removed))
+		if err != nil {
+			m.Logger.Error(err, "failed to scale cluster")
+			updateClusterFailed(m, curvev1.ClusterScaling, err)
+			return ctrl.Result{}, err
+		}
+		if !done {
+			return requeueForWaiting(m)
+		}
+
+		k8sutil.RecordEvent(m, corev1.EventTypeNormal, k8sutil.ReasonScaled, "cluster is scaled successfully")
+		if err := updateClusterReady(m, "cluster is scaled successfully"); err != nil {
 			m.Logger.Error(err, "failed to update Curvefs")
 			return ctrl.Result{}, client.IgnoreNotFound(err)
 		}
@@ -359,5 +609,34 @@ func (r *CurvefsReconciler) reconcileCurvefsCluster(clusterObj *curvev1.Curvefs,
 func (r *CurvefsReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&curvev1.Curvefs{}).
+		Owns(&appsv1.Deployment{}).
+		Owns(&corev1.ConfigMap{}).
+		Owns(&batchv1.Job{}).
+		Owns(&appsv1.DaemonSet{}).
+		Watches(&source.Kind{Type: &corev1.Secret{}}, &handler.EnqueueRequestsFromMapFunc{
+			ToRequests: handler.ToRequestsFunc(r.requestsForSecret),
+		}).
 		Complete(r)
 }
+
+// requestsForSecret returns the clusters that refer to the Secret, they're reconciled to render
+// the credentials into the config again once the Secret is changed.
+func (r *CurvefsReconciler) requestsForSecret(obj handler.MapObject) []reconcile.Request {
+	clusters := &curvev1.CurvefsList{}
+	if err := r.Client.List(context.TODO(), clusters, client.InNamespace(obj.Meta.GetNamespace())); err != nil {
+		logger.Errorf("failed to list Curvefs in namespace %s. %v", obj.Meta.GetNamespace(), err)
+		return nil
+	}
+
+	requests := []reconcile.Request{}
+	for _, cluster := range clusters.Items {
+		if cluster.Spec.S3 == nil || !refersToSecret(cluster.Spec.S3, obj.Meta.GetName()) {
+			continue
+		}
+		requests = append(requests, reconcile.Request{NamespacedName: types.NamespacedName{
+			Namespace: cluster.Namespace,
+			Name:      cluster.Name,
+		}})
+	}
+	return requests
+}
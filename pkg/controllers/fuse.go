@@ -0,0 +1,91 @@
+package controllers
+
+import (
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/opencurve/curve-operator/pkg/clusterd"
+	"github.com/opencurve/curve-operator/pkg/k8sutil"
+	"github.com/opencurve/curve-operator/pkg/service"
+	"github.com/opencurve/curve-operator/pkg/topology"
+)
+
+const (
+	// CLIENT_MDS_ADDRS is the item of client.conf that is the address of mds
+	CLIENT_MDS_ADDRS = "mdsOpt.rpcRetryOpt.addrs"
+)
+
+// isFuseEnabled returns true if the file systems of curvefs are mounted by FUSE clients
+func isFuseEnabled(m *clusterd.FsClusterManager) bool {
+	fuse := m.Cluster.Spec.Fuse
+	return fuse != nil && fuse.Enable
+}
+
+// renderClientConfig renders client.conf of the FUSE clients, it points at the mds of cluster and the
+// S3 service, and the config of the spec takes precedence.
+func renderClientConfig(m *clusterd.FsClusterManager, dc *topology.DeployConfig) (string, error) {
+	templateCM, err := k8sutil.GetConfigMapByName(m.GetContext().Clientset, m.GetNameSpace(), getConfigTemplateName(m))
+	if err != nil {
+		return "", err
+	}
+
+	specConfig, err := service.GetS3Config(m)
+	if err != nil {
+		return "", err
+	}
+	specConfig[strings.ToLower(CLIENT_MDS_ADDRS)] = "${cluster_mds_addr}"
+	for key, value := range m.Cluster.Spec.Fuse.Config {
+		specConfig[strings.ToLower(key)] = value
+	}
+	return renderConfig(dc, templateCM.Data[topology.LAYOUT_CLIENT_NAME], topology.LAYOUT_CLIENT_NAME, specConfig)
+}
+
+// reconcileFuse creates the file systems of a running cluster and mounts them on the nodes once the
+// FUSE clients are enabled, and unmounts them once it's disabled. The health of the mounts is recorded
+// in the status. It doesn't wait the mounts to be ready since the cluster is serving without them.
+func reconcileFuse(m *clusterd.FsClusterManager, dcs []*topology.DeployConfig) error {
+	if !isFuseEnabled(m) {
+		m.Cluster.Status.Mounts = nil
+		return service.StopFuse(m)
+	}
+
+	done, err := extractMissingTemplates(m, dcs, []string{topology.LAYOUT_CLIENT_NAME})
+	if err != nil || !done {
+		return err
+	}
+
+	// the jobs are owned by the cluster, it's reconciled again once they're completed
+	fuse := m.Cluster.Spec.Fuse
+	mdsDc := topology.FilterDeployConfigByRole(dcs, topology.ROLE_MDS)[0]
+	created := true
+	for _, name := range fuse.FileSystems {
+		job, err := service.StartJobCreateFs(m, mdsDc, name)
+		if err != nil {
+			return err
+		}
+		if k8sutil.IsJobFailed(job) {
+			return errors.Errorf("job %q to create file system %q failed", job.Name, name)
+		}
+		if !k8sutil.IsJobCompleted(job) {
+			created = false
+		}
+	}
+	if !created {
+		return nil
+	}
+
+	clientConf, err := renderClientConfig(m, mdsDc)
+	if err != nil {
+		return err
+	}
+	if _, err := service.StartFuse(m, fuse, clientConf); err != nil {
+		return err
+	}
+	mounts, err := service.GetMountStatus(m, fuse)
+	if err != nil {
+		return err
+	}
+	m.Cluster.Status.Mounts = mounts
+	return nil
+}
@@ -0,0 +1,139 @@
+package controllers
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	appsv1 "k8s.io/api/apps/v1"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	curvev1 "github.com/opencurve/curve-operator/api/v1"
+	"github.com/opencurve/curve-operator/pkg/clusterd"
+	"github.com/opencurve/curve-operator/pkg/k8sutil"
+	"github.com/opencurve/curve-operator/pkg/topology"
+)
+
+const (
+	metricsNamespace = "curve_operator"
+)
+
+// clusterPhases are all the phases that the cluster phase metric is reported for
+var clusterPhases = []curvev1.ClusterPhase{
+	curvev1.ClusterCreating,
+	curvev1.ClusterRunning,
+	curvev1.ClusterUpdating,
+	curvev1.ClusterUpgrading,
+	curvev1.ClusterRollingBack,
+	curvev1.ClusterScaling,
+	curvev1.ClusterDeleting,
+	curvev1.ClusterPhaseUnknown,
+}
+
+var (
+	clusterPhaseMetric = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "cluster_phase",
+		Help:      "The phase of the cluster, the current phase is 1 and the others are 0.",
+	}, []string{"namespace", "cluster", "kind", "phase"})
+
+	serviceReadyMetric = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "service_ready",
+		Help:      "Whether the Deployment of the service is ready, 1 if ready and 0 if not.",
+	}, []string{"namespace", "cluster", "kind", "role", "name"})
+
+	upgradeDurationMetric = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: metricsNamespace,
+		Name:      "upgrade_duration_seconds",
+		Help:      "The duration of the upgrades of the cluster from start to finish, by the final state.",
+		// from 1 minute to about 8 hours
+		Buckets: prometheus.ExponentialBuckets(60, 2, 10),
+	}, []string{"namespace", "cluster", "kind", "state"})
+
+	poolJobFailuresMetric = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "pool_job_failures_total",
+		Help:      "The number of the failed jobs to create the pools of the cluster.",
+	}, []string{"namespace", "cluster", "kind", "pool"})
+)
+
+func init() {
+	// the metrics are exposed on the metrics address of manager with the ones of controller-runtime
+	metrics.Registry.MustRegister(
+		clusterPhaseMetric,
+		serviceReadyMetric,
+		upgradeDurationMetric,
+		poolJobFailuresMetric,
+	)
+}
+
+// setClusterPhaseMetric reports the current phase of cluster
+func setClusterPhaseMetric(cluster clusterd.Clusterer) {
+	current := cluster.GetPhase()
+	for _, phase := range clusterPhases {
+		value := 0.0
+		if phase == current {
+			value = 1
+		}
+		clusterPhaseMetric.WithLabelValues(cluster.GetNameSpace(), cluster.GetName(), cluster.GetKind(),
+			string(phase)).Set(value)
+	}
+}
+
+// setServiceReadyMetric reports whether the Deployment of the service is ready
+func setServiceReadyMetric(cluster clusterd.Clusterer, dc *topology.DeployConfig, d *appsv1.Deployment) {
+	value := 0.0
+	if d != nil && k8sutil.IsDeploymentReady(d) {
+		value = 1
+	}
+	serviceReadyMetric.WithLabelValues(cluster.GetNameSpace(), cluster.GetName(), cluster.GetKind(),
+		dc.GetRole(), dc.GetName()).Set(value)
+}
+
+// updateServiceReadyMetrics reports the readiness of all the services of a running cluster, the
+// Deployments are listed at once rather than one by one.
+func updateServiceReadyMetrics(cluster clusterd.Clusterer, dcs []*topology.DeployConfig) error {
+	selector := k8sutil.GetLabelSelector(k8sutil.ClusterSelectorLabels(cluster, nil))
+	deployments, err := k8sutil.GetDeploymentsByLabelSelector(cluster.GetContext().Clientset, cluster.GetNameSpace(), selector)
+	if err != nil {
+		return err
+	}
+
+	byName := map[string]*appsv1.Deployment{}
+	for i := range deployments.Items {
+		byName[deployments.Items[i].Name] = &deployments.Items[i]
+	}
+	for _, dc := range dcs {
+		setServiceReadyMetric(cluster, dc, byName[clusterd.ResourceName(cluster, dc.GetName())])
+	}
+	return nil
+}
+
+// deleteServiceReadyMetric stops reporting the readiness of the removed service
+func deleteServiceReadyMetric(cluster clusterd.Clusterer, role, name string) {
+	serviceReadyMetric.DeleteLabelValues(cluster.GetNameSpace(), cluster.GetName(), cluster.GetKind(), role, name)
+}
+
+// observeUpgradeDuration reports the duration of the finished upgrade
+func observeUpgradeDuration(cluster clusterd.Clusterer, record *curvev1.UpgradeRecord) {
+	if record.FinishedAt == nil {
+		return
+	}
+	duration := record.FinishedAt.Sub(record.StartedAt.Time).Seconds()
+	upgradeDurationMetric.WithLabelValues(cluster.GetNameSpace(), cluster.GetName(), cluster.GetKind(),
+		string(record.State)).Observe(duration)
+}
+
+// incPoolJobFailures counts a failed job to create the pool
+func incPoolJobFailures(cluster clusterd.Clusterer, poolType string) {
+	poolJobFailuresMetric.WithLabelValues(cluster.GetNameSpace(), cluster.GetName(), cluster.GetKind(), poolType).Inc()
+}
+
+// deleteClusterMetrics stops reporting the metrics of the deleted cluster, the counters and the
+// histograms are kept since they're cumulative.
+func deleteClusterMetrics(cluster clusterd.Clusterer, dcs []*topology.DeployConfig) {
+	for _, phase := range clusterPhases {
+		clusterPhaseMetric.DeleteLabelValues(cluster.GetNameSpace(), cluster.GetName(), cluster.GetKind(), string(phase))
+	}
+	for _, dc := range dcs {
+		deleteServiceReadyMetric(cluster, dc.GetRole(), dc.GetName())
+	}
+}
@@ -0,0 +1,147 @@
+package controllers
+
+import (
+	"strings"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/opencurve/curve-operator/pkg/clusterd"
+	"github.com/opencurve/curve-operator/pkg/k8sutil"
+	"github.com/opencurve/curve-operator/pkg/service"
+	"github.com/opencurve/curve-operator/pkg/topology"
+	"github.com/opencurve/curve-operator/pkg/utils"
+)
+
+const (
+	// LEGACY_RESOURCE_PREFIX is the prefix of the Deployments and jobs that are created by the operator
+	// before the resources are named after the cluster, such as "curve-chunkserver0"
+	LEGACY_RESOURCE_PREFIX = "curve-"
+
+	LEGACY_CONFIG_TEMPLATE   = "curve-config-template"
+	LEGACY_CLUSTER_POOL      = "curve-cluster-pool"
+	LEGACY_BS_RECORD_CONFIG  = "bs-record-config"
+	LEGACY_FS_RECORD_CONFIG  = "fs-record-config"
+	LEGACY_AFTER_MUTATE_CONF = "after-mutate-conf"
+)
+
+// migrateLegacyResources adopts the resources of a cluster that is created before the resources are named
+// after the cluster. The ConfigMaps are copied to their new names so that the pool, the recorded config and
+// the rendered config are kept, then the legacy Deployments and jobs are deleted. The services are stopped
+// until they're started again with the new names, since the legacy pods hold the same host ports. It returns
+// true once there's no legacy resource left.
+func migrateLegacyResources(cluster clusterd.Clusterer) (bool, error) {
+	legacyRecordConfig := utils.Choose(cluster.GetKind() == topology.KIND_CURVEBS, LEGACY_BS_RECORD_CONFIG, LEGACY_FS_RECORD_CONFIG)
+	legacyConfigMaps := map[string]string{
+		LEGACY_CONFIG_TEMPLATE:   getConfigTemplateName(cluster),
+		LEGACY_CLUSTER_POOL:      clusterd.ResourceName(cluster, service.CURVE_TOPOLOGY_CONFIGMAP),
+		LEGACY_AFTER_MUTATE_CONF: clusterd.ResourceName(cluster, utils.AFTER_MUTATE_CONF),
+		legacyRecordConfig:       clusterd.ResourceName(cluster, RECORD_CONFIGMAP),
+	}
+	for legacyName, name := range legacyConfigMaps {
+		if err := migrateLegacyConfigMap(cluster, legacyName, name); err != nil {
+			return false, err
+		}
+	}
+
+	clientset := cluster.GetContext().Clientset
+	deployments, err := clientset.AppsV1().Deployments(cluster.GetNameSpace()).List(metav1.ListOptions{})
+	if err != nil {
+		return false, errors.Wrapf(err, "failed to list Deployments in namespace %s", cluster.GetNameSpace())
+	}
+	// the legacy pods are deleted before their Deployments by the foreground deletion
+	propagation := metav1.DeletePropagationForeground
+	removed := true
+	for i := range deployments.Items {
+		d := &deployments.Items[i]
+		if !isLegacyResource(cluster, d) {
+			continue
+		}
+		removed = false
+		if d.DeletionTimestamp != nil {
+			continue
+		}
+		err := clientset.AppsV1().Deployments(d.Namespace).Delete(d.Name, &metav1.DeleteOptions{PropagationPolicy: &propagation})
+		if err != nil && !apierrors.IsNotFound(err) {
+			return false, errors.Wrapf(err, "failed to delete Deployment %s in namespace %s", d.Name, d.Namespace)
+		}
+		logger.Infof("legacy Deployment %s of cluster %q is deleted", d.Name, cluster.GetName())
+		k8sutil.RecordEvent(cluster, corev1.EventTypeNormal, k8sutil.ReasonLegacyResourceRemoved,
+			"legacy Deployment %q is deleted, the service is started again with the name of cluster", d.Name)
+	}
+
+	jobs, err := clientset.BatchV1().Jobs(cluster.GetNameSpace()).List(metav1.ListOptions{})
+	if err != nil {
+		return false, errors.Wrapf(err, "failed to list jobs in namespace %s", cluster.GetNameSpace())
+	}
+	for i := range jobs.Items {
+		if !isLegacyResource(cluster, &jobs.Items[i]) {
+			continue
+		}
+		if err := k8sutil.DeleteBatchJob(clientset, cluster.GetNameSpace(), jobs.Items[i].Name, false); err != nil {
+			return false, err
+		}
+	}
+
+	if !removed {
+		cluster.GetProgress().Message = "waiting for the legacy Deployments to be deleted"
+	}
+	return removed, nil
+}
+
+// migrateLegacyConfigMap copies the legacy ConfigMap to its new name if the new one doesn't exist yet,
+// then deletes the legacy one. The legacy pool ConfigMap isn't owned by the cluster, it's adopted since
+// there's only one cluster in a namespace before the resources are named after the cluster.
+func migrateLegacyConfigMap(cluster clusterd.Clusterer, legacyName, name string) error {
+	clientset := cluster.GetContext().Clientset
+	legacy, err := k8sutil.GetConfigMapByName(clientset, cluster.GetNameSpace(), legacyName)
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil
+		}
+		return err
+	}
+	owner := metav1.GetControllerOf(legacy)
+	if (owner == nil && legacyName != LEGACY_CLUSTER_POOL) || (owner != nil && owner.UID != cluster.GetOwnerInfo().GetUID()) {
+		return nil
+	}
+
+	_, err = k8sutil.GetConfigMapByName(clientset, cluster.GetNameSpace(), name)
+	if err != nil && !apierrors.IsNotFound(err) {
+		return err
+	}
+	if apierrors.IsNotFound(err) {
+		cm := &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: cluster.GetNameSpace(),
+				Labels:    k8sutil.ClusterLabels(cluster, nil),
+			},
+			Data: legacy.Data,
+		}
+		if err := cluster.GetOwnerInfo().SetControllerReference(cm); err != nil {
+			return err
+		}
+		if err := k8sutil.CreateNewConfigMap(clientset, cm); err != nil {
+			return err
+		}
+	}
+
+	if err := k8sutil.DeleteConfigMap(clientset, legacy); err != nil {
+		return err
+	}
+	logger.Infof("legacy ConfigMap %s of cluster %q is migrated to %s", legacyName, cluster.GetName(), name)
+	k8sutil.RecordEvent(cluster, corev1.EventTypeNormal, k8sutil.ReasonLegacyResourceRemoved,
+		"legacy ConfigMap %q is migrated to %q", legacyName, name)
+	return nil
+}
+
+// isLegacyResource returns true if the object is owned by the cluster and named by the legacy prefix,
+// the resources that are named after the cluster are prefixed by its kind such as "curvebs-".
+func isLegacyResource(cluster clusterd.Clusterer, obj metav1.Object) bool {
+	owner := metav1.GetControllerOf(obj)
+	return owner != nil && owner.UID == cluster.GetOwnerInfo().GetUID() &&
+		strings.HasPrefix(obj.GetName(), LEGACY_RESOURCE_PREFIX)
+}
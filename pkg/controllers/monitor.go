@@ -0,0 +1,56 @@
+package controllers
+
+import (
+	"github.com/opencurve/curve-operator/pkg/clusterd"
+	"github.com/opencurve/curve-operator/pkg/monitor"
+	"github.com/opencurve/curve-operator/pkg/topology"
+)
+
+const (
+	STEP_START_MONITOR = "start-monitor"
+)
+
+// isMonitorEnabled returns true if the bundled monitor of cluster is enabled
+func isMonitorEnabled(cluster clusterd.Clusterer) bool {
+	spec := cluster.GetMonitorSpec()
+	return spec != nil && spec.Enable
+}
+
+// isServiceMonitorEnabled returns true if the metrics of cluster are scraped by an existing Prometheus Operator
+func isServiceMonitorEnabled(cluster clusterd.Clusterer) bool {
+	spec := cluster.GetMonitorSpec()
+	return spec != nil && spec.ServiceMonitor != nil && spec.ServiceMonitor.Enable
+}
+
+// startMonitorStep starts the monitor after all the services are started, the step is completed
+// when the ServiceMonitors are created and Prometheus, Grafana and node-exporter are ready.
+func startMonitorStep(cluster clusterd.Clusterer, dcs []*topology.DeployConfig) (bool, error) {
+	if isServiceMonitorEnabled(cluster) {
+		if err := monitor.StartServiceMonitors(cluster, dcs); err != nil {
+			return false, err
+		}
+	}
+	if isMonitorEnabled(cluster) {
+		return monitor.Start(cluster, dcs)
+	}
+	return true, nil
+}
+
+// reconcileMonitor applies the monitor of a running cluster so that the scrape targets follow the
+// services after scaling, and the monitor is started or stopped once it's enabled or disabled.
+// It doesn't wait the monitor to be ready since the cluster is serving without it.
+func reconcileMonitor(cluster clusterd.Clusterer, dcs []*topology.DeployConfig) error {
+	if isServiceMonitorEnabled(cluster) {
+		if err := monitor.StartServiceMonitors(cluster, dcs); err != nil {
+			return err
+		}
+	} else if err := monitor.StopServiceMonitors(cluster); err != nil {
+		return err
+	}
+
+	if !isMonitorEnabled(cluster) {
+		return monitor.Stop(cluster)
+	}
+	_, err := monitor.Start(cluster, dcs)
+	return err
+}
@@ -0,0 +1,116 @@
+package controllers
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/opencurve/curve-operator/pkg/clusterd"
+	"github.com/opencurve/curve-operator/pkg/k8sutil"
+	"github.com/opencurve/curve-operator/pkg/service"
+	"github.com/opencurve/curve-operator/pkg/topology"
+)
+
+const (
+	STEP_RESTART_SERVICE = "restart-%s"
+	STEP_RESTART_ZONE    = "restart-%s-%s"
+)
+
+// serviceChange is a change of spec that the running Deployment of a service doesn't follow yet
+type serviceChange struct {
+	what   string
+	reason string
+}
+
+// getServiceChanges returns the changes of spec that the Deployment of the service doesn't follow,
+// it returns nothing if the Deployment is missing since the drift correction recreates it.
+func getServiceChanges(cluster clusterd.Clusterer, dc *topology.DeployConfig) ([]serviceChange, error) {
+	name := clusterd.ResourceName(cluster, dc.GetName())
+	d, err := cluster.GetContext().Clientset.AppsV1().Deployments(cluster.GetNameSpace()).Get(name, metav1.GetOptions{})
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, errors.Wrapf(err, "failed to get Deployment %s", name)
+	}
+
+	changes := []serviceChange{}
+	secretHash, err := service.GetSecretHash(cluster, dc)
+	if err != nil {
+		return nil, err
+	}
+	if d.Spec.Template.Annotations[service.SECRET_HASH_ANNOTATION] != secretHash {
+		changes = append(changes, serviceChange{what: "Secrets", reason: k8sutil.ReasonSecretChanged})
+	}
+	if len(d.Spec.Template.Spec.Containers) > 0 &&
+		!equality.Semantic.DeepEqual(d.Spec.Template.Spec.Containers[0].Resources, service.GetServiceResources(cluster, dc)) {
+		changes = append(changes, serviceChange{what: "resources", reason: k8sutil.ReasonResourcesChanged})
+	}
+	placementChanged, err := service.IsServicePlacementChanged(cluster, dc, d)
+	if err != nil {
+		return nil, err
+	}
+	if placementChanged {
+		changes = append(changes, serviceChange{what: "placement", reason: k8sutil.ReasonPlacementChanged})
+	}
+	return changes, nil
+}
+
+// getRestartMessage returns the message to restart the services that don't follow the spec,
+// it returns an empty message if all of them are up to date.
+func getRestartMessage(cluster clusterd.Clusterer, dcs []*topology.DeployConfig) (string, error) {
+	changed := []string{}
+	for _, dc := range dcs {
+		changes, err := getServiceChanges(cluster, dc)
+		if err != nil {
+			return "", err
+		}
+		if len(changes) > 0 {
+			changed = append(changed, dc.GetName())
+		}
+	}
+	if len(changed) == 0 {
+		return "", nil
+	}
+	return fmt.Sprintf("start to restart services %s to apply the spec", strings.Join(changed, ", ")), nil
+}
+
+// getRestartSteps returns the steps to restart the services in the order of upgrade, so that
+// only one zone of chunkservers or metaservers is restarted at a time.
+func getRestartSteps(cluster clusterd.Clusterer, dcs []*topology.DeployConfig) ([]reconcileStep, error) {
+	return getRollingSteps(cluster, dcs, STEP_RESTART_SERVICE, STEP_RESTART_ZONE, restartServicesStep)
+}
+
+// restartServicesStep returns the step that applies the spec to the Deployments of services, the
+// ones that are up to date are kept as they are. The config of the services whose Secrets changed
+// is rendered again before they're restarted, since it's mounted by SubPath and never refreshed in
+// the running pods. The step is completed when all of them are ready.
+func restartServicesStep(dcs []*topology.DeployConfig) stepFunc {
+	return func(cluster clusterd.Clusterer, _ []*topology.DeployConfig) (bool, error) {
+		for _, dc := range dcs {
+			changes, err := getServiceChanges(cluster, dc)
+			if err != nil {
+				return false, err
+			}
+			name := clusterd.ResourceName(cluster, dc.GetName())
+			for _, change := range changes {
+				if change.reason == k8sutil.ReasonSecretChanged {
+					for _, conf := range dc.GetProjectLayout().ServiceConfFiles {
+						if err := mutateConfig(cluster, dc, conf.Name); err != nil {
+							return false, err
+						}
+					}
+				}
+				logger.Infof("Deployment %s of cluster %q is restarted since its %s changed", name, cluster.GetName(), change.what)
+				k8sutil.RecordEvent(cluster, corev1.EventTypeNormal, change.reason,
+					"Deployment %q of %s service is restarted since its %s changed", name, dc.GetRole(), change.what)
+			}
+		}
+		return startServices(cluster, dcs)
+	}
+}
@@ -0,0 +1,39 @@
+package controllers
+
+import (
+	corev1 "k8s.io/api/core/v1"
+
+	curvev1 "github.com/opencurve/curve-operator/api/v1"
+	"github.com/opencurve/curve-operator/pkg/clusterd"
+	"github.com/opencurve/curve-operator/pkg/service"
+	"github.com/opencurve/curve-operator/pkg/topology"
+)
+
+const (
+	STEP_START_S3 = "start-s3"
+)
+
+// startEmbeddedS3Step starts the embedded MinIO before the services that store data in it, the step
+// is completed when MinIO is ready and the bucket is created.
+func startEmbeddedS3Step(cluster clusterd.Clusterer, _ []*topology.DeployConfig) (bool, error) {
+	return service.StartEmbeddedS3(cluster)
+}
+
+// reconcileEmbeddedS3 applies the embedded MinIO of a running cluster and stops it once it's disabled,
+// it returns false if MinIO or its bucket is not ready yet.
+func reconcileEmbeddedS3(cluster clusterd.Clusterer) (bool, error) {
+	if !service.IsEmbeddedS3Enabled(cluster) {
+		return true, service.StopEmbeddedS3(cluster)
+	}
+	return service.StartEmbeddedS3(cluster)
+}
+
+// refersToSecret returns true if the credentials of the S3 config are read from the Secret
+func refersToSecret(s3 *curvev1.S3ConfigSpec, name string) bool {
+	for _, ref := range []*corev1.SecretKeySelector{s3.AccessKeySecretRef, s3.SecretKeySecretRef} {
+		if ref != nil && ref.Name == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,129 @@
+package controllers
+
+import (
+	"fmt"
+
+	"github.com/pkg/errors"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/opencurve/curve-operator/pkg/clusterd"
+	"github.com/opencurve/curve-operator/pkg/k8sutil"
+	"github.com/opencurve/curve-operator/pkg/service"
+	"github.com/opencurve/curve-operator/pkg/topology"
+)
+
+const (
+	STEP_REGISTER_SERVERS = "register-servers"
+	STEP_REMOVE_SERVICE   = "remove-%s"
+)
+
+// getScalingMessage returns the message to scale the cluster if any chunkserver or metaserver is
+// added or removed, or an empty string if the cluster doesn't need to be scaled.
+func getScalingMessage(cluster clusterd.Clusterer, dcs []*topology.DeployConfig) (string, error) {
+	added, err := service.GetScaledOutServices(cluster, dcs)
+	if err != nil {
+		return "", err
+	}
+	removed, err := service.GetScaledInDeployments(cluster, dcs)
+	if err != nil {
+		return "", err
+	}
+	if len(added) == 0 && len(removed) == 0 {
+		return "", nil
+	}
+
+	return fmt.Sprintf("start to scale cluster: %d %s services to add and %d to remove",
+		len(added), service.GetPoolServerRole(cluster), len(removed)), nil
+}
+
+// getScaleSteps returns the steps to scale the cluster in order: register the servers to the cluster
+// pool, start the new services, then drain the removed services and delete their Deployments.
+func getScaleSteps(cluster clusterd.Clusterer, removed []appsv1.Deployment) []reconcileStep {
+	role := service.GetPoolServerRole(cluster)
+	poolType, conditionType := getRolePoolType(cluster, topology.ROLE_MDS)
+	steps := []reconcileStep{
+		{name: STEP_REGISTER_SERVERS, run: createPoolStep(topology.ROLE_MDS, poolType, conditionType)},
+		{name: fmt.Sprintf(STEP_START_ROLE, role), run: startRoleStep(role)},
+	}
+	for i := range removed {
+		steps = append(steps, reconcileStep{
+			name: fmt.Sprintf(STEP_REMOVE_SERVICE, removed[i].Labels["name"]),
+			run:  removeServiceStep(&removed[i]),
+		})
+	}
+	return steps
+}
+
+// removeServiceStep returns the step that removes the service of the Deployment, the copysets of
+// a chunkserver are migrated to other chunkservers through MDS, then it's stopped and retired from
+// the topology of MDS before its Deployment is deleted. The Deployment is kept until the end so that
+// the step is found again after the operator is restarted.
+func removeServiceStep(d *appsv1.Deployment) stepFunc {
+	return func(cluster clusterd.Clusterer, dcs []*topology.DeployConfig) (bool, error) {
+		if cluster.GetKind() == topology.KIND_CURVEBS {
+			done, err := runChunkserverJob(cluster, dcs, d, service.StartJobDrainChunkserver, "migrate the copysets of")
+			if err != nil || !done {
+				return false, err
+			}
+			stopped, err := service.StopService(cluster, d)
+			if err != nil {
+				return false, err
+			}
+			if !stopped {
+				cluster.GetProgress().Message = fmt.Sprintf("waiting for the pod of Deployment %q to stop", d.Name)
+				return false, nil
+			}
+			done, err = runChunkserverJob(cluster, dcs, d, service.StartJobRetireChunkserver, "retire")
+			if err != nil || !done {
+				return false, err
+			}
+		}
+
+		if err := service.RemoveService(cluster, d); err != nil {
+			return false, err
+		}
+		deleteServiceReadyMetric(cluster, d.Labels["role"], d.Labels["name"])
+		return true, nil
+	}
+}
+
+// runChunkserverJob runs the job started by startJob against the chunkserver of the Deployment, it
+// returns true once the job is completed and an error that is surfaced in the conditions if it fails.
+func runChunkserverJob(cluster clusterd.Clusterer, dcs []*topology.DeployConfig, d *appsv1.Deployment,
+	startJob func(clusterd.Clusterer, *topology.DeployConfig, *appsv1.Deployment) (*batchv1.Job, error), action string) (bool, error) {
+	mdsDcs := topology.FilterDeployConfigByRole(dcs, topology.ROLE_MDS)
+	if len(mdsDcs) == 0 {
+		return false, errors.Errorf("no mds service to %s the chunkserver", action)
+	}
+
+	job, err := startJob(cluster, mdsDcs[0], d)
+	if err != nil {
+		return false, err
+	}
+
+	switch {
+	case k8sutil.IsJobCompleted(job):
+		return true, nil
+	case k8sutil.IsJobFailed(job):
+		// delete the failed job so that it's created again by the next reconcile
+		if err := k8sutil.DeleteBatchJob(cluster.GetContext().Clientset, job.Namespace, job.Name, false); err != nil {
+			logger.Errorf("failed to delete the failed job %q. %v", job.Name, err)
+		}
+		return false, errors.Errorf("job %q to %s Deployment %q failed: %s", job.Name, action, d.Name, getJobFailedMessage(job))
+	}
+
+	cluster.GetProgress().Message = fmt.Sprintf("waiting for job %q to %s Deployment %q", job.Name, action, d.Name)
+	return false, nil
+}
+
+// getJobFailedMessage returns the reason that the job failed for, such as the deadline is exceeded
+func getJobFailedMessage(job *batchv1.Job) string {
+	for _, condition := range job.Status.Conditions {
+		if condition.Type == batchv1.JobFailed && condition.Status == corev1.ConditionTrue {
+			return fmt.Sprintf("%s, %s", condition.Reason, condition.Message)
+		}
+	}
+	return "unknown reason"
+}
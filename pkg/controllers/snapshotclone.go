@@ -0,0 +1,57 @@
+package controllers
+
+import (
+	curvev1 "github.com/opencurve/curve-operator/api/v1"
+	"github.com/opencurve/curve-operator/pkg/clusterd"
+	"github.com/opencurve/curve-operator/pkg/k8sutil"
+	"github.com/opencurve/curve-operator/pkg/service"
+	"github.com/opencurve/curve-operator/pkg/topology"
+)
+
+// isSnapShotCloneEnabled returns true if the snapshotclone services are deployed
+func isSnapShotCloneEnabled(cluster clusterd.Clusterer) bool {
+	snapshot := cluster.GetSnapShotSpec()
+	return snapshot != nil && snapshot.Enable
+}
+
+// reconcileSnapShotClone starts the snapshotclone services of a running cluster once it's enabled
+// and removes them once it's disabled, it returns false if the started services are not ready yet.
+// The services that are started are kept by correcting the drift like the others.
+func reconcileSnapShotClone(m *clusterd.BsClusterManager, dcs []*topology.DeployConfig) (bool, error) {
+	if !isSnapShotCloneEnabled(m) {
+		return true, removeSnapShotClone(m)
+	}
+
+	if k8sutil.IsConditionTrue(m.Cluster.Status.Conditions, curvev1.ConditionSnapShotCloneReady) {
+		return true, nil
+	}
+	done, err := extractMissingTemplates(m, dcs, topology.ServiceConfigs[topology.ROLE_SNAPSHOTCLONE])
+	if err != nil || !done {
+		return false, err
+	}
+	return startRoleStep(topology.ROLE_SNAPSHOTCLONE)(m, dcs)
+}
+
+// removeSnapShotClone deletes the Deployments of the snapshotclone services
+func removeSnapShotClone(cluster clusterd.Clusterer) error {
+	selector := k8sutil.GetLabelSelector(k8sutil.ClusterSelectorLabels(cluster,
+		map[string]string{"role": topology.ROLE_SNAPSHOTCLONE}))
+	deployments, err := k8sutil.GetDeploymentsByLabelSelector(cluster.GetContext().Clientset, cluster.GetNameSpace(), selector)
+	if err != nil {
+		return err
+	}
+	if len(deployments.Items) == 0 {
+		return nil
+	}
+
+	for i := range deployments.Items {
+		d := &deployments.Items[i]
+		if err := service.RemoveService(cluster, d); err != nil {
+			return err
+		}
+		deleteServiceReadyMetric(cluster, d.Labels["role"], d.Labels["name"])
+	}
+	updateRoleCondition(cluster, curvev1.ConditionSnapShotCloneReady, curvev1.ConditionStatusFalse,
+		curvev1.ConditionRoleDisabled, "snapshotclone is disabled")
+	return nil
+}
@@ -0,0 +1,270 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	curvev1 "github.com/opencurve/curve-operator/api/v1"
+	"github.com/opencurve/curve-operator/pkg/clusterd"
+	"github.com/opencurve/curve-operator/pkg/k8sutil"
+	"github.com/opencurve/curve-operator/pkg/service"
+	"github.com/opencurve/curve-operator/pkg/topology"
+	"github.com/opencurve/curve-operator/pkg/utils"
+)
+
+const (
+	STEP_UPGRADE_SERVICE = "upgrade-%s"
+	STEP_UPGRADE_ZONE    = "upgrade-%s-%s"
+	STEP_CHECK_HEALTH    = "check-%s"
+
+	// maxUpgradeHistory is the number of upgrade records kept in status
+	maxUpgradeHistory = 10
+)
+
+// getUpgradeSteps returns the steps to upgrade the cluster in the documented order: etcd, mds with
+// the leader last, chunkservers or metaservers one zone at a time, then snapshotclone. The health
+// of copysets is checked after each role and each zone before moving on.
+func getUpgradeSteps(cluster clusterd.Clusterer, dcs []*topology.DeployConfig) ([]reconcileStep, error) {
+	return getRollingSteps(cluster, dcs, STEP_UPGRADE_SERVICE, STEP_UPGRADE_ZONE, upgradeServicesStep)
+}
+
+// getRollingSteps returns the steps that run on the services in the order of upgrade, the services
+// are named by serviceStep or zoneStep and the health of copysets is checked between them.
+func getRollingSteps(cluster clusterd.Clusterer, dcs []*topology.DeployConfig, serviceStep, zoneStep string,
+	run func([]*topology.DeployConfig) stepFunc) ([]reconcileStep, error) {
+	steps := []reconcileStep{}
+	for _, role := range getClusterRoles(cluster) {
+		roleDcs := topology.FilterDeployConfigByRole(dcs, role)
+		if len(roleDcs) == 0 {
+			continue
+		}
+
+		if role == service.GetPoolServerRole(cluster) {
+			zoneSteps, err := getZoneRollingSteps(cluster, role, roleDcs, zoneStep, run)
+			if err != nil {
+				return nil, err
+			}
+			steps = append(steps, zoneSteps...)
+			continue
+		}
+
+		if role == topology.ROLE_MDS {
+			roleDcs = sortMdsLeaderLast(roleDcs)
+		}
+		for _, dc := range roleDcs {
+			steps = append(steps, reconcileStep{
+				name: fmt.Sprintf(serviceStep, dc.GetName()),
+				run:  run([]*topology.DeployConfig{dc}),
+			})
+		}
+		steps = append(steps, reconcileStep{name: fmt.Sprintf(STEP_CHECK_HEALTH, role), run: checkHealthStep(role)})
+	}
+	return steps, nil
+}
+
+// getZoneRollingSteps returns the steps that run on the services of role one zone at a time
+func getZoneRollingSteps(cluster clusterd.Clusterer, role string, dcs []*topology.DeployConfig, zoneStep string,
+	run func([]*topology.DeployConfig) stepFunc) ([]reconcileStep, error) {
+	serviceZones, err := service.GetServiceZones(cluster, dcs)
+	if err != nil {
+		return nil, err
+	}
+
+	zoneDcs := map[string][]*topology.DeployConfig{}
+	for _, dc := range dcs {
+		zone := serviceZones[dc.GetName()]
+		zoneDcs[zone] = append(zoneDcs[zone], dc)
+	}
+	zones := []string{}
+	for zone := range zoneDcs {
+		zones = append(zones, zone)
+	}
+	sort.Strings(zones)
+
+	steps := []reconcileStep{}
+	for _, zone := range zones {
+		name := fmt.Sprintf(zoneStep, role, utils.Choose(len(zone) > 0, zone, "unknown-zone"))
+		steps = append(steps,
+			reconcileStep{name: name, run: run(zoneDcs[zone])},
+			reconcileStep{name: fmt.Sprintf(STEP_CHECK_HEALTH, name), run: checkHealthStep(name)},
+		)
+	}
+	return steps, nil
+}
+
+// sortMdsLeaderLast moves the leader of mds to the last so that the leader is switched only once
+func sortMdsLeaderLast(dcs []*topology.DeployConfig) []*topology.DeployConfig {
+	leader := service.GetMdsLeader(dcs)
+	if leader == nil {
+		logger.Warning("failed to find the leader of mds, upgrade mds in the order of nodes")
+		return dcs
+	}
+
+	sorted := []*topology.DeployConfig{}
+	for _, dc := range dcs {
+		if dc != leader {
+			sorted = append(sorted, dc)
+		}
+	}
+	return append(sorted, leader)
+}
+
+// upgradeServicesStep returns the step that updates the Deployments of services to the new image,
+// the step is completed when all of them are ready.
+func upgradeServicesStep(dcs []*topology.DeployConfig) stepFunc {
+	return func(cluster clusterd.Clusterer, _ []*topology.DeployConfig) (bool, error) {
+		ready, err := startServices(cluster, dcs)
+		if err != nil || !ready {
+			return false, err
+		}
+
+		if record := getUpgradeRecord(cluster); record != nil {
+			for _, dc := range dcs {
+				setServiceImage(record, dc.GetName(), cluster.GetContainerImage())
+			}
+		}
+		return true, nil
+	}
+}
+
+// checkHealthStep returns the step that runs the job to wait the copysets to be healthy
+func checkHealthStep(name string) stepFunc {
+	return func(cluster clusterd.Clusterer, dcs []*topology.DeployConfig) (bool, error) {
+		mdsDcs := topology.FilterDeployConfigByRole(dcs, topology.ROLE_MDS)
+		if len(mdsDcs) == 0 {
+			return false, errors.New("no mds service to check the health of cluster")
+		}
+
+		job, err := service.StartJobCheckHealth(cluster, mdsDcs[0], name)
+		if err != nil {
+			return false, err
+		}
+
+		switch {
+		case k8sutil.IsJobCompleted(job), k8sutil.IsJobFailed(job):
+			// the job is deleted anyway so that it's created again by the next check
+			if err := k8sutil.DeleteBatchJob(cluster.GetContext().Clientset, job.Namespace, job.Name, false); err != nil {
+				logger.Errorf("failed to delete the job %q. %v", job.Name, err)
+			}
+			if k8sutil.IsJobFailed(job) {
+				return false, errors.Errorf("copysets are not healthy after %s, job %q failed", name, job.Name)
+			}
+			return true, nil
+		}
+
+		cluster.GetProgress().Message = fmt.Sprintf("waiting for copysets to be healthy after %s", name)
+		return false, nil
+	}
+}
+
+// isUpgradePaused returns true if the upgrade is paused by annotation of the cluster
+func isUpgradePaused(annotations map[string]string) bool {
+	return utils.IsTrueStr(annotations[curvev1.AnnotationPauseUpgrade])
+}
+
+// pauseUpgrade records that the upgrade is paused in the progress of cluster status
+func pauseUpgrade(cluster clusterd.Clusterer) error {
+	progress := cluster.GetProgress()
+	message := fmt.Sprintf("upgrade is paused by annotation %q", curvev1.AnnotationPauseUpgrade)
+	if progress.Message == message {
+		return nil
+	}
+
+	progress.Message = message
+	k8sutil.RecordEvent(cluster, corev1.EventTypeNormal, k8sutil.ReasonUpgradePaused,
+		"upgrade is paused after %d completed steps", len(progress.CompletedSteps))
+	return k8sutil.UpdateClusterStatus(cluster)
+}
+
+// getUpgradeRecord returns the record of the latest upgrade, it returns nil if the cluster is never upgraded
+func getUpgradeRecord(cluster clusterd.Clusterer) *curvev1.UpgradeRecord {
+	history := *cluster.GetUpgradeHistory()
+	if len(history) == 0 {
+		return nil
+	}
+	return &history[len(history)-1]
+}
+
+// startUpgradeRecord records a new upgrade from the version that services are running to image,
+// the oldest records are dropped to keep at most maxUpgradeHistory records.
+func startUpgradeRecord(cluster clusterd.Clusterer, dcs []*topology.DeployConfig, image string) {
+	record := curvev1.UpgradeRecord{
+		FromImage: cluster.GetVersion().Image,
+		ToImage:   image,
+		State:     curvev1.UpgradeStateUpgrading,
+		StartedAt: metav1.Now(),
+	}
+	for _, dc := range dcs {
+		record.Services = append(record.Services, curvev1.ServiceVersion{Name: dc.GetName(), Image: record.FromImage})
+	}
+
+	history := cluster.GetUpgradeHistory()
+	*history = append(*history, record)
+	if len(*history) > maxUpgradeHistory {
+		*history = (*history)[len(*history)-maxUpgradeHistory:]
+	}
+}
+
+// finishUpgradeRecord marks the latest upgrade as finished with state
+func finishUpgradeRecord(cluster clusterd.Clusterer, state curvev1.UpgradeState) {
+	record := getUpgradeRecord(cluster)
+	if record == nil {
+		return
+	}
+	now := metav1.Now()
+	record.State = state
+	record.FinishedAt = &now
+	observeUpgradeDuration(cluster, record)
+}
+
+// setServiceImage records the image that the service is running in the upgrade record
+func setServiceImage(record *curvev1.UpgradeRecord, name, image string) {
+	for i := range record.Services {
+		if record.Services[i].Name == name {
+			record.Services[i].Image = image
+			return
+		}
+	}
+	record.Services = append(record.Services, curvev1.ServiceVersion{Name: name, Image: image})
+}
+
+// isUpgradeRolledBack returns true if the latest upgrade to image is rolled back, the image isn't
+// upgraded to again until it's changed in spec.
+func isUpgradeRolledBack(cluster clusterd.Clusterer, image string) bool {
+	record := getUpgradeRecord(cluster)
+	return record != nil && record.ToImage == image && record.State == curvev1.UpgradeStateRolledBack
+}
+
+// isRollbackRequested returns true if the rollback of upgrade is requested by annotation of the cluster
+func isRollbackRequested(annotations map[string]string) bool {
+	return utils.IsTrueStr(annotations[curvev1.AnnotationRollback])
+}
+
+// startRollback removes the rollback annotation and moves the cluster to RollingBack phase, the
+// services are deployed with the version in status again by the same steps of upgrade.
+func startRollback(cluster clusterd.Clusterer) error {
+	// the annotation is removed before the status is changed, because the update of object
+	// overwrites the status in memory with the one in server
+	accessor, err := meta.Accessor(cluster.GetObject())
+	if err != nil {
+		return err
+	}
+	annotations := accessor.GetAnnotations()
+	delete(annotations, curvev1.AnnotationRollback)
+	accessor.SetAnnotations(annotations)
+	if err := cluster.GetContext().Client.Update(context.TODO(), cluster.GetObject()); err != nil {
+		return errors.Wrapf(err, "failed to remove annotation %q", curvev1.AnnotationRollback)
+	}
+
+	record := getUpgradeRecord(cluster)
+	record.State = curvev1.UpgradeStateRollingBack
+	message := fmt.Sprintf("start to roll back cluster from %q to %q", record.ToImage, record.FromImage)
+	k8sutil.RecordEvent(cluster, corev1.EventTypeNormal, k8sutil.ReasonRollingBack, "%s", message)
+	return updateClusterProgressing(cluster, curvev1.ClusterRollingBack, curvev1.ConditionRollingBackReason, message)
+}
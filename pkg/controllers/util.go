@@ -10,6 +10,7 @@ import (
 	curvev1 "github.com/opencurve/curve-operator/api/v1"
 	"github.com/opencurve/curve-operator/pkg/clusterd"
 	"github.com/opencurve/curve-operator/pkg/k8sutil"
+	"github.com/opencurve/curve-operator/pkg/service"
 	"github.com/opencurve/curve-operator/pkg/topology"
 	"github.com/opencurve/curve-operator/pkg/utils"
 
@@ -19,8 +20,8 @@ import (
 )
 
 const (
-	BS_RECORD_CONFIGMAP = "bs-record-config"
-	FS_RECORD_CONFIGMAP = "fs-record-config"
+	RECORD_CONFIGMAP  = "record-config"
+	NGINX_CONFIG_FILE = "nginx.conf"
 )
 
 var roles = []string{
@@ -93,14 +94,13 @@ func parseSpecParameters(cluster clusterd.Clusterer) (map[string]map[string]stri
 }
 
 func createorUpdateRecordConfigMap(cluster clusterd.Clusterer) error {
-	configmapName := topology.Choose(
-		cluster.GetKind() == topology.KIND_CURVEBS, BS_RECORD_CONFIGMAP, FS_RECORD_CONFIGMAP)
 	_, mapStringData := parseSpecParameters(cluster)
 
 	cm := &corev1.ConfigMap{
 		ObjectMeta: metav1.ObjectMeta{
-			Name:      configmapName,
+			Name:      clusterd.ResourceName(cluster, RECORD_CONFIGMAP),
 			Namespace: cluster.GetNameSpace(),
+			Labels:    k8sutil.ClusterLabels(cluster, nil),
 		},
 		Data: mapStringData,
 	}
@@ -120,9 +120,7 @@ func createorUpdateRecordConfigMap(cluster clusterd.Clusterer) error {
 
 // getDataFromRecordConfigMap reads data from the ConfigMap of the record and returns the data for formatting
 func getDataFromRecordConfigMap(cluster clusterd.Clusterer) (map[string]map[string]string, error) {
-	configmapName := topology.Choose(
-		cluster.GetKind() == topology.KIND_CURVEBS, BS_RECORD_CONFIGMAP, FS_RECORD_CONFIGMAP)
-
+	configmapName := clusterd.ResourceName(cluster, RECORD_CONFIGMAP)
 	cm, err := k8sutil.GetConfigMapByName(cluster.GetContext().Clientset, cluster.GetNameSpace(), configmapName)
 	if err != nil {
 		return nil, err
@@ -152,28 +150,33 @@ func getDataFromRecordConfigMap(cluster clusterd.Clusterer) (map[string]map[stri
 	return allData, nil
 }
 
-// constructTemplateConfigMap start a dummy Deployment service and read template config file to a ConfigMap
-func constructConfigMap(cluster clusterd.Clusterer, dcs []*topology.DeployConfig) error {
-	if err := makeDummyDeployment(cluster, dcs); err != nil {
-		return err
+// constructConfigMap extracts the config templates from the dummy Deployment once it's ready,
+// it returns false if the dummy Deployment is not ready yet.
+func constructConfigMap(cluster clusterd.Clusterer, dcs []*topology.DeployConfig) (bool, error) {
+	ready, err := makeDummyDeployment(cluster, dcs)
+	if err != nil || !ready {
+		return false, err
 	}
 
 	if err := makeTemplateConfigMap(cluster, dcs); err != nil {
-		return err
+		return false, err
 	}
+	k8sutil.RecordEvent(cluster, corev1.EventTypeNormal, k8sutil.ReasonConfigTemplateExtracted,
+		"config templates are extracted from image %q into ConfigMap %q", cluster.GetContainerImage(), getConfigTemplateName(cluster))
 
 	if _, err := makeMutateConfigMap(cluster); err != nil {
-		return err
+		return false, err
 	}
 
-	return nil
+	return true, nil
 }
 
 func makeMutateConfigMap(cluster clusterd.Clusterer) (*corev1.ConfigMap, error) {
 	cm := &corev1.ConfigMap{
 		ObjectMeta: metav1.ObjectMeta{
-			Name:      utils.AFTER_MUTATE_CONF,
+			Name:      clusterd.ResourceName(cluster, utils.AFTER_MUTATE_CONF),
 			Namespace: cluster.GetNameSpace(),
+			Labels:    k8sutil.ClusterLabels(cluster, nil),
 		},
 		Data: map[string]string{},
 	}
@@ -192,17 +195,39 @@ func makeMutateConfigMap(cluster clusterd.Clusterer) (*corev1.ConfigMap, error)
 }
 
 func mutateConfig(cluster clusterd.Clusterer, dc *topology.DeployConfig, name string) error {
-	templateCM, err := cluster.GetContext().Clientset.CoreV1().ConfigMaps(cluster.GetNameSpace()).Get(CURVE_CONFIG_TEMPLATE, metav1.GetOptions{})
+	templateCM, err := cluster.GetContext().Clientset.CoreV1().ConfigMaps(cluster.GetNameSpace()).Get(getConfigTemplateName(cluster), metav1.GetOptions{})
 	if err != nil {
 		return err
 	}
-	afterMutateCM, err := cluster.GetContext().Clientset.CoreV1().ConfigMaps(cluster.GetNameSpace()).Get(utils.AFTER_MUTATE_CONF, metav1.GetOptions{})
+	afterMutateCM, err := cluster.GetContext().Clientset.CoreV1().ConfigMaps(cluster.GetNameSpace()).Get(clusterd.ResourceName(cluster, utils.AFTER_MUTATE_CONF), metav1.GetOptions{})
 	if err != nil {
 		return err
 	}
 
-	input := templateCM.Data[name]
+	specConfig, err := service.GetS3Config(cluster)
+	if err != nil {
+		return err
+	}
+	content, err := renderConfig(dc, templateCM.Data[name], name, specConfig)
+	if err != nil {
+		return err
+	}
+	if afterMutateCM.Data == nil {
+		afterMutateCM.Data = map[string]string{}
+	}
+	afterMutateCM.Data[getMutateConfigKey(dc, name)] = content
 
+	_, err = k8sutil.UpdateConfigMap(cluster.GetContext().Clientset, afterMutateCM)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// renderConfig renders the config template of the service with its config and variables, specConfig
+// is the config resolved from the spec of cluster such as the credentials of S3 service.
+func renderConfig(dc *topology.DeployConfig, input, name string, specConfig map[string]string) (string, error) {
 	var key, value string
 	output := []string{}
 	scanner := bufio.NewScanner(strings.NewReader(input))
@@ -210,25 +235,21 @@ func mutateConfig(cluster clusterd.Clusterer, dc *topology.DeployConfig, name st
 		in := scanner.Text()
 		err := kvFilter(dc, in, &key, &value)
 		if err != nil {
-			return err
+			return "", err
 		}
-		out, err := mutate(dc, in, key, value, name)
+		out, err := mutate(dc, in, key, value, name, specConfig)
 		if err != nil {
-			return err
+			return "", err
 		}
 
 		output = append(output, out)
 	}
-	content := strings.Join(output, "\n")
-	afterKey := fmt.Sprintf("%s_%s", dc.GetName(), name)
-	afterMutateCM.Data[afterKey] = content
-
-	_, err = k8sutil.UpdateConfigMap(cluster.GetContext().Clientset, afterMutateCM)
-	if err != nil {
-		return err
-	}
+	return strings.Join(output, "\n"), nil
+}
 
-	return nil
+// getMutateConfigKey returns the key of the rendered config of the service in after-mutate ConfigMap
+func getMutateConfigKey(dc *topology.DeployConfig, name string) string {
+	return fmt.Sprintf("%s_%s", dc.GetName(), name)
 }
 
 func kvFilter(dc *topology.DeployConfig, line string, key, value *string) error {
@@ -250,20 +271,27 @@ func kvFilter(dc *topology.DeployConfig, line string, key, value *string) error
 	return nil
 }
 
-func mutate(dc *topology.DeployConfig, in, key, value string, name string) (out string, err error) {
+func mutate(dc *topology.DeployConfig, in, key, value string, name string, specConfig map[string]string) (out string, err error) {
+	// nginx.conf is not a key-value config, the variables such as the upstream servers of
+	// snapshotclone are rendered in every line of it
+	if name == NGINX_CONFIG_FILE {
+		return dc.GetVariables().Rendering(in)
+	}
 	if len(key) == 0 {
 		out = in
-		if name == "nginx.conf" { // only for nginx.conf
-			out, err = dc.GetVariables().Rendering(in)
-		}
 		return
 	}
 
-	// replace config
-	v, ok := dc.GetServiceConfig()[strings.ToLower(key)]
-	if ok {
+	// replace config, the config of role takes precedence over the one resolved from spec
+	if v, ok := specConfig[strings.ToLower(key)]; ok {
 		value = v
 	}
+	// client.conf is not the config of any service, the config of role doesn't apply to it
+	if name != topology.LAYOUT_CLIENT_NAME {
+		if v, ok := dc.GetServiceConfig()[strings.ToLower(key)]; ok {
+			value = v
+		}
+	}
 
 	// replace variable
 	value, err = dc.GetVariables().Rendering(value)
@@ -271,5 +299,6 @@ func mutate(dc *topology.DeployConfig, in, key, value string, name string) (out
 		return
 	}
 
+	out = fmt.Sprintf("%s%s%s", key, dc.GetConfigKvFilter(), value)
 	return
 }
@@ -0,0 +1,467 @@
+package csi
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/coreos/pkg/capnslog"
+	"github.com/pkg/errors"
+	appsv1 "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/opencurve/curve-operator/pkg/clusterd"
+	"github.com/opencurve/curve-operator/pkg/k8sutil"
+	"github.com/opencurve/curve-operator/pkg/utils"
+)
+
+var logger = capnslog.NewPackageLogger("github.com/opencurve/curve-operator", "csi")
+
+const (
+	CSI_CONTROLLER = "csi-controller"
+	CSI_NODE       = "csi-node"
+	CSI_CONFIG     = "csi-config"
+	CSI_PLUGIN     = "csi-plugin"
+
+	CSI_SOCKET_DIR     = "/csi"
+	CSI_SOCKET_ADDRESS = "unix:///csi/csi.sock"
+
+	// CONFIG_HASH_ANNOTATION is the annotation of the pod template that records the hash of the config
+	// of driver, the plugins are restarted once the config is changed such as the addresses of mds.
+	CONFIG_HASH_ANNOTATION = "operator.curve.io/config-hash"
+)
+
+// Driver is the CSI driver of a kind of cluster, it's deployed in the same way with the config and
+// the parameters of StorageClass generated from the cluster
+type Driver struct {
+	// Config is the files of config that are mounted into the plugins at ConfigDir, it's optional
+	Config    map[string]string
+	ConfigDir string
+	// Parameters are the parameters of StorageClass that are generated from the cluster
+	Parameters           map[string]string
+	AllowVolumeExpansion bool
+	// ControllerArgs and NodeArgs are the args of the plugin of controller and node besides the endpoint
+	ControllerArgs []string
+	NodeArgs       []string
+	// NodeVolumes and NodeVolumeMounts are the directories on host that the node plugin uses besides
+	// the ones of kubelet
+	NodeVolumes      []v1.Volume
+	NodeVolumeMounts []v1.VolumeMount
+}
+
+// getClusterScopedName returns the name of the cluster-scoped resource of driver, the namespace is
+// included since the clusters in different namespaces may have the same name
+func getClusterScopedName(cluster clusterd.Clusterer) string {
+	return fmt.Sprintf("%s-%s", cluster.GetNameSpace(), clusterd.ResourceName(cluster, "csi"))
+}
+
+// getLabels returns the labels of the component of driver
+func getLabels(app string) map[string]string {
+	return map[string]string{"app": app}
+}
+
+// NewHostPathVolume returns the volume of the path on host and its mount at the same path
+func NewHostPathVolume(name, hostPath string, hostPathType v1.HostPathType) (v1.Volume, v1.VolumeMount) {
+	vol := v1.Volume{
+		Name: name,
+		VolumeSource: v1.VolumeSource{
+			HostPath: &v1.HostPathVolumeSource{Path: hostPath, Type: &hostPathType},
+		},
+	}
+	return vol, v1.VolumeMount{Name: name, MountPath: hostPath}
+}
+
+// Start creates or updates the CSI driver of cluster, includes the RBAC of the sidecars, the config,
+// the controller plugin, the node plugins and the StorageClass. It doesn't wait the plugins to be ready.
+func Start(cluster clusterd.Clusterer, driver *Driver) error {
+	serviceAccount, err := startRBAC(cluster)
+	if err != nil {
+		return err
+	}
+	configHash, err := startConfig(cluster, driver)
+	if err != nil {
+		return err
+	}
+	if err := startController(cluster, driver, serviceAccount, configHash); err != nil {
+		return err
+	}
+	if err := startNode(cluster, driver, serviceAccount, configHash); err != nil {
+		return err
+	}
+	return startStorageClass(cluster, driver)
+}
+
+// Stop deletes the CSI driver of cluster, the PVs that are provisioned are kept but can't be attached
+// until it's started again.
+func Stop(cluster clusterd.Clusterer) error {
+	clientset := cluster.GetContext().Clientset
+	namespace := cluster.GetNameSpace()
+
+	d := &appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{
+		Name:      clusterd.ResourceName(cluster, CSI_CONTROLLER),
+		Namespace: namespace,
+	}}
+	exist, err := k8sutil.IsDeploymentExist(clientset, d)
+	if err != nil {
+		return err
+	}
+	if exist {
+		if err := k8sutil.DeleteDeployment(clientset, d); err != nil {
+			return err
+		}
+		k8sutil.RecordEvent(cluster, v1.EventTypeNormal, k8sutil.ReasonCSIStopped, "CSI driver is stopped")
+	}
+	if err := k8sutil.DeleteDaemonSetIfExists(clientset, namespace, clusterd.ResourceName(cluster, CSI_NODE)); err != nil {
+		return err
+	}
+
+	// the cluster-scoped resources are not owned by the cluster, they must be deleted explicitly
+	if err := deleteStaleStorageClasses(cluster, ""); err != nil {
+		return err
+	}
+	if err := k8sutil.DeleteClusterRoleBindingIfExists(clientset, getClusterScopedName(cluster)); err != nil {
+		return err
+	}
+	return k8sutil.DeleteClusterRoleIfExists(clientset, getClusterScopedName(cluster))
+}
+
+// startRBAC creates the ServiceAccount of the plugins and grants the sidecars to provision, attach
+// and resize the volumes, it returns the name of the ServiceAccount.
+func startRBAC(cluster clusterd.Clusterer) (string, error) {
+	clientset := cluster.GetContext().Clientset
+	sa := &v1.ServiceAccount{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      clusterd.ResourceName(cluster, "csi"),
+			Namespace: cluster.GetNameSpace(),
+			Labels:    k8sutil.ClusterLabels(cluster, nil),
+		},
+	}
+	if err := cluster.GetOwnerInfo().SetControllerReference(sa); err != nil {
+		return "", err
+	}
+	if _, err := k8sutil.CreateOrUpdateServiceAccount(clientset, sa); err != nil {
+		return "", err
+	}
+
+	readVerbs := []string{"get", "list", "watch"}
+	role := &rbacv1.ClusterRole{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   getClusterScopedName(cluster),
+			Labels: k8sutil.ClusterLabels(cluster, nil),
+		},
+		Rules: []rbacv1.PolicyRule{
+			{APIGroups: []string{""}, Resources: []string{"persistentvolumes"},
+				Verbs: []string{"get", "list", "watch", "create", "update", "patch", "delete"}},
+			{APIGroups: []string{""}, Resources: []string{"persistentvolumeclaims"},
+				Verbs: []string{"get", "list", "watch", "update", "patch"}},
+			{APIGroups: []string{""}, Resources: []string{"persistentvolumeclaims/status"},
+				Verbs: []string{"update", "patch"}},
+			{APIGroups: []string{""}, Resources: []string{"events"},
+				Verbs: []string{"get", "list", "watch", "create", "update", "patch"}},
+			{APIGroups: []string{""}, Resources: []string{"nodes", "pods"}, Verbs: readVerbs},
+			{APIGroups: []string{"storage.k8s.io"}, Resources: []string{"storageclasses", "csinodes"}, Verbs: readVerbs},
+			{APIGroups: []string{"storage.k8s.io"}, Resources: []string{"volumeattachments"},
+				Verbs: []string{"get", "list", "watch", "update", "patch"}},
+			{APIGroups: []string{"storage.k8s.io"}, Resources: []string{"volumeattachments/status"},
+				Verbs: []string{"patch"}},
+			// the sidecars elect the leader by leases
+			{APIGroups: []string{"coordination.k8s.io"}, Resources: []string{"leases"},
+				Verbs: []string{"get", "list", "watch", "create", "update", "patch", "delete"}},
+		},
+	}
+	if _, err := k8sutil.CreateOrUpdateClusterRole(clientset, role); err != nil {
+		return "", err
+	}
+
+	binding := &rbacv1.ClusterRoleBinding{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   getClusterScopedName(cluster),
+			Labels: k8sutil.ClusterLabels(cluster, nil),
+		},
+		Subjects: []rbacv1.Subject{
+			{Kind: rbacv1.ServiceAccountKind, Name: sa.Name, Namespace: sa.Namespace},
+		},
+		RoleRef: rbacv1.RoleRef{APIGroup: rbacv1.GroupName, Kind: "ClusterRole", Name: role.Name},
+	}
+	if _, err := k8sutil.CreateOrUpdateClusterRoleBinding(clientset, binding); err != nil {
+		return "", err
+	}
+	return sa.Name, nil
+}
+
+// startConfig creates or updates the ConfigMap of the config of driver, it returns the hash of config.
+// It's skipped if the driver has no config.
+func startConfig(cluster clusterd.Clusterer, driver *Driver) (string, error) {
+	if len(driver.Config) == 0 {
+		return "", nil
+	}
+
+	cm := &v1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      clusterd.ResourceName(cluster, CSI_CONFIG),
+			Namespace: cluster.GetNameSpace(),
+			Labels:    k8sutil.ClusterLabels(cluster, nil),
+		},
+		Data: driver.Config,
+	}
+	if err := cluster.GetOwnerInfo().SetControllerReference(cm); err != nil {
+		return "", err
+	}
+	if _, err := k8sutil.CreateOrUpdateConfigMap(cluster.GetContext().Clientset, cm); err != nil {
+		return "", err
+	}
+
+	names := []string{}
+	for name := range driver.Config {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	contents := []string{}
+	for _, name := range names {
+		contents = append(contents, driver.Config[name])
+	}
+	return utils.Hash(strings.Join(contents, "")), nil
+}
+
+// getConfigVolumes returns the volume of the config of driver and its mount, they're empty if the
+// driver has no config
+func getConfigVolumes(cluster clusterd.Clusterer, driver *Driver) ([]v1.Volume, []v1.VolumeMount) {
+	if len(driver.Config) == 0 {
+		return nil, nil
+	}
+	vol := v1.Volume{
+		Name: CSI_CONFIG,
+		VolumeSource: v1.VolumeSource{
+			ConfigMap: &v1.ConfigMapVolumeSource{
+				LocalObjectReference: v1.LocalObjectReference{Name: clusterd.ResourceName(cluster, CSI_CONFIG)},
+			},
+		},
+	}
+	return []v1.Volume{vol}, []v1.VolumeMount{{Name: CSI_CONFIG, MountPath: driver.ConfigDir, ReadOnly: true}}
+}
+
+// makeSidecar returns the container of a Kubernetes CSI sidecar that talks to the plugin by the socket
+func makeSidecar(name, image string, args ...string) v1.Container {
+	return v1.Container{
+		Name:  name,
+		Image: image,
+		Args:  append([]string{fmt.Sprintf("--csi-address=%s", CSI_SOCKET_ADDRESS), "--v=2"}, args...),
+		VolumeMounts: []v1.VolumeMount{
+			{Name: "socket-dir", MountPath: CSI_SOCKET_DIR},
+		},
+	}
+}
+
+// makePlugin returns the container of the CSI plugin with the args of controller or node
+func makePlugin(cluster clusterd.Clusterer, args []string, mounts []v1.VolumeMount) v1.Container {
+	csi := cluster.GetCSISpec()
+	return v1.Container{
+		Name:  CSI_PLUGIN,
+		Image: csi.ContainerImage,
+		Args:  append([]string{fmt.Sprintf("--endpoint=%s", CSI_SOCKET_ADDRESS)}, args...),
+		Env: []v1.EnvVar{
+			{Name: "NODE_ID", ValueFrom: &v1.EnvVarSource{
+				FieldRef: &v1.ObjectFieldSelector{FieldPath: "spec.nodeName"},
+			}},
+		},
+		VolumeMounts:    mounts,
+		SecurityContext: k8sutil.PrivilegedContext(true),
+	}
+}
+
+// startController creates or updates the Deployment of the controller plugin with the sidecars to
+// provision, attach and resize the volumes
+func startController(cluster clusterd.Clusterer, driver *Driver, serviceAccount, configHash string) error {
+	csi := cluster.GetCSISpec()
+	configVols, configMounts := getConfigVolumes(cluster, driver)
+	containers := []v1.Container{
+		makeSidecar("csi-provisioner", csi.Sidecars.ProvisionerImage,
+			"--timeout=150s", "--retry-interval-start=500ms", "--leader-election"),
+		makeSidecar("csi-attacher", csi.Sidecars.AttacherImage, "--leader-election"),
+		makeSidecar("csi-resizer", csi.Sidecars.ResizerImage, "--leader-election"),
+		makePlugin(cluster, driver.ControllerArgs, append([]v1.VolumeMount{
+			{Name: "socket-dir", MountPath: CSI_SOCKET_DIR},
+		}, configMounts...)),
+	}
+
+	labels := k8sutil.ClusterLabels(cluster, getLabels(CSI_CONTROLLER))
+	replicas := int32(1)
+	d := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      clusterd.ResourceName(cluster, CSI_CONTROLLER),
+			Namespace: cluster.GetNameSpace(),
+			Labels:    labels,
+		},
+		Spec: appsv1.DeploymentSpec{
+			Selector: &metav1.LabelSelector{
+				MatchLabels: k8sutil.ClusterSelectorLabels(cluster, getLabels(CSI_CONTROLLER)),
+			},
+			Template: v1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels:      labels,
+					Annotations: map[string]string{CONFIG_HASH_ANNOTATION: configHash},
+				},
+				Spec: v1.PodSpec{
+					ServiceAccountName: serviceAccount,
+					Containers:         containers,
+					HostNetwork:        true,
+					DNSPolicy:          v1.DNSClusterFirstWithHostNet,
+					Volumes: append([]v1.Volume{
+						{Name: "socket-dir", VolumeSource: v1.VolumeSource{EmptyDir: &v1.EmptyDirVolumeSource{}}},
+					}, configVols...),
+				},
+			},
+			Replicas: &replicas,
+		},
+	}
+
+	if err := cluster.GetOwnerInfo().SetControllerReference(d); err != nil {
+		return err
+	}
+	newDeploy, err := k8sutil.CreateOrUpdateDeployment(cluster.GetContext().Clientset, d)
+	if err != nil {
+		return err
+	}
+	if newDeploy.Generation > newDeploy.Status.ObservedGeneration {
+		logger.Infof("Apply %s Deployment in namespace %s successed", CSI_CONTROLLER, cluster.GetNameSpace())
+		k8sutil.RecordEvent(cluster, v1.EventTypeNormal, k8sutil.ReasonCSIStarted,
+			"Deployment %q of CSI controller is started", d.GetName())
+	}
+	return nil
+}
+
+// startNode creates or updates the DaemonSet of the node plugins that are registered to kubelet and
+// mount the volumes into the pods
+func startNode(cluster clusterd.Clusterer, driver *Driver, serviceAccount, configHash string) error {
+	csi := cluster.GetCSISpec()
+	socketDir := path.Join(csi.KubeletDir, "plugins", csi.DriverName)
+	socketVol, _ := NewHostPathVolume("socket-dir", socketDir, v1.HostPathDirectoryOrCreate)
+	registrationVol, registrationMount := NewHostPathVolume("registration-dir",
+		path.Join(csi.KubeletDir, "plugins_registry"), v1.HostPathDirectoryOrCreate)
+	registrationMount.MountPath = "/registration"
+	// the volumes that are mounted into the pods must be propagated to kubelet
+	propagation := v1.MountPropagationBidirectional
+	kubeletVol, kubeletMount := NewHostPathVolume("kubelet-dir", csi.KubeletDir, v1.HostPathDirectory)
+	kubeletMount.MountPropagation = &propagation
+	configVols, configMounts := getConfigVolumes(cluster, driver)
+
+	registrar := makeSidecar("csi-node-driver-registrar", csi.Sidecars.RegistrarImage,
+		fmt.Sprintf("--kubelet-registration-path=%s", path.Join(socketDir, "csi.sock")))
+	registrar.VolumeMounts = append(registrar.VolumeMounts, registrationMount)
+	mounts := append([]v1.VolumeMount{
+		{Name: "socket-dir", MountPath: CSI_SOCKET_DIR},
+		kubeletMount,
+	}, configMounts...)
+	plugin := makePlugin(cluster, driver.NodeArgs, append(mounts, driver.NodeVolumeMounts...))
+
+	labels := k8sutil.ClusterLabels(cluster, getLabels(CSI_NODE))
+	ds := &appsv1.DaemonSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      clusterd.ResourceName(cluster, CSI_NODE),
+			Namespace: cluster.GetNameSpace(),
+			Labels:    labels,
+		},
+		Spec: appsv1.DaemonSetSpec{
+			Selector: &metav1.LabelSelector{
+				MatchLabels: k8sutil.ClusterSelectorLabels(cluster, getLabels(CSI_NODE)),
+			},
+			Template: v1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels:      labels,
+					Annotations: map[string]string{CONFIG_HASH_ANNOTATION: configHash},
+				},
+				Spec: v1.PodSpec{
+					ServiceAccountName: serviceAccount,
+					Containers:         []v1.Container{registrar, plugin},
+					NodeSelector:       csi.NodeSelector,
+					HostNetwork:        true,
+					HostPID:            true,
+					DNSPolicy:          v1.DNSClusterFirstWithHostNet,
+					Volumes: append(append([]v1.Volume{
+						socketVol,
+						registrationVol,
+						kubeletVol,
+					}, configVols...), driver.NodeVolumes...),
+				},
+			},
+		},
+	}
+
+	if err := cluster.GetOwnerInfo().SetControllerReference(ds); err != nil {
+		return err
+	}
+	newDs, err := k8sutil.CreateOrUpdateDaemonSet(cluster.GetContext().Clientset, ds)
+	if err != nil {
+		return err
+	}
+	if newDs.Generation > newDs.Status.ObservedGeneration {
+		logger.Infof("Apply %s DaemonSet in namespace %s successed", CSI_NODE, cluster.GetNameSpace())
+		k8sutil.RecordEvent(cluster, v1.EventTypeNormal, k8sutil.ReasonCSIStarted,
+			"DaemonSet %q of CSI node plugins is started", ds.GetName())
+	}
+	return nil
+}
+
+// startStorageClass creates or updates the StorageClass that provisions the PVCs by the driver, the
+// parameters in spec take precedence over the generated ones.
+func startStorageClass(cluster clusterd.Clusterer, driver *Driver) error {
+	csi := cluster.GetCSISpec()
+	parameters := map[string]string{}
+	for key, value := range driver.Parameters {
+		parameters[key] = value
+	}
+	for key, value := range csi.StorageClass.Parameters {
+		parameters[key] = value
+	}
+
+	reclaimPolicy := csi.StorageClass.ReclaimPolicy
+	allowVolumeExpansion := driver.AllowVolumeExpansion
+	sc := &storagev1.StorageClass{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   csi.StorageClass.Name,
+			Labels: k8sutil.ClusterLabels(cluster, nil),
+		},
+		Provisioner:          csi.DriverName,
+		Parameters:           parameters,
+		ReclaimPolicy:        &reclaimPolicy,
+		AllowVolumeExpansion: &allowVolumeExpansion,
+	}
+	clientset := cluster.GetContext().Clientset
+	existing, err := clientset.StorageV1().StorageClasses().Get(sc.Name, metav1.GetOptions{})
+	if err != nil && !apierrors.IsNotFound(err) {
+		return errors.Wrapf(err, "failed to get StorageClass %s", sc.Name)
+	}
+	if err == nil && existing.Labels[k8sutil.LabelClusterUID] != string(cluster.GetOwnerInfo().GetUID()) {
+		return errors.Errorf("StorageClass %q already exists and is not managed by the cluster", sc.Name)
+	}
+	if _, err := k8sutil.CreateOrUpdateStorageClass(clientset, sc); err != nil {
+		return err
+	}
+	// the StorageClass of the old name is removed once it's renamed
+	return deleteStaleStorageClasses(cluster, sc.Name)
+}
+
+// deleteStaleStorageClasses deletes the StorageClasses that are created for the cluster except keep
+func deleteStaleStorageClasses(cluster clusterd.Clusterer, keep string) error {
+	clientset := cluster.GetContext().Clientset
+	selector := k8sutil.GetLabelSelector(k8sutil.ClusterLabels(cluster, nil))
+	scs, err := clientset.StorageV1().StorageClasses().List(metav1.ListOptions{LabelSelector: selector})
+	if err != nil {
+		return errors.Wrapf(err, "failed to list StorageClasses by label %q", selector)
+	}
+	for _, sc := range scs.Items {
+		if sc.Name == keep {
+			continue
+		}
+		if err := k8sutil.DeleteStorageClassIfExists(clientset, sc.Name); err != nil {
+			return err
+		}
+		logger.Infof("StorageClass %q of cluster %q is deleted", sc.Name, cluster.GetName())
+	}
+	return nil
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"time"
 
+	"github.com/pkg/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
 	"sigs.k8s.io/controller-runtime/pkg/client"
@@ -12,6 +13,15 @@ import (
 	"github.com/opencurve/curve-operator/pkg/clusterd"
 )
 
+// clusterConditionTypes are the conditions that summarize the state of the whole cluster,
+// only one of them is true at any time.
+var clusterConditionTypes = []curvev1.ConditionType{
+	curvev1.ConditionProgressing,
+	curvev1.ConditionClusterReady,
+	curvev1.ConditionDeleting,
+	curvev1.ConditionFailure,
+}
+
 // UpdateCondition function will export each condition into the cluster custom resource
 func UpdateCondition(ctx context.Context, client client.Client, kind string, namespacedName types.NamespacedName, phase curvev1.ClusterPhase, condition curvev1.ClusterCondition) {
 	// use client.Client unit test this more easily with updating statuses which must use the client
@@ -22,121 +32,132 @@ func UpdateCondition(ctx context.Context, client client.Client, kind string, nam
 			logger.Errorf("failed to get cluster %v to update the conditions. %v", namespacedName, err)
 			return
 		}
-		UpdateBsClusterCondition(client, cluster, phase, condition)
+		if err := UpdateBsClusterCondition(client, cluster, phase, condition); err != nil {
+			logger.Errorf("failed to update cluster condition to %+v. %v", condition, err)
+		}
 	case clusterd.KIND_CURVEFS:
 		cluster := &curvev1.Curvefs{}
 		if err := client.Get(ctx, namespacedName, cluster); err != nil {
 			logger.Errorf("failed to get cluster %v to update the conditions. %v", namespacedName, err)
 			return
 		}
-		UpdateFsClusterCondition(client, cluster, phase, condition)
+		if err := UpdateFsClusterCondition(client, cluster, phase, condition); err != nil {
+			logger.Errorf("failed to update cluster condition to %+v. %v", condition, err)
+		}
 	default:
 		logger.Errorf("Unknown cluster kind %q", kind)
 	}
 }
 
-// UpdateFsClusterCondition function will export each condition into the cluster custom resource
-func UpdateFsClusterCondition(client client.Client, cluster *curvev1.Curvefs, phase curvev1.ClusterPhase, newCondition curvev1.ClusterCondition) {
-	// Keep the conditions that already existed if they are in the list of long-term conditions,
-	// otherwise discard the temporary conditions
-	var currentCondition *curvev1.ClusterCondition
-	var conditions []curvev1.ClusterCondition
-	for _, condition := range cluster.Status.Conditions {
-		// Only keep conditions in the list if it's a persisted condition such as the cluster creation being completed.
-		// The transient conditions are not persisted. However, if the currently requested condition is not expected to
-		// reset the transient conditions, they are retained. For example, if the operator is checking for ceph health
-		// in the middle of the reconcile, the progress condition should not be reset by the status check update.
-		if condition.Type == curvev1.ConditionClusterReady {
-			if newCondition.Type != condition.Type {
-				conditions = append(conditions, newCondition)
-				continue
-			}
-			// Update the existing condition with the new status
-			currentCondition = condition.DeepCopy()
-			if currentCondition.Status != newCondition.Status || currentCondition.Message != newCondition.Message {
-				// Update the last transition time since the status changed
-				currentCondition.LastTransitionTime = metav1.NewTime(time.Now())
-			}
-			currentCondition.Status = newCondition.Status
-			currentCondition.Reason = newCondition.Reason
-			currentCondition.Message = newCondition.Message
-		}
+// UpdateClusterCondition exports the condition into the cluster custom resource and moves the cluster to phase
+func UpdateClusterCondition(cluster clusterd.Clusterer, phase curvev1.ClusterPhase, condition curvev1.ClusterCondition) error {
+	client := cluster.GetContext().Client
+	switch c := cluster.(type) {
+	case *clusterd.BsClusterManager:
+		return UpdateBsClusterCondition(client, c.Cluster, phase, condition)
+	case *clusterd.FsClusterManager:
+		return UpdateFsClusterCondition(client, c.Cluster, phase, condition)
 	}
-	if currentCondition == nil {
-		// Create a new condition since not found in the existing conditions
-		currentCondition = &curvev1.ClusterCondition{
-			Type:               newCondition.Type,
-			Status:             newCondition.Status,
-			Reason:             newCondition.Reason,
-			Message:            newCondition.Message,
-			LastTransitionTime: metav1.NewTime(time.Now()),
-		}
+	return errors.Errorf("unknown cluster kind %q", cluster.GetKind())
+}
+
+// UpdateClusterSubCondition exports a per-role condition such as 'EtcdReady' into the cluster
+// custom resource, the phase and the message of the cluster are kept.
+func UpdateClusterSubCondition(cluster clusterd.Clusterer, condition curvev1.ClusterCondition) error {
+	client := cluster.GetContext().Client
+	switch c := cluster.(type) {
+	case *clusterd.BsClusterManager:
+		return UpdateBsClusterCondition(client, c.Cluster, c.Cluster.Status.Phase, condition)
+	case *clusterd.FsClusterManager:
+		return UpdateFsClusterCondition(client, c.Cluster, c.Cluster.Status.Phase, condition)
 	}
-	conditions = append(conditions, *currentCondition)
-	cluster.Status.Conditions = conditions
+	return errors.Errorf("unknown cluster kind %q", cluster.GetKind())
+}
+
+// UpdateFsClusterCondition function will export each condition into the cluster custom resource
+func UpdateFsClusterCondition(client client.Client, cluster *curvev1.Curvefs, phase curvev1.ClusterPhase, newCondition curvev1.ClusterCondition) error {
+	cluster.Status.Conditions = setCondition(cluster.Status.Conditions, newCondition)
 
 	// Once the cluster begins deleting, the phase should not revert back to any other phase
-	if string(cluster.Status.Phase) != string(curvev1.ConditionDeleting) {
+	if cluster.Status.Phase != curvev1.ClusterDeleting {
 		cluster.Status.Phase = phase
-		cluster.Status.Message = currentCondition.Message
-		logger.Debugf("CurveFsCluster %q status: %q. %q", cluster.GetName(), cluster.Status.Phase, cluster.Status.Message)
 	}
+	if isClusterConditionType(newCondition.Type) {
+		cluster.Status.Message = newCondition.Message
+	}
+	logger.Debugf("CurveFsCluster %q status: %q. %q", cluster.GetName(), cluster.Status.Phase, cluster.Status.Message)
 
-	if err := client.Status().Update(context.TODO(), cluster); err != nil {
-		logger.Errorf("failed to update cluster condition to %+v. %v", *currentCondition, err)
+	return client.Status().Update(context.TODO(), cluster)
+}
 
+// UpdateBsClusterCondition function will export each condition into the cluster custom resource
+func UpdateBsClusterCondition(client client.Client, cluster *curvev1.CurveCluster, phase curvev1.ClusterPhase, newCondition curvev1.ClusterCondition) error {
+	cluster.Status.Conditions = setCondition(cluster.Status.Conditions, newCondition)
+
+	// Once the cluster begins deleting, the phase should not revert back to any other phase
+	if cluster.Status.Phase != curvev1.ClusterDeleting {
+		cluster.Status.Phase = phase
 	}
+	if isClusterConditionType(newCondition.Type) {
+		cluster.Status.Message = newCondition.Message
+	}
+	logger.Debugf("CurveBsCluster %q status: %q. %q", cluster.GetName(), cluster.Status.Phase, cluster.Status.Message)
+
+	return client.Status().Update(context.TODO(), cluster)
 }
 
-// UpdateBsClusterCondition function will export each condition into the cluster custom resource
-func UpdateBsClusterCondition(client client.Client, cluster *curvev1.CurveCluster, phase curvev1.ClusterPhase, newCondition curvev1.ClusterCondition) {
-	// Keep the conditions that already existed if they are in the list of long-term conditions,
-	// otherwise discard the temporary conditions
-	var currentCondition *curvev1.ClusterCondition
-	var conditions []curvev1.ClusterCondition
-	for _, condition := range cluster.Status.Conditions {
-		// Only keep conditions in the list if it's a persisted condition such as the cluster creation being completed.
-		// The transient conditions are not persisted. However, if the currently requested condition is not expected to
-		// reset the transient conditions, they are retained. For example, if the operator is checking for ceph health
-		// in the middle of the reconcile, the progress condition should not be reset by the status check update.
-		if condition.Type == curvev1.ConditionClusterReady {
-			if newCondition.Type != condition.Type {
-				conditions = append(conditions, newCondition)
-				continue
-			}
-			// Update the existing condition with the new status
-			currentCondition = condition.DeepCopy()
-			if currentCondition.Status != newCondition.Status || currentCondition.Message != newCondition.Message {
+// setCondition updates the condition of the same type or appends it if not found. When one of
+// the cluster conditions becomes true, the other cluster conditions are set to false.
+func setCondition(conditions []curvev1.ClusterCondition, newCondition curvev1.ClusterCondition) []curvev1.ClusterCondition {
+	now := metav1.NewTime(time.Now())
+	found := false
+	for i := range conditions {
+		condition := &conditions[i]
+		if condition.Type == newCondition.Type {
+			found = true
+			if condition.Status != newCondition.Status {
 				// Update the last transition time since the status changed
-				currentCondition.LastTransitionTime = metav1.NewTime(time.Now())
+				condition.LastTransitionTime = now
 			}
-			currentCondition.Status = newCondition.Status
-			currentCondition.Reason = newCondition.Reason
-			currentCondition.Message = newCondition.Message
+			condition.Status = newCondition.Status
+			condition.Reason = newCondition.Reason
+			condition.Message = newCondition.Message
+			continue
 		}
-	}
-	if currentCondition == nil {
-		// Create a new condition since not found in the existing conditions
-		currentCondition = &curvev1.ClusterCondition{
-			Type:               newCondition.Type,
-			Status:             newCondition.Status,
-			Reason:             newCondition.Reason,
-			Message:            newCondition.Message,
-			LastTransitionTime: metav1.NewTime(time.Now()),
+
+		if newCondition.Status == curvev1.ConditionStatusTrue &&
+			isClusterConditionType(newCondition.Type) &&
+			isClusterConditionType(condition.Type) &&
+			condition.Status == curvev1.ConditionStatusTrue {
+			condition.Status = curvev1.ConditionStatusFalse
+			condition.LastTransitionTime = now
 		}
 	}
-	conditions = append(conditions, *currentCondition)
-	cluster.Status.Conditions = conditions
 
-	// Once the cluster begins deleting, the phase should not revert back to any other phase
-	if string(cluster.Status.Phase) != string(curvev1.ConditionDeleting) {
-		cluster.Status.Phase = phase
-		cluster.Status.Message = currentCondition.Message
-		logger.Debugf("CurveBsCluster %q status: %q. %q", cluster.GetName(), cluster.Status.Phase, cluster.Status.Message)
+	if !found {
+		// Create a new condition since not found in the existing conditions
+		newCondition.LastTransitionTime = now
+		conditions = append(conditions, newCondition)
 	}
+	return conditions
+}
 
-	if err := client.Status().Update(context.TODO(), cluster); err != nil {
-		logger.Errorf("failed to update cluster condition to %+v. %v", *currentCondition, err)
+// isClusterConditionType returns true if the condition summarizes the state of the whole cluster
+func isClusterConditionType(conditionType curvev1.ConditionType) bool {
+	for _, t := range clusterConditionTypes {
+		if t == conditionType {
+			return true
+		}
+	}
+	return false
+}
 
+// IsConditionTrue returns true if the condition of conditionType is found and true
+func IsConditionTrue(conditions []curvev1.ClusterCondition, conditionType curvev1.ConditionType) bool {
+	for _, condition := range conditions {
+		if condition.Type == conditionType {
+			return condition.Status == curvev1.ConditionStatusTrue
+		}
 	}
+	return false
 }
@@ -0,0 +1,49 @@
+package k8sutil
+
+import (
+	"github.com/pkg/errors"
+	appsv1 "k8s.io/api/apps/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// CreateOrUpdateDaemonSet create DaemonSet if not exist or update it, the caller should check
+// the readiness of the returned DaemonSet by IsDaemonSetReady.
+func CreateOrUpdateDaemonSet(clientset kubernetes.Interface, ds *appsv1.DaemonSet) (*appsv1.DaemonSet, error) {
+	existing, err := clientset.AppsV1().DaemonSets(ds.Namespace).Get(ds.Name, metav1.GetOptions{})
+	if err != nil {
+		if !apierrors.IsNotFound(err) {
+			return nil, errors.Wrapf(err, "failed to get DaemonSet %s in namespace %s", ds.Name, ds.Namespace)
+		}
+		newDs, err := clientset.AppsV1().DaemonSets(ds.Namespace).Create(ds)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to create DaemonSet %s in namespace %s", ds.Name, ds.Namespace)
+		}
+		return newDs, nil
+	}
+
+	ds.ResourceVersion = existing.ResourceVersion
+	updatedDs, err := clientset.AppsV1().DaemonSets(ds.Namespace).Update(ds)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to update DaemonSet %s in namespace %s", ds.Name, ds.Namespace)
+	}
+	return updatedDs, nil
+}
+
+// IsDaemonSetReady check whether the DaemonSet controller has observed the latest spec
+// and the pods on all the scheduled nodes are updated and available.
+func IsDaemonSetReady(ds *appsv1.DaemonSet) bool {
+	return ds.Status.ObservedGeneration >= ds.Generation &&
+		ds.Status.UpdatedNumberScheduled == ds.Status.DesiredNumberScheduled &&
+		ds.Status.NumberAvailable == ds.Status.DesiredNumberScheduled
+}
+
+// DeleteDaemonSetIfExists delete a DaemonSet in specified namespace, it's ok if it doesn't exist
+func DeleteDaemonSetIfExists(clientset kubernetes.Interface, namespace, name string) error {
+	err := clientset.AppsV1().DaemonSets(namespace).Delete(name, &metav1.DeleteOptions{})
+	if err != nil && !apierrors.IsNotFound(err) {
+		return errors.Wrapf(err, "failed to delete DaemonSet %s in namespace %s", name, namespace)
+	}
+	return nil
+}
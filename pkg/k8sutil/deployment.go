@@ -1,9 +1,6 @@
 package k8sutil
 
 import (
-	"fmt"
-	"time"
-
 	"emperror.dev/errors"
 	appsv1 "k8s.io/api/apps/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
@@ -33,82 +30,82 @@ func GetDeployment(clientset kubernetes.Interface, d *appsv1.Deployment) error {
 	return nil
 }
 
-// CreateNewDeploymentAndWaitStart create a new Deployment in specified namespace and wait it to start up
-func CreateNewDeploymentAndWaitStart(clientset kubernetes.Interface, d *appsv1.Deployment) error {
-	newDeploy, err := clientset.AppsV1().Deployments(d.Namespace).Create(d)
+// GetDeploymentsByLabelSelector list the Deployments in specified namespace by label selector
+func GetDeploymentsByLabelSelector(clientset kubernetes.Interface, namespace string, selector string) (*appsv1.DeploymentList, error) {
+	deployments, err := clientset.AppsV1().Deployments(namespace).List(metav1.ListOptions{
+		LabelSelector: selector,
+	})
 	if err != nil {
-		return err
+		return nil, errors.Wrapf(err, "failed to list Deployments by LabelSelector %s", selector)
 	}
+	return deployments, nil
+}
 
-	if err := WaitForDeploymentToStart(clientset, newDeploy); err != nil {
-		return err
+// CreateOrUpdateDeployment create Deployment if not exist or update it, it doesn't wait the Deployment
+// to start and the caller should check the readiness of the returned Deployment by IsDeploymentReady.
+func CreateOrUpdateDeployment(clientset kubernetes.Interface, d *appsv1.Deployment) (*appsv1.Deployment, error) {
+	existing, err := clientset.AppsV1().Deployments(d.Namespace).Get(d.Name, metav1.GetOptions{})
+	if err != nil {
+		if !apierrors.IsNotFound(err) {
+			return nil, errors.Wrapf(err, "failed to get Deployment %s in namespace %s", d.Name, d.Namespace)
+		}
+		newDeploy, err := clientset.AppsV1().Deployments(d.Namespace).Create(d)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to create Deployment %s in namespace %s", d.Name, d.Namespace)
+		}
+		return newDeploy, nil
 	}
 
-	return nil
-}
-
-// UpdateDeploymentAndWaitStart update a Deployment and wait it to start
-func UpdateDeploymentAndWaitStart(clientset kubernetes.Interface, d *appsv1.Deployment) (*appsv1.Deployment, error) {
+	d.ResourceVersion = existing.ResourceVersion
 	updatedDeploy, err := clientset.AppsV1().Deployments(d.Namespace).Update(d)
 	if err != nil {
 		return nil, errors.Wrapf(err, "failed to update Deployment %s in namespace %s", d.Name, d.Namespace)
 	}
-
-	if err := WaitForDeploymentToStart(clientset, updatedDeploy); err != nil {
-		return nil, err
-	}
-
 	return updatedDeploy, nil
 }
 
-// CreateOrUpdateDeploymentAndWaitStart create Deployment if not exist or update deployment and wait it to start.
-func CreateOrUpdateDeploymentAndWaitStart(clientset kubernetes.Interface, d *appsv1.Deployment) (*appsv1.Deployment, error) {
-	isExist, err := IsDeploymentExist(clientset, d)
-	if err != nil {
-		return nil, err
+// IsDeploymentReady check whether the Deployment controller has observed the latest spec
+// and all the replicas are updated and available.
+func IsDeploymentReady(d *appsv1.Deployment) bool {
+	replicas := int32(1)
+	if d.Spec.Replicas != nil {
+		replicas = *d.Spec.Replicas
 	}
+	return d.Status.ObservedGeneration >= d.Generation &&
+		d.Status.Replicas == replicas &&
+		d.Status.UpdatedReplicas == replicas &&
+		d.Status.ReadyReplicas == replicas &&
+		d.Status.AvailableReplicas == replicas
+}
 
-	if !isExist {
-		err = CreateNewDeploymentAndWaitStart(clientset, d)
-		if err != nil {
-			return nil, err
+// IsDeploymentProgressDeadlineExceeded check whether the Deployment failed to progress,
+// this can happen if the pod cannot be scheduled on a node and stays in "pending" state.
+func IsDeploymentProgressDeadlineExceeded(d *appsv1.Deployment) bool {
+	for _, condition := range d.Status.Conditions {
+		if condition.Type == appsv1.DeploymentProgressing && condition.Reason == "ProgressDeadlineExceeded" {
+			return true
 		}
-		return nil, nil
 	}
+	return false
+}
 
-	newDeploy, err := UpdateDeploymentAndWaitStart(clientset, d)
+// ScaleDeployment sets the replicas of the Deployment, the caller should check the replicas in the status
+// of the returned Deployment.
+func ScaleDeployment(clientset kubernetes.Interface, d *appsv1.Deployment, replicas int32) (*appsv1.Deployment, error) {
+	existing, err := clientset.AppsV1().Deployments(d.Namespace).Get(d.Name, metav1.GetOptions{})
 	if err != nil {
-		return nil, err
+		return nil, errors.Wrapf(err, "failed to get Deployment %s in namespace %s", d.Name, d.Namespace)
 	}
-	return newDeploy, nil
-}
-
-func WaitForDeploymentToStart(clientset kubernetes.Interface, d *appsv1.Deployment) error {
-	sleepTime := 3
-	attempts := 100
-	for i := 0; i < attempts; i++ {
-		deploy, err := clientset.AppsV1().Deployments(d.Namespace).Get(d.Name, metav1.GetOptions{})
-		if err != nil {
-			return errors.Wrapf(err, "failed to get Deployment %s in namespace %s", d.Name, d.Namespace)
-		}
-		if deploy.Status.ObservedGeneration >= d.Status.ObservedGeneration &&
-			deploy.Status.UpdatedReplicas > 0 &&
-			deploy.Status.ReadyReplicas > 0 {
-			return nil
-		}
-
-		// If ProgressDeadlineExceeded is reached let's fail earlier
-		// This can happen if one of the deployment cannot be scheduled on a node and stays in "pending" state
-		for _, condition := range d.Status.Conditions {
-			if condition.Type == appsv1.DeploymentProgressing && condition.Reason == "ProgressDeadlineExceeded" {
-				return fmt.Errorf("gave up waiting for deployment %s to update because %s", d.Name, condition.Reason)
-			}
-		}
-
-		time.Sleep(time.Duration(sleepTime) * time.Second)
+	if existing.Spec.Replicas != nil && *existing.Spec.Replicas == replicas {
+		return existing, nil
 	}
 
-	return fmt.Errorf("give up waiting for deployment %q to update", d.Name)
+	existing.Spec.Replicas = &replicas
+	updatedDeploy, err := clientset.AppsV1().Deployments(d.Namespace).Update(existing)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to scale Deployment %s in namespace %s", d.Name, d.Namespace)
+	}
+	return updatedDeploy, nil
 }
 
 // DeleteDeployment delete a Deployment in specified namespace
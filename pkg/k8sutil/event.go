@@ -0,0 +1,57 @@
+package k8sutil
+
+import (
+	"github.com/opencurve/curve-operator/pkg/clusterd"
+)
+
+// Reasons of the events emitted on the cluster custom resource
+const (
+	ReasonConfigTemplateExtracted = "ConfigTemplateExtracted"
+	ReasonServiceStarted          = "ServiceStarted"
+	ReasonPoolJobStarted          = "PoolJobStarted"
+	ReasonClusterCreated          = "ClusterCreated"
+	ReasonConfigUpdating          = "ConfigUpdating"
+	ReasonConfigUpdated           = "ConfigUpdated"
+	ReasonUpgrading               = "Upgrading"
+	ReasonUpgraded                = "Upgraded"
+	ReasonUpgradePaused           = "UpgradePaused"
+	ReasonRollingBack             = "RollingBack"
+	ReasonRolledBack              = "RolledBack"
+	ReasonScaling                 = "Scaling"
+	ReasonScaled                  = "Scaled"
+	ReasonCleanUpJobStarted       = "CleanUpJobStarted"
+	ReasonClusterDeleted          = "ClusterDeleted"
+	ReasonReconcileFailed         = "ReconcileFailed"
+	ReasonDeploymentRecreated     = "DeploymentRecreated"
+	ReasonLegacyResourceRemoved   = "LegacyResourceRemoved"
+	ReasonConfigReRendered        = "ConfigReRendered"
+	ReasonDrainJobStarted         = "DrainJobStarted"
+	ReasonRetireJobStarted        = "RetireJobStarted"
+	ReasonServiceRemoved          = "ServiceRemoved"
+	ReasonFormatJobStarted        = "FormatJobStarted"
+	ReasonMonitorStarted          = "MonitorStarted"
+	ReasonMonitorStopped          = "MonitorStopped"
+	ReasonSecretChanged           = "SecretChanged"
+	ReasonResourcesChanged        = "ResourcesChanged"
+	ReasonPlacementChanged        = "PlacementChanged"
+	ReasonServicesRestarting      = "ServicesRestarting"
+	ReasonServicesRestarted       = "ServicesRestarted"
+	ReasonEmbeddedS3Started       = "EmbeddedS3Started"
+	ReasonEmbeddedS3Stopped       = "EmbeddedS3Stopped"
+	ReasonCreateFsJobStarted      = "CreateFsJobStarted"
+	ReasonFuseStarted             = "FuseStarted"
+	ReasonFuseStopped             = "FuseStopped"
+	ReasonCSIStarted              = "CSIStarted"
+	ReasonCSIStopped              = "CSIStopped"
+)
+
+// RecordEvent emits an event on the cluster custom resource, eventType is one of
+// v1.EventTypeNormal and v1.EventTypeWarning.
+func RecordEvent(cluster clusterd.Clusterer, eventType, reason, messageFmt string, args ...interface{}) {
+	recorder := cluster.GetContext().Recorder
+	if recorder == nil {
+		logger.Warningf("no event recorder for cluster %q, drop event %s: %s", cluster.GetName(), reason, messageFmt)
+		return
+	}
+	recorder.Eventf(cluster.GetObject(), eventType, reason, messageFmt, args...)
+}
@@ -5,40 +5,67 @@ import (
 	"time"
 
 	batch "k8s.io/api/batch/v1"
+	v1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/client-go/kubernetes"
 )
 
-// RunReplaceableJob runs a Kubernetes job with the intention that the job can be replaced by
-// another call to this function with the same job name. For example, if a storage operator is
-// restarted/updated before the job can complete, the operator's next run of the job should replace
-// the previous job if deleteIfFound is set to true.
-func RunReplaceableJob(clientset kubernetes.Interface, job *batch.Job, deleteIfFound bool) error {
-	// check if the job was already created and what its status is
+// CreateJobIfNotExist creates the job if it doesn't exist and returns the current job, it doesn't wait
+// the job to complete and the caller should check the job by IsJobCompleted and IsJobFailed.
+func CreateJobIfNotExist(clientset kubernetes.Interface, job *batch.Job) (*batch.Job, error) {
+	existingJob, err := clientset.BatchV1().Jobs(job.Namespace).Get(job.Name, metav1.GetOptions{})
+	if err == nil {
+		return existingJob, nil
+	}
+	if !errors.IsNotFound(err) {
+		return nil, fmt.Errorf("failed to get job %s. %+v", job.Name, err)
+	}
+
+	newJob, err := clientset.BatchV1().Jobs(job.Namespace).Create(job)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create job %s. %+v", job.Name, err)
+	}
+	logger.Infof("job %s is created", job.Name)
+	return newJob, nil
+}
+
+// CreateOrReplaceJob creates the job if it doesn't exist. If the existing job has a different value of
+// the annotation key, it's deleted without waiting and the job is created by a later call after the
+// deletion is finished, the returned job is the one being deleted in this case.
+func CreateOrReplaceJob(clientset kubernetes.Interface, job *batch.Job, key string) (*batch.Job, error) {
 	existingJob, err := clientset.BatchV1().Jobs(job.Namespace).Get(job.Name, metav1.GetOptions{})
 	if err != nil && !errors.IsNotFound(err) {
-		// do nothing
-	} else if err == nil {
-		// if the job is still running, and the caller has not asked for deletion,
-		// allow it to continue to completion
-		if existingJob.Status.Active > 0 && !deleteIfFound {
-			logger.Infof("Found previous job %s. Status=%+v", job.Name, existingJob.Status)
-			return nil
+		return nil, fmt.Errorf("failed to get job %s. %+v", job.Name, err)
+	}
+	if err == nil && existingJob.DeletionTimestamp == nil &&
+		existingJob.Annotations[key] != job.Annotations[key] {
+		logger.Infof("Removing outdated job %s to start a new one", job.Name)
+		if err := DeleteBatchJob(clientset, job.Namespace, job.Name, false); err != nil {
+			return nil, fmt.Errorf("failed to remove job %s. %+v", job.Name, err)
 		}
+	}
+
+	return CreateJobIfNotExist(clientset, job)
+}
 
-		// delete the job that already exists from a previous run
-		logger.Infof("Removing previous job %s to start a new one", job.Name)
-		err := DeleteBatchJob(clientset, job.Namespace, existingJob.Name, true)
-		if err != nil {
-			return fmt.Errorf("failed to remove job %s. %+v", job.Name, err)
+// IsJobCompleted check whether the job has completed successfully
+func IsJobCompleted(job *batch.Job) bool {
+	return job.DeletionTimestamp == nil && hasJobCondition(job, batch.JobComplete)
+}
+
+// IsJobFailed check whether the job has failed, e.g. reached the backoff limit
+func IsJobFailed(job *batch.Job) bool {
+	return job.DeletionTimestamp == nil && hasJobCondition(job, batch.JobFailed)
+}
+
+func hasJobCondition(job *batch.Job, conditionType batch.JobConditionType) bool {
+	for _, condition := range job.Status.Conditions {
+		if condition.Type == conditionType && condition.Status == v1.ConditionTrue {
+			return true
 		}
 	}
-
-	// always create new job
-	_, err = clientset.BatchV1().Jobs(job.Namespace).Create(job)
-	return err
+	return false
 }
 
 // DeleteBatchJob deletes a Kubernetes job.
@@ -75,27 +102,3 @@ func DeleteBatchJob(clientset kubernetes.Interface, namespace, name string, wait
 	logger.Warningf("gave up waiting for batch job %s to be deleted", name)
 	return nil
 }
-
-// WaitForJobCompletion waits for a job to reach the completed state.
-// Assumes that only one pod needs to complete.
-func WaitForJobCompletion(clientset kubernetes.Interface, job *batch.Job, timeout time.Duration) error {
-	return wait.Poll(5*time.Second, timeout, func() (bool, error) {
-		job, err := clientset.BatchV1().Jobs(job.Namespace).Get(job.Name, metav1.GetOptions{})
-		if err != nil {
-			return false, fmt.Errorf("failed to detect job %s. %+v", job.Name, err)
-		}
-
-		// if the job is still running, allow it to continue to completion
-		if job.Status.Active > 0 {
-			return false, nil
-		}
-		if job.Status.Failed > 0 {
-			return false, fmt.Errorf("job %s run failed", job.Name)
-		}
-		if job.Status.Succeeded > 0 {
-			return true, nil
-		}
-		logger.Debugf("job is still initializing")
-		return false, nil
-	})
-}
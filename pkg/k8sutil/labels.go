@@ -1,6 +1,19 @@
 package k8sutil
 
-import "strings"
+import (
+	"strings"
+
+	"github.com/opencurve/curve-operator/pkg/clusterd"
+)
+
+const (
+	// LabelClusterKind is the label of the kind of cluster, curvebs or curvefs
+	LabelClusterKind = "operator.curve.io/cluster-kind"
+	// LabelClusterName is the label of the name of cluster
+	LabelClusterName = "operator.curve.io/cluster-name"
+	// LabelClusterUID is the label of the UID of cluster
+	LabelClusterUID = "operator.curve.io/cluster-uid"
+)
 
 // GetLabelSelector get labelSelector by labels of pod
 func GetLabelSelector(labels map[string]string) string {
@@ -11,3 +24,24 @@ func GetLabelSelector(labels map[string]string) string {
 	selector := strings.Join(labelSelector, ",")
 	return selector
 }
+
+// ClusterSelectorLabels returns the labels merged with the kind and the name of cluster,
+// it's used as the selector of resources that only select the resources of the cluster.
+func ClusterSelectorLabels(cluster clusterd.Clusterer, labels map[string]string) map[string]string {
+	merged := map[string]string{
+		LabelClusterKind: cluster.GetKind(),
+		LabelClusterName: cluster.GetName(),
+	}
+	for k, v := range labels {
+		merged[k] = v
+	}
+	return merged
+}
+
+// ClusterLabels returns the labels merged with the identity of cluster, includes the UID
+// of cluster to distinguish the resources of a deleted cluster with the same name.
+func ClusterLabels(cluster clusterd.Clusterer, labels map[string]string) map[string]string {
+	merged := ClusterSelectorLabels(cluster, labels)
+	merged[LabelClusterUID] = string(cluster.GetOwnerInfo().GetUID())
+	return merged
+}
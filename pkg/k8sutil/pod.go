@@ -16,3 +16,15 @@ func GetPodsByLabelSelector(clientset kubernetes.Interface, namespace string, se
 	}
 	return pods, nil
 }
+
+// GetPodLogs returns the last lines of the logs of the container in the pod
+func GetPodLogs(clientset kubernetes.Interface, namespace, name, container string, tailLines int64) (string, error) {
+	logs, err := clientset.CoreV1().Pods(namespace).GetLogs(name, &v1.PodLogOptions{
+		Container: container,
+		TailLines: &tailLines,
+	}).Do().Raw()
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to get logs of container %s in pod %s", container, name)
+	}
+	return string(logs), nil
+}
@@ -0,0 +1,102 @@
+package k8sutil
+
+import (
+	"github.com/pkg/errors"
+	v1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// CreateOrUpdateServiceAccount create ServiceAccount if not exist or update its labels
+func CreateOrUpdateServiceAccount(clientset kubernetes.Interface, sa *v1.ServiceAccount) (*v1.ServiceAccount, error) {
+	existing, err := clientset.CoreV1().ServiceAccounts(sa.Namespace).Get(sa.Name, metav1.GetOptions{})
+	if err != nil {
+		if !apierrors.IsNotFound(err) {
+			return nil, errors.Wrapf(err, "failed to get ServiceAccount %s in namespace %s", sa.Name, sa.Namespace)
+		}
+		newSa, err := clientset.CoreV1().ServiceAccounts(sa.Namespace).Create(sa)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to create ServiceAccount %s in namespace %s", sa.Name, sa.Namespace)
+		}
+		return newSa, nil
+	}
+
+	// the secrets of token are managed by Kubernetes
+	existing.Labels = sa.Labels
+	existing.OwnerReferences = sa.OwnerReferences
+	updatedSa, err := clientset.CoreV1().ServiceAccounts(sa.Namespace).Update(existing)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to update ServiceAccount %s in namespace %s", sa.Name, sa.Namespace)
+	}
+	return updatedSa, nil
+}
+
+// CreateOrUpdateClusterRole create ClusterRole if not exist or update it
+func CreateOrUpdateClusterRole(clientset kubernetes.Interface, role *rbacv1.ClusterRole) (*rbacv1.ClusterRole, error) {
+	existing, err := clientset.RbacV1().ClusterRoles().Get(role.Name, metav1.GetOptions{})
+	if err != nil {
+		if !apierrors.IsNotFound(err) {
+			return nil, errors.Wrapf(err, "failed to get ClusterRole %s", role.Name)
+		}
+		newRole, err := clientset.RbacV1().ClusterRoles().Create(role)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to create ClusterRole %s", role.Name)
+		}
+		return newRole, nil
+	}
+
+	role.ResourceVersion = existing.ResourceVersion
+	updatedRole, err := clientset.RbacV1().ClusterRoles().Update(role)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to update ClusterRole %s", role.Name)
+	}
+	return updatedRole, nil
+}
+
+// CreateOrUpdateClusterRoleBinding create ClusterRoleBinding if not exist or update it, the binding
+// is recreated if it refers to another role since the role reference is immutable.
+func CreateOrUpdateClusterRoleBinding(clientset kubernetes.Interface, binding *rbacv1.ClusterRoleBinding) (*rbacv1.ClusterRoleBinding, error) {
+	existing, err := clientset.RbacV1().ClusterRoleBindings().Get(binding.Name, metav1.GetOptions{})
+	if err != nil && !apierrors.IsNotFound(err) {
+		return nil, errors.Wrapf(err, "failed to get ClusterRoleBinding %s", binding.Name)
+	}
+	if err == nil {
+		if existing.RoleRef == binding.RoleRef {
+			binding.ResourceVersion = existing.ResourceVersion
+			updatedBinding, err := clientset.RbacV1().ClusterRoleBindings().Update(binding)
+			if err != nil {
+				return nil, errors.Wrapf(err, "failed to update ClusterRoleBinding %s", binding.Name)
+			}
+			return updatedBinding, nil
+		}
+		if err := DeleteClusterRoleBindingIfExists(clientset, binding.Name); err != nil {
+			return nil, err
+		}
+	}
+
+	newBinding, err := clientset.RbacV1().ClusterRoleBindings().Create(binding)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to create ClusterRoleBinding %s", binding.Name)
+	}
+	return newBinding, nil
+}
+
+// DeleteClusterRoleIfExists delete a ClusterRole, it's ok if it doesn't exist
+func DeleteClusterRoleIfExists(clientset kubernetes.Interface, name string) error {
+	err := clientset.RbacV1().ClusterRoles().Delete(name, &metav1.DeleteOptions{})
+	if err != nil && !apierrors.IsNotFound(err) {
+		return errors.Wrapf(err, "failed to delete ClusterRole %s", name)
+	}
+	return nil
+}
+
+// DeleteClusterRoleBindingIfExists delete a ClusterRoleBinding, it's ok if it doesn't exist
+func DeleteClusterRoleBindingIfExists(clientset kubernetes.Interface, name string) error {
+	err := clientset.RbacV1().ClusterRoleBindings().Delete(name, &metav1.DeleteOptions{})
+	if err != nil && !apierrors.IsNotFound(err) {
+		return errors.Wrapf(err, "failed to delete ClusterRoleBinding %s", name)
+	}
+	return nil
+}
@@ -0,0 +1,49 @@
+package k8sutil
+
+import (
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// GetSecretKey returns the value of the key of Secret in specified namespace that is selected by ref,
+// it returns empty if the Secret or the key doesn't exist and ref is optional.
+func GetSecretKey(clientset kubernetes.Interface, namespace string, ref *corev1.SecretKeySelector) (string, error) {
+	optional := ref.Optional != nil && *ref.Optional
+	secret, err := clientset.CoreV1().Secrets(namespace).Get(ref.Name, metav1.GetOptions{})
+	if err != nil {
+		if apierrors.IsNotFound(err) && optional {
+			return "", nil
+		}
+		return "", errors.Wrapf(err, "failed to get Secret %s in namespace %s", ref.Name, namespace)
+	}
+
+	value, ok := secret.Data[ref.Key]
+	if !ok {
+		if optional {
+			return "", nil
+		}
+		return "", errors.Errorf("key %q is not found in Secret %s in namespace %s", ref.Key, ref.Name, namespace)
+	}
+	return string(value), nil
+}
+
+// CreateSecretIfNotExist creates the Secret if it doesn't exist and returns the current Secret,
+// the data of the existing Secret is kept.
+func CreateSecretIfNotExist(clientset kubernetes.Interface, s *corev1.Secret) (*corev1.Secret, error) {
+	existing, err := clientset.CoreV1().Secrets(s.Namespace).Get(s.Name, metav1.GetOptions{})
+	if err == nil {
+		return existing, nil
+	}
+	if !apierrors.IsNotFound(err) {
+		return nil, errors.Wrapf(err, "failed to get Secret %s in namespace %s", s.Name, s.Namespace)
+	}
+
+	newSecret, err := clientset.CoreV1().Secrets(s.Namespace).Create(s)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to create Secret %s in namespace %s", s.Name, s.Namespace)
+	}
+	return newSecret, nil
+}
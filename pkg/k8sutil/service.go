@@ -0,0 +1,53 @@
+package k8sutil
+
+import (
+	"github.com/pkg/errors"
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// CreateOrUpdateService create Service if not exist or update it, the cluster ip allocated
+// to the existing Service is kept since it's immutable.
+func CreateOrUpdateService(clientset kubernetes.Interface, s *v1.Service) (*v1.Service, error) {
+	existing, err := clientset.CoreV1().Services(s.Namespace).Get(s.Name, metav1.GetOptions{})
+	if err != nil {
+		if !apierrors.IsNotFound(err) {
+			return nil, errors.Wrapf(err, "failed to get Service %s in namespace %s", s.Name, s.Namespace)
+		}
+		newService, err := clientset.CoreV1().Services(s.Namespace).Create(s)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to create Service %s in namespace %s", s.Name, s.Namespace)
+		}
+		return newService, nil
+	}
+
+	s.ResourceVersion = existing.ResourceVersion
+	s.Spec.ClusterIP = existing.Spec.ClusterIP
+	updatedService, err := clientset.CoreV1().Services(s.Namespace).Update(s)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to update Service %s in namespace %s", s.Name, s.Namespace)
+	}
+	return updatedService, nil
+}
+
+// GetServicesByLabelSelector list the Services in specified namespace by label selector
+func GetServicesByLabelSelector(clientset kubernetes.Interface, namespace string, selector string) (*v1.ServiceList, error) {
+	services, err := clientset.CoreV1().Services(namespace).List(metav1.ListOptions{
+		LabelSelector: selector,
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to list Services by LabelSelector %s", selector)
+	}
+	return services, nil
+}
+
+// DeleteService delete a Service in specified namespace, it's ok if it doesn't exist
+func DeleteService(clientset kubernetes.Interface, namespace, name string) error {
+	err := clientset.CoreV1().Services(namespace).Delete(name, &metav1.DeleteOptions{})
+	if err != nil && !apierrors.IsNotFound(err) {
+		return errors.Wrapf(err, "failed to delete Service %s in namespace %s", name, namespace)
+	}
+	return nil
+}
@@ -1,6 +1,27 @@
 package k8sutil
 
+import (
+	"context"
+
+	"github.com/pkg/errors"
+
+	"github.com/opencurve/curve-operator/pkg/clusterd"
+)
+
 // TODO:
 func retryUpdateStatus() {
 
 }
+
+// UpdateClusterStatus persists the status of the cluster custom resource as it is,
+// e.g. the progress of reconcile steps
+func UpdateClusterStatus(cluster clusterd.Clusterer) error {
+	client := cluster.GetContext().Client
+	switch c := cluster.(type) {
+	case *clusterd.BsClusterManager:
+		return client.Status().Update(context.TODO(), c.Cluster)
+	case *clusterd.FsClusterManager:
+		return client.Status().Update(context.TODO(), c.Cluster)
+	}
+	return errors.Errorf("unknown cluster kind %q", cluster.GetKind())
+}
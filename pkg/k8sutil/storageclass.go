@@ -0,0 +1,51 @@
+package k8sutil
+
+import (
+	"reflect"
+
+	"github.com/pkg/errors"
+	storagev1 "k8s.io/api/storage/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// CreateOrUpdateStorageClass create StorageClass if not exist or update it, the StorageClass is
+// recreated if its provisioner, parameters or reclaim policy is changed since they're immutable.
+// The PVs that are already provisioned are not affected.
+func CreateOrUpdateStorageClass(clientset kubernetes.Interface, sc *storagev1.StorageClass) (*storagev1.StorageClass, error) {
+	existing, err := clientset.StorageV1().StorageClasses().Get(sc.Name, metav1.GetOptions{})
+	if err != nil && !apierrors.IsNotFound(err) {
+		return nil, errors.Wrapf(err, "failed to get StorageClass %s", sc.Name)
+	}
+	if err == nil {
+		if existing.Provisioner == sc.Provisioner &&
+			reflect.DeepEqual(existing.Parameters, sc.Parameters) &&
+			reflect.DeepEqual(existing.ReclaimPolicy, sc.ReclaimPolicy) {
+			sc.ResourceVersion = existing.ResourceVersion
+			updatedSc, err := clientset.StorageV1().StorageClasses().Update(sc)
+			if err != nil {
+				return nil, errors.Wrapf(err, "failed to update StorageClass %s", sc.Name)
+			}
+			return updatedSc, nil
+		}
+		if err := DeleteStorageClassIfExists(clientset, sc.Name); err != nil {
+			return nil, err
+		}
+	}
+
+	newSc, err := clientset.StorageV1().StorageClasses().Create(sc)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to create StorageClass %s", sc.Name)
+	}
+	return newSc, nil
+}
+
+// DeleteStorageClassIfExists delete a StorageClass, it's ok if it doesn't exist
+func DeleteStorageClassIfExists(clientset kubernetes.Interface, name string) error {
+	err := clientset.StorageV1().StorageClasses().Delete(name, &metav1.DeleteOptions{})
+	if err != nil && !apierrors.IsNotFound(err) {
+		return errors.Wrapf(err, "failed to delete StorageClass %s", name)
+	}
+	return nil
+}
@@ -0,0 +1,168 @@
+package monitor
+
+import (
+	"embed"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	appsv1 "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+
+	"github.com/opencurve/curve-operator/pkg/clusterd"
+	"github.com/opencurve/curve-operator/pkg/topology"
+	"github.com/opencurve/curve-operator/pkg/utils"
+)
+
+const (
+	GRAFANA_PROVISIONING = "grafana-provisioning"
+	GRAFANA_DASHBOARDS   = "grafana-dashboards"
+
+	GRAFANA_DATA_DIR         = "/var/lib/grafana"
+	GRAFANA_PROVISIONING_DIR = "/etc/grafana/provisioning"
+	GRAFANA_DASHBOARDS_DIR   = "/etc/grafana/dashboards"
+
+	GRAFANA_DATASOURCE_NAME = "Prometheus"
+	GRAFANA_FOLDER          = "Curve"
+)
+
+//go:embed dashboards/*.json
+var dashboards embed.FS
+
+var grafana_datasources string = `
+apiVersion: 1
+datasources:
+  - name: %s
+    type: prometheus
+    access: proxy
+    url: http://127.0.0.1:%d
+    isDefault: true
+    editable: false
+`
+
+var grafana_dashboard_providers string = `
+apiVersion: 1
+providers:
+  - name: curve
+    folder: %s
+    type: file
+    disableDeletion: true
+    allowUiUpdates: false
+    updateIntervalSeconds: 30
+    options:
+      path: %s
+`
+
+// getDashboards returns the Curve dashboards to provision, the ones of the other kind of cluster are excluded
+func getDashboards(cluster clusterd.Clusterer) (map[string]string, error) {
+	excluded := topology.KIND_CURVEFS
+	if cluster.GetKind() == topology.KIND_CURVEFS {
+		excluded = topology.KIND_CURVEBS
+	}
+
+	entries, err := dashboards.ReadDir("dashboards")
+	if err != nil {
+		return nil, err
+	}
+	data := map[string]string{}
+	for _, entry := range entries {
+		if strings.HasPrefix(entry.Name(), excluded) {
+			continue
+		}
+		content, err := dashboards.ReadFile(path.Join("dashboards", entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		data[entry.Name()] = string(content)
+	}
+	return data, nil
+}
+
+// startGrafana creates or updates Grafana on the monitor host, the Prometheus data source and the
+// Curve dashboards are provisioned from the ConfigMaps.
+func startGrafana(cluster clusterd.Clusterer, _ []*topology.DeployConfig) (*appsv1.Deployment, error) {
+	monitor := cluster.GetMonitorSpec()
+	grafana := monitor.Grafana
+
+	provisioning := map[string]string{
+		"datasources.yaml": fmt.Sprintf(grafana_datasources, GRAFANA_DATASOURCE_NAME, monitor.Prometheus.ListenPort),
+		"dashboards.yaml":  fmt.Sprintf(grafana_dashboard_providers, GRAFANA_FOLDER, GRAFANA_DASHBOARDS_DIR),
+	}
+	dashboardData, err := getDashboards(cluster)
+	if err != nil {
+		return nil, err
+	}
+	provisioningName := getResourceName(cluster, GRAFANA_PROVISIONING)
+	if err := createOrUpdateConfigMap(cluster, provisioningName, provisioning); err != nil {
+		return nil, err
+	}
+	dashboardsName := getResourceName(cluster, GRAFANA_DASHBOARDS)
+	if err := createOrUpdateConfigMap(cluster, dashboardsName, dashboardData); err != nil {
+		return nil, err
+	}
+
+	// the provisioning files are loaded only at start, the dashboards are reloaded by Grafana itself
+	keys := []string{}
+	for key := range provisioning {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	configs := []string{}
+	for _, key := range keys {
+		configs = append(configs, provisioning[key])
+	}
+
+	vols := []v1.Volume{
+		newHostPathVolume("data", grafana.DataDir),
+		{
+			Name: "provisioning",
+			VolumeSource: v1.VolumeSource{
+				ConfigMap: &v1.ConfigMapVolumeSource{
+					LocalObjectReference: v1.LocalObjectReference{Name: provisioningName},
+					Items: []v1.KeyToPath{
+						{Key: "datasources.yaml", Path: "datasources/datasources.yaml"},
+						{Key: "dashboards.yaml", Path: "dashboards/dashboards.yaml"},
+					},
+				},
+			},
+		},
+		{
+			Name: "dashboards",
+			VolumeSource: v1.VolumeSource{
+				ConfigMap: &v1.ConfigMapVolumeSource{
+					LocalObjectReference: v1.LocalObjectReference{Name: dashboardsName},
+				},
+			},
+		},
+	}
+	container := v1.Container{
+		Name:  GRAFANA,
+		Image: grafana.ContainerImage,
+		Env: []v1.EnvVar{
+			{Name: "GF_SERVER_HTTP_PORT", Value: fmt.Sprint(grafana.ListenPort)},
+			{Name: "GF_SECURITY_ADMIN_USER", Value: grafana.UserName},
+			{Name: "GF_PATHS_DATA", Value: GRAFANA_DATA_DIR},
+			{Name: "GF_PATHS_PROVISIONING", Value: GRAFANA_PROVISIONING_DIR},
+		},
+		VolumeMounts: []v1.VolumeMount{
+			{Name: "data", MountPath: GRAFANA_DATA_DIR},
+			{Name: "provisioning", MountPath: GRAFANA_PROVISIONING_DIR, ReadOnly: true},
+			{Name: "dashboards", MountPath: GRAFANA_DASHBOARDS_DIR, ReadOnly: true},
+		},
+		Ports: []v1.ContainerPort{
+			{Name: "http", ContainerPort: int32(grafana.ListenPort), HostPort: int32(grafana.ListenPort)},
+		},
+		ReadinessProbe: newHTTPReadinessProbe("/api/health", grafana.ListenPort),
+	}
+	// the password is read from the Secret by kubelet, Grafana only sets it for the admin user
+	// on the first start so that it's not necessary to restart Grafana once it's changed
+	if grafana.PasswordSecretRef != nil {
+		container.Env = append(container.Env, v1.EnvVar{
+			Name:      "GF_SECURITY_ADMIN_PASSWORD",
+			ValueFrom: &v1.EnvVarSource{SecretKeyRef: grafana.PasswordSecretRef},
+		})
+	}
+
+	return makeMonitorDeployment(cluster, GRAFANA, container, vols, utils.Hash(strings.Join(configs, "")))
+}
@@ -0,0 +1,235 @@
+package monitor
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/coreos/pkg/capnslog"
+	"github.com/pkg/errors"
+	appsv1 "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+
+	"github.com/opencurve/curve-operator/pkg/clusterd"
+	"github.com/opencurve/curve-operator/pkg/k8sutil"
+	"github.com/opencurve/curve-operator/pkg/service"
+	"github.com/opencurve/curve-operator/pkg/topology"
+)
+
+var logger = capnslog.NewPackageLogger("github.com/opencurve/curve-operator", "monitor")
+
+const (
+	PROMETHEUS    = "prometheus"
+	GRAFANA       = "grafana"
+	NODE_EXPORTER = "node-exporter"
+
+	// CONFIG_HASH_ANNOTATION is the annotation of the pod template that records the hash of its config,
+	// the pods are restarted once the config is changed.
+	CONFIG_HASH_ANNOTATION = "operator.curve.io/config-hash"
+)
+
+// Start creates or updates Prometheus and Grafana on the monitor host and node-exporter on
+// every node of the cluster, it returns false and records the ones that are not ready in the
+// progress if any.
+func Start(cluster clusterd.Clusterer, dcs []*topology.DeployConfig) (bool, error) {
+	notReady := []string{}
+
+	ds, err := startNodeExporter(cluster, dcs)
+	if err != nil {
+		return false, err
+	}
+	if !k8sutil.IsDaemonSetReady(ds) {
+		notReady = append(notReady, ds.Name)
+	}
+
+	for _, start := range []func(clusterd.Clusterer, []*topology.DeployConfig) (*appsv1.Deployment, error){
+		startPrometheus,
+		startGrafana,
+	} {
+		d, err := start(cluster, dcs)
+		if err != nil {
+			return false, err
+		}
+		if k8sutil.IsDeploymentProgressDeadlineExceeded(d) {
+			return false, errors.Errorf("Deployment %q of monitor failed to progress", d.Name)
+		}
+		if !k8sutil.IsDeploymentReady(d) {
+			notReady = append(notReady, d.Name)
+		}
+	}
+
+	if len(notReady) > 0 {
+		cluster.GetProgress().Message = fmt.Sprintf("waiting for monitor to be ready: %s", strings.Join(notReady, ", "))
+		return false, nil
+	}
+	return true, nil
+}
+
+// Stop deletes the monitor of the cluster, the data of Prometheus and Grafana is kept on the
+// monitor host so that it's not lost if the monitor is enabled again.
+func Stop(cluster clusterd.Clusterer) error {
+	clientset := cluster.GetContext().Clientset
+	namespace := cluster.GetNameSpace()
+
+	deleted := false
+	for _, app := range []string{PROMETHEUS, GRAFANA} {
+		d := &appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: getResourceName(cluster, app), Namespace: namespace}}
+		exist, err := k8sutil.IsDeploymentExist(clientset, d)
+		if err != nil {
+			return err
+		}
+		if !exist {
+			continue
+		}
+		if err := k8sutil.DeleteDeployment(clientset, d); err != nil {
+			return err
+		}
+		deleted = true
+	}
+	if err := k8sutil.DeleteDaemonSetIfExists(clientset, namespace, getResourceName(cluster, NODE_EXPORTER)); err != nil {
+		return err
+	}
+
+	// the ConfigMaps are deleted along with the Deployments, they're owned by the cluster and
+	// removed with it anyway, so a failure to delete them is only logged
+	if deleted {
+		for _, name := range []string{
+			getResourceName(cluster, PROMETHEUS),
+			getResourceName(cluster, GRAFANA_PROVISIONING),
+			getResourceName(cluster, GRAFANA_DASHBOARDS),
+		} {
+			cm := &v1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace}}
+			if err := k8sutil.DeleteConfigMap(clientset, cm); err != nil {
+				logger.Warningf("failed to delete ConfigMap %q of monitor. %v", name, err)
+			}
+		}
+		k8sutil.RecordEvent(cluster, v1.EventTypeNormal, k8sutil.ReasonMonitorStopped, "monitor is stopped")
+	}
+	return nil
+}
+
+// getResourceName returns the name of the resource of the monitor app
+func getResourceName(cluster clusterd.Clusterer, app string) string {
+	return clusterd.ResourceName(cluster, app)
+}
+
+// getClusterRoles returns the roles of the services of cluster
+func getClusterRoles(cluster clusterd.Clusterer) []string {
+	if cluster.GetKind() == topology.KIND_CURVEFS {
+		return topology.CURVEFS_ROLES
+	}
+	return topology.CURVEBS_ROLES
+}
+
+// getMonitorLabels returns the labels of the monitor app
+func getMonitorLabels(app string) map[string]string {
+	return map[string]string{"app": app}
+}
+
+// newHostPathVolume returns the volume of the directory on host
+func newHostPathVolume(name, path string) v1.Volume {
+	hostPathType := v1.HostPathDirectoryOrCreate
+	return v1.Volume{
+		Name: name,
+		VolumeSource: v1.VolumeSource{
+			HostPath: &v1.HostPathVolumeSource{Path: path, Type: &hostPathType},
+		},
+	}
+}
+
+// newHTTPReadinessProbe returns the probe that checks the readiness by the http path on host network
+func newHTTPReadinessProbe(path string, port int) *v1.Probe {
+	return &v1.Probe{
+		Handler: v1.Handler{
+			HTTPGet: &v1.HTTPGetAction{Path: path, Port: intstr.FromInt(port)},
+		},
+		InitialDelaySeconds: 5,
+		PeriodSeconds:       10,
+	}
+}
+
+// makeMonitorDeployment creates or updates the Deployment of Prometheus or Grafana on the monitor host,
+// the pod is restarted if the hash of its config is changed.
+func makeMonitorDeployment(cluster clusterd.Clusterer, app string, container v1.Container,
+	vols []v1.Volume, configHash string) (*appsv1.Deployment, error) {
+	monitor := cluster.GetMonitorSpec()
+	labels := k8sutil.ClusterLabels(cluster, getMonitorLabels(app))
+	// the data directory on host is owned by root
+	rootUser := int64(0)
+	replicas := int32(1)
+
+	d := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      getResourceName(cluster, app),
+			Namespace: cluster.GetNameSpace(),
+			Labels:    labels,
+		},
+		Spec: appsv1.DeploymentSpec{
+			Selector: &metav1.LabelSelector{
+				MatchLabels: k8sutil.ClusterSelectorLabels(cluster, getMonitorLabels(app)),
+			},
+			Template: v1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels:      labels,
+					Annotations: map[string]string{CONFIG_HASH_ANNOTATION: configHash},
+				},
+				Spec: v1.PodSpec{
+					Containers:      []v1.Container{container},
+					RestartPolicy:   v1.RestartPolicyAlways,
+					HostNetwork:     true,
+					DNSPolicy:       v1.DNSClusterFirstWithHostNet,
+					SecurityContext: &v1.PodSecurityContext{RunAsUser: &rootUser},
+					Volumes:         vols,
+				},
+			},
+			Replicas: &replicas,
+			// the data directory can't be shared by two pods
+			Strategy: appsv1.DeploymentStrategy{
+				Type: appsv1.RecreateDeploymentStrategyType,
+			},
+		},
+	}
+
+	err := service.ApplyPlacement(cluster, &d.Spec.Template.Spec, monitor.MonitorHost, monitor.Placement)
+	if err != nil {
+		return nil, err
+	}
+
+	err = cluster.GetOwnerInfo().SetControllerReference(d)
+	if err != nil {
+		return nil, err
+	}
+
+	newDeploy, err := k8sutil.CreateOrUpdateDeployment(cluster.GetContext().Clientset, d)
+	if err != nil {
+		return nil, err
+	}
+
+	if newDeploy.Generation > newDeploy.Status.ObservedGeneration {
+		logger.Infof("Apply %s Deployment in namespace %s successed", app, cluster.GetNameSpace())
+		k8sutil.RecordEvent(cluster, v1.EventTypeNormal, k8sutil.ReasonMonitorStarted,
+			"Deployment %q of %s is started on node %q", d.GetName(), app, monitor.MonitorHost)
+	}
+	return newDeploy, nil
+}
+
+// createOrUpdateConfigMap creates or updates the ConfigMap of the monitor app owned by the cluster
+func createOrUpdateConfigMap(cluster clusterd.Clusterer, name string, data map[string]string) error {
+	cm := &v1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: cluster.GetNameSpace(),
+			Labels:    k8sutil.ClusterLabels(cluster, nil),
+		},
+		Data: data,
+	}
+
+	err := cluster.GetOwnerInfo().SetControllerReference(cm)
+	if err != nil {
+		return err
+	}
+
+	_, err = k8sutil.CreateOrUpdateConfigMap(cluster.GetContext().Clientset, cm)
+	return err
+}
@@ -0,0 +1,116 @@
+package monitor
+
+import (
+	"fmt"
+
+	appsv1 "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/opencurve/curve-operator/pkg/clusterd"
+	"github.com/opencurve/curve-operator/pkg/k8sutil"
+	"github.com/opencurve/curve-operator/pkg/topology"
+)
+
+const (
+	NODE_EXPORTER_ROOTFS = "/host"
+)
+
+// getClusterNodes returns the nodes that the services of cluster are deployed on
+func getClusterNodes(dcs []*topology.DeployConfig) []string {
+	nodes := []string{}
+	seen := map[string]bool{}
+	for _, dc := range dcs {
+		if !seen[dc.GetHost()] {
+			nodes = append(nodes, dc.GetHost())
+			seen[dc.GetHost()] = true
+		}
+	}
+	return nodes
+}
+
+// startNodeExporter creates or updates the DaemonSet of node-exporter that runs on the nodes of
+// the services, the root filesystem of node is mounted to report the usage of its disks.
+func startNodeExporter(cluster clusterd.Clusterer, dcs []*topology.DeployConfig) (*appsv1.DaemonSet, error) {
+	nodeExporter := cluster.GetMonitorSpec().NodeExporter
+	labels := k8sutil.ClusterLabels(cluster, getMonitorLabels(NODE_EXPORTER))
+	propagation := v1.MountPropagationHostToContainer
+
+	container := v1.Container{
+		Name:  NODE_EXPORTER,
+		Image: nodeExporter.ContainerImage,
+		Args: []string{
+			fmt.Sprintf("--web.listen-address=:%d", nodeExporter.ListenPort),
+			fmt.Sprintf("--path.rootfs=%s", NODE_EXPORTER_ROOTFS),
+		},
+		VolumeMounts: []v1.VolumeMount{
+			{Name: "rootfs", MountPath: NODE_EXPORTER_ROOTFS, ReadOnly: true, MountPropagation: &propagation},
+		},
+		Ports: []v1.ContainerPort{
+			{Name: "metrics", ContainerPort: int32(nodeExporter.ListenPort), HostPort: int32(nodeExporter.ListenPort)},
+		},
+		ReadinessProbe: newHTTPReadinessProbe("/metrics", nodeExporter.ListenPort),
+	}
+
+	ds := &appsv1.DaemonSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      getResourceName(cluster, NODE_EXPORTER),
+			Namespace: cluster.GetNameSpace(),
+			Labels:    labels,
+		},
+		Spec: appsv1.DaemonSetSpec{
+			Selector: &metav1.LabelSelector{
+				MatchLabels: k8sutil.ClusterSelectorLabels(cluster, getMonitorLabels(NODE_EXPORTER)),
+			},
+			Template: v1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: labels,
+				},
+				Spec: v1.PodSpec{
+					Containers: []v1.Container{container},
+					Affinity: &v1.Affinity{
+						NodeAffinity: &v1.NodeAffinity{
+							RequiredDuringSchedulingIgnoredDuringExecution: &v1.NodeSelector{
+								NodeSelectorTerms: []v1.NodeSelectorTerm{{
+									MatchFields: []v1.NodeSelectorRequirement{{
+										Key:      "metadata.name",
+										Operator: v1.NodeSelectorOpIn,
+										Values:   getClusterNodes(dcs),
+									}},
+								}},
+							},
+						},
+					},
+					HostNetwork: true,
+					HostPID:     true,
+					DNSPolicy:   v1.DNSClusterFirstWithHostNet,
+					Volumes: []v1.Volume{
+						{
+							Name: "rootfs",
+							VolumeSource: v1.VolumeSource{
+								HostPath: &v1.HostPathVolumeSource{Path: "/"},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	err := cluster.GetOwnerInfo().SetControllerReference(ds)
+	if err != nil {
+		return nil, err
+	}
+
+	newDs, err := k8sutil.CreateOrUpdateDaemonSet(cluster.GetContext().Clientset, ds)
+	if err != nil {
+		return nil, err
+	}
+
+	if newDs.Generation > newDs.Status.ObservedGeneration {
+		logger.Infof("Apply %s DaemonSet in namespace %s successed", NODE_EXPORTER, cluster.GetNameSpace())
+		k8sutil.RecordEvent(cluster, v1.EventTypeNormal, k8sutil.ReasonMonitorStarted,
+			"DaemonSet %q of %s is started on %d nodes", ds.GetName(), NODE_EXPORTER, len(getClusterNodes(dcs)))
+	}
+	return newDs, nil
+}
@@ -0,0 +1,156 @@
+package monitor
+
+import (
+	"fmt"
+	"sort"
+
+	appsv1 "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/yaml"
+
+	"github.com/opencurve/curve-operator/pkg/clusterd"
+	"github.com/opencurve/curve-operator/pkg/service"
+	"github.com/opencurve/curve-operator/pkg/topology"
+	"github.com/opencurve/curve-operator/pkg/utils"
+)
+
+const (
+	PROMETHEUS_CONFIG_NAME = "prometheus.yml"
+	PROMETHEUS_CONFIG_DIR  = "/etc/prometheus"
+	PROMETHEUS_DATA_DIR    = "/prometheus"
+
+	PROMETHEUS_SCRAPE_INTERVAL = "15s"
+)
+
+type prometheusConfig struct {
+	Global        globalConfig   `json:"global"`
+	ScrapeConfigs []scrapeConfig `json:"scrape_configs"`
+}
+
+type globalConfig struct {
+	ScrapeInterval     string `json:"scrape_interval"`
+	EvaluationInterval string `json:"evaluation_interval"`
+}
+
+type scrapeConfig struct {
+	JobName       string         `json:"job_name"`
+	MetricsPath   string         `json:"metrics_path,omitempty"`
+	StaticConfigs []staticConfig `json:"static_configs"`
+}
+
+type staticConfig struct {
+	Targets []string          `json:"targets"`
+	Labels  map[string]string `json:"labels,omitempty"`
+}
+
+// getNodeIps returns the ips of the nodes that the services are deployed on
+func getNodeIps(dcs []*topology.DeployConfig) map[string]string {
+	ips := map[string]string{}
+	for _, dc := range dcs {
+		ips[dc.GetHost()] = dc.GetHostIp()
+	}
+	return ips
+}
+
+// genPrometheusConfig generates the config of Prometheus that scrapes every service of the cluster
+// by one job for each role, and node-exporter on every node.
+func genPrometheusConfig(cluster clusterd.Clusterer, dcs []*topology.DeployConfig) (string, error) {
+	config := prometheusConfig{
+		Global: globalConfig{
+			ScrapeInterval:     PROMETHEUS_SCRAPE_INTERVAL,
+			EvaluationInterval: PROMETHEUS_SCRAPE_INTERVAL,
+		},
+	}
+
+	for _, role := range getClusterRoles(cluster) {
+		roleDcs := topology.FilterDeployConfigByRole(dcs, role)
+		if len(roleDcs) == 0 {
+			continue
+		}
+
+		job := scrapeConfig{JobName: role}
+		for _, dc := range roleDcs {
+			port, path := service.GetMetricsEndpoint(dc)
+			job.MetricsPath = path
+			job.StaticConfigs = append(job.StaticConfigs, staticConfig{
+				Targets: []string{fmt.Sprintf("%s:%d", dc.GetHostIp(), port)},
+				Labels:  map[string]string{"service": dc.GetName(), "host": dc.GetHost()},
+			})
+		}
+		config.ScrapeConfigs = append(config.ScrapeConfigs, job)
+	}
+
+	nodeIps := getNodeIps(dcs)
+	hosts := []string{}
+	for host := range nodeIps {
+		hosts = append(hosts, host)
+	}
+	sort.Strings(hosts)
+	nodeJob := scrapeConfig{JobName: NODE_EXPORTER}
+	for _, host := range hosts {
+		nodeJob.StaticConfigs = append(nodeJob.StaticConfigs, staticConfig{
+			Targets: []string{fmt.Sprintf("%s:%d", nodeIps[host], cluster.GetMonitorSpec().NodeExporter.ListenPort)},
+			Labels:  map[string]string{"host": host},
+		})
+	}
+	config.ScrapeConfigs = append(config.ScrapeConfigs, nodeJob)
+
+	data, err := yaml.Marshal(config)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// startPrometheus creates or updates Prometheus on the monitor host with the scrape config generated
+// from the services, Prometheus is restarted to load the config once it's changed.
+func startPrometheus(cluster clusterd.Clusterer, dcs []*topology.DeployConfig) (*appsv1.Deployment, error) {
+	prometheus := cluster.GetMonitorSpec().Prometheus
+	config, err := genPrometheusConfig(cluster, dcs)
+	if err != nil {
+		return nil, err
+	}
+	configMapName := getResourceName(cluster, PROMETHEUS)
+	if err := createOrUpdateConfigMap(cluster, configMapName, map[string]string{PROMETHEUS_CONFIG_NAME: config}); err != nil {
+		return nil, err
+	}
+
+	args := []string{
+		fmt.Sprintf("--config.file=%s/%s", PROMETHEUS_CONFIG_DIR, PROMETHEUS_CONFIG_NAME),
+		fmt.Sprintf("--storage.tsdb.path=%s", PROMETHEUS_DATA_DIR),
+		fmt.Sprintf("--web.listen-address=:%d", prometheus.ListenPort),
+	}
+	if len(prometheus.RetentionTime) > 0 {
+		args = append(args, fmt.Sprintf("--storage.tsdb.retention.time=%s", prometheus.RetentionTime))
+	}
+	if len(prometheus.RetentionSize) > 0 {
+		args = append(args, fmt.Sprintf("--storage.tsdb.retention.size=%s", prometheus.RetentionSize))
+	}
+
+	vols := []v1.Volume{
+		newHostPathVolume("data", prometheus.DataDir),
+		{
+			Name: "config",
+			VolumeSource: v1.VolumeSource{
+				ConfigMap: &v1.ConfigMapVolumeSource{
+					LocalObjectReference: v1.LocalObjectReference{Name: configMapName},
+				},
+			},
+		},
+	}
+	container := v1.Container{
+		Name:  PROMETHEUS,
+		Image: prometheus.ContainerImage,
+		Args:  args,
+		VolumeMounts: []v1.VolumeMount{
+			{Name: "data", MountPath: PROMETHEUS_DATA_DIR},
+			{Name: "config", MountPath: PROMETHEUS_CONFIG_DIR, ReadOnly: true},
+		},
+		Ports: []v1.ContainerPort{
+			{Name: "http", ContainerPort: int32(prometheus.ListenPort), HostPort: int32(prometheus.ListenPort)},
+		},
+		ReadinessProbe: newHTTPReadinessProbe("/-/ready", prometheus.ListenPort),
+	}
+
+	return makeMonitorDeployment(cluster, PROMETHEUS, container, vols, utils.Hash(config))
+}
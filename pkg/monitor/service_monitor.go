@@ -0,0 +1,169 @@
+package monitor
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/opencurve/curve-operator/pkg/clusterd"
+	"github.com/opencurve/curve-operator/pkg/k8sutil"
+	"github.com/opencurve/curve-operator/pkg/service"
+	"github.com/opencurve/curve-operator/pkg/topology"
+)
+
+// serviceMonitorGVK is the kind of ServiceMonitor of Prometheus Operator, it's handled as unstructured
+// object so that the operator doesn't depend on the types of Prometheus Operator.
+var serviceMonitorGVK = schema.GroupVersionKind{Group: "monitoring.coreos.com", Version: "v1", Kind: "ServiceMonitor"}
+
+// StartServiceMonitors creates or updates the headless Service of every service and one ServiceMonitor
+// for each role, so that an existing Prometheus Operator scrapes the metrics of the cluster. The
+// Services of the removed services are deleted.
+func StartServiceMonitors(cluster clusterd.Clusterer, dcs []*topology.DeployConfig) error {
+	for _, dc := range dcs {
+		if _, err := service.StartMetricsService(cluster, dc); err != nil {
+			return err
+		}
+	}
+	if err := service.DeleteMetricsServices(cluster, dcs); err != nil {
+		return err
+	}
+
+	for _, role := range getClusterRoles(cluster) {
+		roleDcs := topology.FilterDeployConfigByRole(dcs, role)
+		if len(roleDcs) == 0 {
+			continue
+		}
+		_, path := service.GetMetricsEndpoint(roleDcs[0])
+		if err := createOrUpdateServiceMonitor(cluster, role, path); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// StopServiceMonitors deletes the ServiceMonitors and the headless Services of the cluster
+func StopServiceMonitors(cluster clusterd.Clusterer) error {
+	if err := service.DeleteMetricsServices(cluster, nil); err != nil {
+		return err
+	}
+
+	c := cluster.GetContext().Client
+	list := &unstructured.UnstructuredList{}
+	list.SetGroupVersionKind(serviceMonitorGVK.GroupVersion().WithKind(serviceMonitorGVK.Kind + "List"))
+	err := c.List(context.TODO(), list, client.InNamespace(cluster.GetNameSpace()),
+		client.MatchingLabels(k8sutil.ClusterSelectorLabels(cluster, getServiceMonitorLabels(""))))
+	if err != nil {
+		// nothing to delete if Prometheus Operator is not installed
+		if meta.IsNoMatchError(err) {
+			return nil
+		}
+		return errors.Wrap(err, "failed to list ServiceMonitors")
+	}
+
+	for i := range list.Items {
+		if err := c.Delete(context.TODO(), &list.Items[i]); err != nil && !apierrors.IsNotFound(err) {
+			return errors.Wrapf(err, "failed to delete ServiceMonitor %s", list.Items[i].GetName())
+		}
+		logger.Infof("ServiceMonitor %s of cluster %q is deleted", list.Items[i].GetName(), cluster.GetName())
+	}
+	return nil
+}
+
+// getServiceMonitorLabels returns the labels of the ServiceMonitor and the headless Services of role,
+// it selects the ones of all roles if role is empty.
+func getServiceMonitorLabels(role string) map[string]string {
+	labels := map[string]string{"app": service.METRICS_SERVICE_APP}
+	if len(role) > 0 {
+		labels["role"] = role
+	}
+	return labels
+}
+
+// toInterfaceMap converts the labels to the type of the content of unstructured object
+func toInterfaceMap(labels map[string]string) map[string]interface{} {
+	m := map[string]interface{}{}
+	for k, v := range labels {
+		m[k] = v
+	}
+	return m
+}
+
+// createOrUpdateServiceMonitor creates or updates the ServiceMonitor that scrapes the headless Services
+// of role, the job of metrics is named by role and the service and the host are added as labels to
+// match the dashboards of the bundled Grafana.
+func createOrUpdateServiceMonitor(cluster clusterd.Clusterer, role, path string) error {
+	spec := cluster.GetMonitorSpec().ServiceMonitor
+	labels := map[string]string{}
+	for k, v := range spec.Labels {
+		labels[k] = v
+	}
+	for k, v := range k8sutil.ClusterLabels(cluster, getServiceMonitorLabels(role)) {
+		labels[k] = v
+	}
+
+	endpoint := map[string]interface{}{
+		"port": service.METRICS_PORT_NAME,
+		"path": path,
+		"relabelings": []interface{}{
+			map[string]interface{}{
+				"sourceLabels": []interface{}{"__meta_kubernetes_service_label_name"},
+				"targetLabel":  "service",
+			},
+			map[string]interface{}{
+				"sourceLabels": []interface{}{"__meta_kubernetes_pod_node_name"},
+				"targetLabel":  "host",
+			},
+		},
+	}
+	if len(spec.Interval) > 0 {
+		endpoint["interval"] = spec.Interval
+	}
+
+	sm := &unstructured.Unstructured{}
+	sm.SetGroupVersionKind(serviceMonitorGVK)
+	sm.SetName(getResourceName(cluster, role+"-metrics"))
+	sm.SetNamespace(cluster.GetNameSpace())
+	sm.SetLabels(labels)
+	sm.Object["spec"] = map[string]interface{}{
+		"jobLabel": "role",
+		"selector": map[string]interface{}{
+			"matchLabels": toInterfaceMap(k8sutil.ClusterSelectorLabels(cluster, getServiceMonitorLabels(role))),
+		},
+		"namespaceSelector": map[string]interface{}{
+			"matchNames": []interface{}{cluster.GetNameSpace()},
+		},
+		"endpoints": []interface{}{endpoint},
+	}
+	if err := cluster.GetOwnerInfo().SetControllerReference(sm); err != nil {
+		return err
+	}
+
+	c := cluster.GetContext().Client
+	existing := &unstructured.Unstructured{}
+	existing.SetGroupVersionKind(serviceMonitorGVK)
+	err := c.Get(context.TODO(), types.NamespacedName{Namespace: sm.GetNamespace(), Name: sm.GetName()}, existing)
+	switch {
+	case meta.IsNoMatchError(err):
+		return errors.Wrap(err, "ServiceMonitor is not supported, please install Prometheus Operator first")
+	case apierrors.IsNotFound(err):
+		if err := c.Create(context.TODO(), sm); err != nil {
+			return errors.Wrapf(err, "failed to create ServiceMonitor %s", sm.GetName())
+		}
+		logger.Infof("ServiceMonitor %s of cluster %q is created", sm.GetName(), cluster.GetName())
+		return nil
+	case err != nil:
+		return errors.Wrapf(err, "failed to get ServiceMonitor %s", sm.GetName())
+	}
+
+	sm.SetResourceVersion(existing.GetResourceVersion())
+	if err := c.Update(context.TODO(), sm); err != nil {
+		return errors.Wrapf(err, "failed to update ServiceMonitor %s", sm.GetName())
+	}
+	return nil
+}
@@ -1,6 +1,7 @@
 package service
 
 import (
+	"fmt"
 	"strings"
 	"time"
 
@@ -20,23 +21,24 @@ const (
 )
 
 var (
-	CURVE_CLEAN_UP_APP_NAME = "curve-cleanup-%s"
+	CURVE_CLEAN_UP_APP_NAME = "cleanup-%s"
 	CURVE_CLEAN_UP_POD_NAME = "curve-cleanup"
 )
 
 func StartClusterCleanUpJob(cluster clusterd.Clusterer, dcs []*topology.DeployConfig) error {
-	labels := map[string]string{"app": CURVE_CLEAN_UP_APP_NAME}
+	labels := k8sutil.ClusterLabels(cluster, map[string]string{"app": CURVE_CLEAN_UP_POD_NAME})
 	securityContext := k8sutil.PrivilegedContext(true)
 
 	commandLine := `rm -rf ${CURVE_DATA_DIR_HOST_PATH} && rm -rf ${CURVE_LOG_DIR_HOST_PATH} `
 
 	for _, dc := range dcs {
 		vols, volMounts := getServiceHostPathVolumeAndMount(dc)
-		jobName := k8sutil.TruncateNodeNameForJob(CURVE_CLEAN_UP_APP_NAME, dc.GetHost())
+		// one job for each service because every service has its own data and log directories
+		jobName := clusterd.ResourceName(cluster, fmt.Sprintf(CURVE_CLEAN_UP_APP_NAME, dc.GetName()))
 		container := v1.Container{
 			Name:            CURVE_CLEAN_UP_POD_NAME,
 			Image:           cluster.GetContainerImage(),
-			ImagePullPolicy: v1.PullIfNotPresent,
+			ImagePullPolicy: cluster.GetImagePullPolicy(),
 			Command: []string{
 				"/bin/bash",
 				"-c",
@@ -62,29 +64,32 @@ func StartClusterCleanUpJob(cluster clusterd.Clusterer, dcs []*topology.DeployCo
 				},
 				Volumes:       vols,
 				RestartPolicy: v1.RestartPolicyOnFailure,
-				NodeName:      dc.GetHost(),
 			},
 		}
 
 		ttlTimeout := int32(0)
 		job := &batchv1.Job{
 			ObjectMeta: metav1.ObjectMeta{
-				Name:      CURVE_CLEAN_UP_POD_NAME,
+				Name:      jobName,
 				Namespace: cluster.GetNameSpace(),
 				Labels:    labels,
 			},
 			Spec: batchv1.JobSpec{
-				Selector: &metav1.LabelSelector{
-					MatchLabels: labels,
-				},
 				Template:                podTempalteSpec,
 				TTLSecondsAfterFinished: &ttlTimeout, // delete itself immediately after finished.
 			},
 		}
-		err := k8sutil.RunReplaceableJob(cluster.GetContext().Clientset, job, true)
+		applyJobResources(cluster, &job.Spec.Template.Spec)
+		err := ApplyPlacement(cluster, &job.Spec.Template.Spec, dc.GetHost(), GetServicePlacement(cluster, dc))
+		if err != nil {
+			return err
+		}
+		_, err = k8sutil.CreateJobIfNotExist(cluster.GetContext().Clientset, job)
 		if err != nil {
 			return err
 		}
+		k8sutil.RecordEvent(cluster, v1.EventTypeNormal, k8sutil.ReasonCleanUpJobStarted,
+			"Job %q to clean up data and log directories is started on node %q", job.GetName(), dc.GetHost())
 	}
 
 	return nil
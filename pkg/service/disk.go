@@ -0,0 +1,183 @@
+package service
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	batchv1 "k8s.io/api/batch/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/opencurve/curve-operator/pkg/clusterd"
+	"github.com/opencurve/curve-operator/pkg/k8sutil"
+	"github.com/opencurve/curve-operator/pkg/topology"
+	"github.com/opencurve/curve-operator/pkg/utils"
+)
+
+const (
+	PREPARE_DISK_CONTAINER = "prepare-disk"
+	FORMAT_CONTAINER       = "format-chunkfile-pool"
+	FORMAT_JOB_NAME        = "format-%s"
+	CHUNKFILE_VOLUME       = "chunkfile-pool"
+
+	// DEVICE_HASH_ANNOTATION is the annotation of the format job that records the device it formats
+	DEVICE_HASH_ANNOTATION = "operator.curve.io/device-hash"
+
+	// DEFAULT_CHUNKFILE_SIZE is the size of every chunk file in chunkfilepool, 16MB
+	DEFAULT_CHUNKFILE_SIZE = 16 * 1024 * 1024
+
+	// FORMAT_PROGRESS_INTERVAL is the interval in seconds that the format job reports its progress
+	FORMAT_PROGRESS_INTERVAL = 10
+	// FORMAT_PROGRESS_PREFIX is the prefix of the log lines that the format job reports its progress
+	FORMAT_PROGRESS_PREFIX = "FORMAT_PROGRESS="
+)
+
+// StartJobFormatDevice create job to format and mount the device of the chunkserver, then the
+// chunkfilepool is preallocated by curve_format which reports the percent complete in its logs.
+// The job is replaced if the device of the chunkserver is changed.
+func StartJobFormatDevice(cluster clusterd.Clusterer, dc *topology.DeployConfig) (*batchv1.Job, error) {
+	device := dc.GetDevice()
+	if device == nil {
+		return nil, errors.Errorf("no device is specified for %s", dc.GetName())
+	}
+	layout := dc.GetProjectLayout()
+	hostPathType := v1.HostPathDirectoryOrCreate
+	propagation := v1.MountPropagationHostToContainer
+	deviceHash := utils.Hash(fmt.Sprintf("%s:%s:%d", device.Name, device.MountPath, device.Percentage))
+
+	prepareContainer := v1.Container{
+		Name: PREPARE_DISK_CONTAINER,
+		Command: []string{
+			"bash",
+			"-c",
+			prepare_disk,
+		},
+		Image:           cluster.GetContainerImage(),
+		ImagePullPolicy: cluster.GetImagePullPolicy(),
+		SecurityContext: k8sutil.PrivilegedContext(true),
+		Env: []v1.EnvVar{
+			{Name: "DEVICES", Value: fmt.Sprintf("%s:%s", device.Name, device.MountPath)},
+		},
+	}
+
+	// the chunkfilepool is preallocated in the device mounted by the init container
+	formatContainer := v1.Container{
+		Name: FORMAT_CONTAINER,
+		Command: []string{
+			"bash",
+			"-c",
+			format_chunkfile_pool,
+		},
+		Image:           cluster.GetContainerImage(),
+		ImagePullPolicy: cluster.GetImagePullPolicy(),
+		VolumeMounts: []v1.VolumeMount{
+			{Name: CHUNKFILE_VOLUME, MountPath: layout.ChunkfilePoolRootDir, MountPropagation: &propagation},
+		},
+		Env: []v1.EnvVar{
+			{Name: "FORMAT_BINARY_PATH", Value: layout.FormatBinaryPath},
+			{Name: "ALLOCATE_PERCENT", Value: fmt.Sprint(device.Percentage)},
+			{Name: "CHUNKFILE_SIZE", Value: fmt.Sprint(DEFAULT_CHUNKFILE_SIZE)},
+			{Name: "CHUNKFILE_POOL_DIR", Value: layout.ChunkfilePoolDir},
+			{Name: "CHUNKFILE_POOL_META_PATH", Value: layout.ChunkfilePoolMetaPath},
+			{Name: "PROGRESS_INTERVAL", Value: fmt.Sprint(FORMAT_PROGRESS_INTERVAL)},
+		},
+	}
+
+	labels := k8sutil.ClusterLabels(cluster, map[string]string{
+		"app":         PREPARE_DISK_CONTAINER,
+		"node":        dc.GetHost(),
+		"chunkserver": dc.GetName(),
+	})
+	job := &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        GetFormatJobName(cluster, dc),
+			Namespace:   cluster.GetNameSpace(),
+			Labels:      labels,
+			Annotations: map[string]string{DEVICE_HASH_ANNOTATION: deviceHash},
+		},
+		Spec: batchv1.JobSpec{
+			Template: v1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: labels,
+				},
+				Spec: v1.PodSpec{
+					InitContainers: []v1.Container{
+						prepareContainer,
+					},
+					Containers: []v1.Container{
+						formatContainer,
+					},
+					RestartPolicy: v1.RestartPolicyNever,
+					HostPID:       true,
+					Volumes: []v1.Volume{
+						{
+							Name: CHUNKFILE_VOLUME,
+							VolumeSource: v1.VolumeSource{
+								HostPath: &v1.HostPathVolumeSource{Path: device.MountPath, Type: &hostPathType},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+	applyJobResources(cluster, &job.Spec.Template.Spec)
+	err := ApplyPlacement(cluster, &job.Spec.Template.Spec, dc.GetHost(), GetServicePlacement(cluster, dc))
+	if err != nil {
+		return nil, err
+	}
+
+	err = cluster.GetOwnerInfo().SetControllerReference(job)
+	if err != nil {
+		return nil, err
+	}
+
+	currentJob, err := k8sutil.CreateOrReplaceJob(cluster.GetContext().Clientset, job, DEVICE_HASH_ANNOTATION)
+	if err != nil {
+		return nil, err
+	}
+
+	if currentJob.Status.StartTime == nil && currentJob.Status.Active == 0 {
+		k8sutil.RecordEvent(cluster, v1.EventTypeNormal, k8sutil.ReasonFormatJobStarted,
+			"Job %q to format device %s on node %q is started", job.GetName(), device.Name, dc.GetHost())
+	}
+	return currentJob, nil
+}
+
+// GetFormatJobName returns the name of the job to format the device of the chunkserver
+func GetFormatJobName(cluster clusterd.Clusterer, dc *topology.DeployConfig) string {
+	return clusterd.ResourceName(cluster, fmt.Sprintf(FORMAT_JOB_NAME, dc.GetName()))
+}
+
+// GetFormatProgress returns the percent complete that the format job reports in its latest logs,
+// it returns 0 if the job hasn't reported any progress yet.
+func GetFormatProgress(cluster clusterd.Clusterer, job *batchv1.Job) (int, error) {
+	clientset := cluster.GetContext().Clientset
+	pods, err := k8sutil.GetPodsByLabelSelector(clientset, job.Namespace, fmt.Sprintf("job-name=%s", job.Name))
+	if err != nil {
+		return 0, err
+	}
+
+	progress := 0
+	for _, pod := range pods.Items {
+		if pod.Status.Phase != v1.PodRunning && pod.Status.Phase != v1.PodSucceeded {
+			continue
+		}
+		logs, err := k8sutil.GetPodLogs(clientset, pod.Namespace, pod.Name, FORMAT_CONTAINER, 10)
+		if err != nil {
+			return 0, err
+		}
+		for _, line := range strings.Split(logs, "\n") {
+			if !strings.HasPrefix(line, FORMAT_PROGRESS_PREFIX) {
+				continue
+			}
+			// the later lines report the newer progress
+			if n, err := strconv.Atoi(strings.TrimPrefix(line, FORMAT_PROGRESS_PREFIX)); err == nil {
+				progress = n
+			}
+		}
+	}
+	return progress, nil
+}
@@ -0,0 +1,332 @@
+package service
+
+import (
+	"fmt"
+	"path"
+
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	curvev1 "github.com/opencurve/curve-operator/api/v1"
+	"github.com/opencurve/curve-operator/pkg/clusterd"
+	"github.com/opencurve/curve-operator/pkg/k8sutil"
+	"github.com/opencurve/curve-operator/pkg/topology"
+	"github.com/opencurve/curve-operator/pkg/utils"
+)
+
+const (
+	FUSE           = "fuse"
+	FUSE_CONF      = "fuse-conf"
+	CREATE_FS      = "create-fs"
+	CREATE_FS_JOB  = "create-fs-%s"
+	FUSE_FS_TYPE   = "s3"
+	FUSE_MOUNT_VOL = "mount"
+
+	// FUSE_CONFIG_HASH_ANNOTATION is the annotation of the pod template that records the hash of
+	// client.conf, the file systems are mounted again once it's changed.
+	FUSE_CONFIG_HASH_ANNOTATION = "operator.curve.io/config-hash"
+)
+
+var mount_fs string = `
+mkdir -p ${MOUNT_POINT}
+# the mount left by the last container is stale
+umount -l ${MOUNT_POINT} 2>/dev/null || true
+exec ${FUSE_BINARY_PATH} -f \
+    -o default_permissions \
+    -o allow_other \
+    -o fsname=${FS_NAME} \
+    -o fstype=${FS_TYPE} \
+    -o user=curvefs \
+    -o conf=${CLIENT_CONF_PATH} \
+    ${MOUNT_POINT}
+`
+
+// getClientConfPath returns the path of client.conf in the container of FUSE client
+func getClientConfPath() string {
+	return path.Join(topology.LAYOUT_CURVEFS_CLIENT_DIR, topology.LAYOUT_SERVICE_CONF_DIR, topology.LAYOUT_CLIENT_NAME)
+}
+
+// getMountPoint returns the mount point of the file system in the container
+func getMountPoint(name string) string {
+	return path.Join(topology.LAYOUT_CURVEFS_CLIENT_DIR, topology.LAYOUT_CLIENT_MOUNT_DIR, name)
+}
+
+// StartJobCreateFs creates the job to create the file system by curvefs_tool if it doesn't exist, the
+// S3 service to store its data is read from tools.conf. The caller should check whether the returned
+// job is completed.
+func StartJobCreateFs(cluster clusterd.Clusterer, dc *topology.DeployConfig, name string) (*batchv1.Job, error) {
+	vols, volMounts := getToolsAndTopoVolumeAndMount(cluster, dc)
+	container := v1.Container{
+		Name: CREATE_FS,
+		Command: []string{
+			dc.GetProjectLayout().ToolsBinaryPath,
+			"create-fs",
+			fmt.Sprintf("-fsName=%s", name),
+			fmt.Sprintf("-fsType=%s", FUSE_FS_TYPE),
+		},
+		Image:           cluster.GetContainerImage(),
+		ImagePullPolicy: cluster.GetImagePullPolicy(),
+		VolumeMounts:    volMounts,
+	}
+
+	labels := k8sutil.ClusterLabels(cluster, map[string]string{"app": CREATE_FS, "fs": name})
+	job := &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      clusterd.ResourceName(cluster, fmt.Sprintf(CREATE_FS_JOB, name)),
+			Namespace: cluster.GetNameSpace(),
+			Labels:    labels,
+		},
+		Spec: batchv1.JobSpec{
+			Template: v1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: labels,
+				},
+				Spec: v1.PodSpec{
+					Containers: []v1.Container{
+						container,
+					},
+					RestartPolicy: v1.RestartPolicyOnFailure,
+					HostNetwork:   true,
+					DNSPolicy:     v1.DNSClusterFirstWithHostNet,
+					Volumes:       vols,
+				},
+			},
+		},
+	}
+	applyJobResources(cluster, &job.Spec.Template.Spec)
+
+	err := cluster.GetOwnerInfo().SetControllerReference(job)
+	if err != nil {
+		return nil, err
+	}
+
+	currentJob, err := k8sutil.CreateJobIfNotExist(cluster.GetContext().Clientset, job)
+	if err != nil {
+		return nil, err
+	}
+
+	if currentJob.Status.StartTime == nil && currentJob.Status.Active == 0 {
+		k8sutil.RecordEvent(cluster, v1.EventTypeNormal, k8sutil.ReasonCreateFsJobStarted,
+			"Job %q to create file system %q is started", job.GetName(), name)
+	}
+	return currentJob, nil
+}
+
+// makeFuseContainer returns the container that mounts the file system in the foreground, the mount
+// is propagated to the mount directory on host.
+func makeFuseContainer(cluster clusterd.Clusterer, name string) v1.Container {
+	mountPoint := getMountPoint(name)
+	propagation := v1.MountPropagationBidirectional
+
+	return v1.Container{
+		Name:            name,
+		Command:         []string{"bash", "-c", mount_fs},
+		Image:           cluster.GetContainerImage(),
+		ImagePullPolicy: cluster.GetImagePullPolicy(),
+		Env: []v1.EnvVar{
+			{Name: "FS_NAME", Value: name},
+			{Name: "FS_TYPE", Value: FUSE_FS_TYPE},
+			{Name: "MOUNT_POINT", Value: mountPoint},
+			{Name: "CLIENT_CONF_PATH", Value: getClientConfPath()},
+			{Name: "FUSE_BINARY_PATH", Value: path.Join(topology.LAYOUT_CURVEFS_CLIENT_DIR,
+				topology.LAYOUT_SERVICE_BIN_DIR, topology.BINARY_CURVEFS_FUSE)},
+		},
+		VolumeMounts: []v1.VolumeMount{
+			{Name: FUSE_CONF, MountPath: getClientConfPath(), SubPath: topology.LAYOUT_CLIENT_NAME, ReadOnly: true},
+			{Name: FUSE_MOUNT_VOL, MountPath: path.Dir(mountPoint), MountPropagation: &propagation},
+			{Name: "dev-fuse", MountPath: "/dev/fuse"},
+		},
+		// the mount is ready once it's listed in the mount table of the container
+		ReadinessProbe: &v1.Probe{
+			Handler: v1.Handler{
+				Exec: &v1.ExecAction{
+					Command: []string{"sh", "-c", fmt.Sprintf("grep -qs ' %s ' /proc/mounts", mountPoint)},
+				},
+			},
+			InitialDelaySeconds: 5,
+			PeriodSeconds:       10,
+		},
+		SecurityContext: k8sutil.PrivilegedContext(true),
+	}
+}
+
+// StartFuse creates or updates the DaemonSet that mounts the file systems on the nodes of FUSE clients,
+// one container for one file system. The pods are restarted once the rendered client.conf is changed.
+func StartFuse(cluster clusterd.Clusterer, fuse *curvev1.FuseSpec, clientConf string) (*appsv1.DaemonSet, error) {
+	cm := &v1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      clusterd.ResourceName(cluster, FUSE_CONF),
+			Namespace: cluster.GetNameSpace(),
+			Labels:    k8sutil.ClusterLabels(cluster, nil),
+		},
+		Data: map[string]string{topology.LAYOUT_CLIENT_NAME: clientConf},
+	}
+	err := cluster.GetOwnerInfo().SetControllerReference(cm)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := k8sutil.CreateOrUpdateConfigMap(cluster.GetContext().Clientset, cm); err != nil {
+		return nil, err
+	}
+
+	containers := []v1.Container{}
+	for _, name := range fuse.FileSystems {
+		containers = append(containers, makeFuseContainer(cluster, name))
+	}
+
+	hostPathType := v1.HostPathDirectoryOrCreate
+	charDevice := v1.HostPathCharDev
+	labels := k8sutil.ClusterLabels(cluster, map[string]string{"app": FUSE})
+	ds := &appsv1.DaemonSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      clusterd.ResourceName(cluster, FUSE),
+			Namespace: cluster.GetNameSpace(),
+			Labels:    labels,
+		},
+		Spec: appsv1.DaemonSetSpec{
+			Selector: &metav1.LabelSelector{
+				MatchLabels: k8sutil.ClusterSelectorLabels(cluster, map[string]string{"app": FUSE}),
+			},
+			Template: v1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels:      labels,
+					Annotations: map[string]string{FUSE_CONFIG_HASH_ANNOTATION: utils.Hash(clientConf)},
+				},
+				Spec: v1.PodSpec{
+					Containers: containers,
+					Affinity: &v1.Affinity{
+						NodeAffinity: &v1.NodeAffinity{
+							RequiredDuringSchedulingIgnoredDuringExecution: &v1.NodeSelector{
+								NodeSelectorTerms: []v1.NodeSelectorTerm{{
+									MatchFields: []v1.NodeSelectorRequirement{{
+										Key:      "metadata.name",
+										Operator: v1.NodeSelectorOpIn,
+										Values:   fuse.Nodes,
+									}},
+								}},
+							},
+						},
+					},
+					HostNetwork: true,
+					DNSPolicy:   v1.DNSClusterFirstWithHostNet,
+					Volumes: []v1.Volume{
+						{
+							Name: FUSE_CONF,
+							VolumeSource: v1.VolumeSource{
+								ConfigMap: &v1.ConfigMapVolumeSource{
+									LocalObjectReference: v1.LocalObjectReference{Name: cm.Name},
+								},
+							},
+						},
+						{
+							Name: FUSE_MOUNT_VOL,
+							VolumeSource: v1.VolumeSource{
+								HostPath: &v1.HostPathVolumeSource{Path: fuse.MountDir, Type: &hostPathType},
+							},
+						},
+						{
+							Name: "dev-fuse",
+							VolumeSource: v1.VolumeSource{
+								HostPath: &v1.HostPathVolumeSource{Path: "/dev/fuse", Type: &charDevice},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	err = cluster.GetOwnerInfo().SetControllerReference(ds)
+	if err != nil {
+		return nil, err
+	}
+
+	newDs, err := k8sutil.CreateOrUpdateDaemonSet(cluster.GetContext().Clientset, ds)
+	if err != nil {
+		return nil, err
+	}
+
+	if newDs.Generation > newDs.Status.ObservedGeneration {
+		logger.Infof("Apply %s DaemonSet in namespace %s successed", FUSE, cluster.GetNameSpace())
+		k8sutil.RecordEvent(cluster, v1.EventTypeNormal, k8sutil.ReasonFuseStarted,
+			"DaemonSet %q to mount %d file systems is started on %d nodes", ds.GetName(), len(fuse.FileSystems), len(fuse.Nodes))
+	}
+	return newDs, nil
+}
+
+// StopFuse deletes the DaemonSet of FUSE clients so that the file systems are unmounted, the file
+// systems and their data are kept in the cluster.
+func StopFuse(cluster clusterd.Clusterer) error {
+	clientset := cluster.GetContext().Clientset
+	name := clusterd.ResourceName(cluster, FUSE)
+	_, err := clientset.AppsV1().DaemonSets(cluster.GetNameSpace()).Get(name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := k8sutil.DeleteDaemonSetIfExists(clientset, cluster.GetNameSpace(), name); err != nil {
+		return err
+	}
+	k8sutil.RecordEvent(cluster, v1.EventTypeNormal, k8sutil.ReasonFuseStopped, "FUSE clients are stopped")
+	return nil
+}
+
+// GetMountStatus returns the health of the mounts of the file systems on the nodes of FUSE clients
+// by the readiness of the containers that mount them.
+func GetMountStatus(cluster clusterd.Clusterer, fuse *curvev1.FuseSpec) ([]curvev1.MountStatus, error) {
+	selector := k8sutil.GetLabelSelector(k8sutil.ClusterSelectorLabels(cluster, map[string]string{"app": FUSE}))
+	pods, err := k8sutil.GetPodsByLabelSelector(cluster.GetContext().Clientset, cluster.GetNameSpace(), selector)
+	if err != nil {
+		return nil, err
+	}
+
+	podOfNode := map[string]*v1.Pod{}
+	for i := range pods.Items {
+		pod := &pods.Items[i]
+		if pod.DeletionTimestamp == nil && len(pod.Spec.NodeName) > 0 {
+			podOfNode[pod.Spec.NodeName] = pod
+		}
+	}
+
+	mounts := []curvev1.MountStatus{}
+	for _, node := range fuse.Nodes {
+		pod := podOfNode[node]
+		for _, name := range fuse.FileSystems {
+			mount := curvev1.MountStatus{Node: node, FileSystem: name}
+			mount.Ready, mount.Message = getContainerMountStatus(pod, name)
+			mounts = append(mounts, mount)
+		}
+	}
+	return mounts, nil
+}
+
+// getContainerMountStatus returns whether the container of the pod mounts the file system and the
+// reason if it doesn't
+func getContainerMountStatus(pod *v1.Pod, name string) (bool, string) {
+	if pod == nil {
+		return false, "FUSE client is not scheduled on the node"
+	}
+	for _, status := range pod.Status.ContainerStatuses {
+		if status.Name != name {
+			continue
+		}
+		if status.Ready {
+			return true, ""
+		}
+		switch {
+		case status.State.Waiting != nil:
+			return false, fmt.Sprintf("waiting: %s", status.State.Waiting.Reason)
+		case status.State.Terminated != nil:
+			return false, fmt.Sprintf("terminated: %s", status.State.Terminated.Reason)
+		default:
+			return false, "file system is not mounted yet"
+		}
+	}
+	return false, fmt.Sprintf("FUSE client is %s", pod.Status.Phase)
+}
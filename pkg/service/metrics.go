@@ -0,0 +1,105 @@
+package service
+
+import (
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+
+	"github.com/opencurve/curve-operator/pkg/clusterd"
+	"github.com/opencurve/curve-operator/pkg/k8sutil"
+	"github.com/opencurve/curve-operator/pkg/topology"
+)
+
+const (
+	// METRICS_PORT_NAME is the name of the port of the headless Service that exports the metrics
+	METRICS_PORT_NAME = "metrics"
+	// METRICS_SERVICE_APP is the app label of the headless Services that export the metrics
+	METRICS_SERVICE_APP = "curve-metrics"
+
+	// BRPC_METRICS_PATH is the path that the brpc servers of curve export the metrics in Prometheus format
+	BRPC_METRICS_PATH = "/brpc_metrics"
+	// ETCD_METRICS_PATH is the path that etcd exports the metrics on its client port
+	ETCD_METRICS_PATH = "/metrics"
+)
+
+// GetMetricsEndpoint returns the port and the path that the service exports its metrics, the brpc
+// servers of mds and snapshotclone export them on the dummy port and chunkserver and metaserver
+// on the listen port.
+func GetMetricsEndpoint(dc *topology.DeployConfig) (int, string) {
+	switch dc.GetRole() {
+	case topology.ROLE_ETCD:
+		return dc.GetListenClientPort(), ETCD_METRICS_PATH
+	case topology.ROLE_MDS, topology.ROLE_SNAPSHOTCLONE:
+		return dc.GetListenDummyPort(), BRPC_METRICS_PATH
+	default:
+		return dc.GetListenPort(), BRPC_METRICS_PATH
+	}
+}
+
+// getMetricsServiceLabel returns the labels of the headless Service that exports the metrics of dc
+func getMetricsServiceLabel(dc *topology.DeployConfig) map[string]string {
+	labels := getServiceLabel(dc)
+	labels["app"] = METRICS_SERVICE_APP
+	return labels
+}
+
+// GetMetricsServiceName returns the name of the headless Service that exports the metrics of dc
+func GetMetricsServiceName(cluster clusterd.Clusterer, dc *topology.DeployConfig) string {
+	return clusterd.ResourceName(cluster, dc.GetName()+"-metrics")
+}
+
+// StartMetricsService creates or updates the headless Service that selects the pod of dc and exports
+// its metrics port, the pod runs on host network so that the endpoint is the ip of its node.
+func StartMetricsService(cluster clusterd.Clusterer, dc *topology.DeployConfig) (*v1.Service, error) {
+	port, _ := GetMetricsEndpoint(dc)
+	s := &v1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      GetMetricsServiceName(cluster, dc),
+			Namespace: cluster.GetNameSpace(),
+			Labels:    k8sutil.ClusterLabels(cluster, getMetricsServiceLabel(dc)),
+		},
+		Spec: v1.ServiceSpec{
+			ClusterIP: v1.ClusterIPNone,
+			Selector:  k8sutil.ClusterSelectorLabels(cluster, getServiceLabel(dc)),
+			Ports: []v1.ServicePort{
+				{
+					Name:       METRICS_PORT_NAME,
+					Port:       int32(port),
+					TargetPort: intstr.FromInt(port),
+				},
+			},
+		},
+	}
+
+	err := cluster.GetOwnerInfo().SetControllerReference(s)
+	if err != nil {
+		return nil, err
+	}
+	return k8sutil.CreateOrUpdateService(cluster.GetContext().Clientset, s)
+}
+
+// DeleteMetricsServices deletes the headless Services that export the metrics except the ones of dcs,
+// it removes all of them if dcs is empty.
+func DeleteMetricsServices(cluster clusterd.Clusterer, dcs []*topology.DeployConfig) error {
+	clientset := cluster.GetContext().Clientset
+	selector := k8sutil.GetLabelSelector(k8sutil.ClusterSelectorLabels(cluster, map[string]string{"app": METRICS_SERVICE_APP}))
+	services, err := k8sutil.GetServicesByLabelSelector(clientset, cluster.GetNameSpace(), selector)
+	if err != nil {
+		return err
+	}
+
+	kept := map[string]bool{}
+	for _, dc := range dcs {
+		kept[GetMetricsServiceName(cluster, dc)] = true
+	}
+	for _, s := range services.Items {
+		if kept[s.Name] {
+			continue
+		}
+		if err := k8sutil.DeleteService(clientset, s.Namespace, s.Name); err != nil {
+			return err
+		}
+		logger.Infof("metrics Service %s of cluster %q is deleted", s.Name, cluster.GetName())
+	}
+	return nil
+}
@@ -0,0 +1,305 @@
+package service
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+
+	"github.com/pkg/errors"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+
+	"github.com/opencurve/curve-operator/pkg/clusterd"
+	"github.com/opencurve/curve-operator/pkg/k8sutil"
+)
+
+const (
+	MINIO                = "minio"
+	MINIO_CREDENTIALS    = "minio-credentials"
+	MINIO_CREATE_BUCKET  = "minio-create-bucket"
+	MINIO_DATA_DIR       = "/data"
+	MINIO_ACCESS_KEY_KEY = "accessKey"
+	MINIO_SECRET_KEY_KEY = "secretKey"
+
+	// BUCKET_ANNOTATION is the annotation of the bucket job that records the bucket it creates
+	BUCKET_ANNOTATION = "operator.curve.io/bucket"
+)
+
+var create_bucket string = `
+mc alias set s3 http://${S3_ADDRESS} "${S3_ACCESS_KEY}" "${S3_SECRET_KEY}" && mc mb --ignore-existing s3/${S3_BUCKET}
+`
+
+// IsEmbeddedS3Enabled returns true if the MinIO deployed by operator is the S3 service of cluster
+func IsEmbeddedS3Enabled(cluster clusterd.Clusterer) bool {
+	s3 := cluster.GetS3Spec()
+	return s3 != nil && s3.Embedded != nil && s3.Embedded.Enable
+}
+
+// getEmbeddedS3Address returns the address of MinIO that is listening on the host network
+func getEmbeddedS3Address(cluster clusterd.Clusterer) (string, error) {
+	embedded := cluster.GetS3Spec().Embedded
+	hostIp, err := k8sutil.GetNodeIpByName(embedded.Host, cluster.GetContext().Clientset)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s:%d", hostIp, embedded.ListenPort), nil
+}
+
+// getEmbeddedS3CredentialRefs returns the references to the credentials of MinIO in the Secret
+// generated by operator
+func getEmbeddedS3CredentialRefs(cluster clusterd.Clusterer) (*v1.SecretKeySelector, *v1.SecretKeySelector) {
+	name := v1.LocalObjectReference{Name: clusterd.ResourceName(cluster, MINIO_CREDENTIALS)}
+	return &v1.SecretKeySelector{LocalObjectReference: name, Key: MINIO_ACCESS_KEY_KEY},
+		&v1.SecretKeySelector{LocalObjectReference: name, Key: MINIO_SECRET_KEY_KEY}
+}
+
+// randomHex returns a random hex string of n characters
+func randomHex(n int) (string, error) {
+	b := make([]byte, (n+1)/2)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b)[:n], nil
+}
+
+// createEmbeddedS3Credentials generates the credentials of MinIO once, they're kept in the Secret
+// owned by the cluster so that the objects are accessible after MinIO restarts.
+func createEmbeddedS3Credentials(cluster clusterd.Clusterer) error {
+	ak, err := randomHex(20)
+	if err != nil {
+		return errors.Wrap(err, "failed to generate the access key of MinIO")
+	}
+	sk, err := randomHex(40)
+	if err != nil {
+		return errors.Wrap(err, "failed to generate the secret key of MinIO")
+	}
+
+	secret := &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      clusterd.ResourceName(cluster, MINIO_CREDENTIALS),
+			Namespace: cluster.GetNameSpace(),
+			Labels:    k8sutil.ClusterLabels(cluster, map[string]string{"app": MINIO}),
+		},
+		StringData: map[string]string{
+			MINIO_ACCESS_KEY_KEY: ak,
+			MINIO_SECRET_KEY_KEY: sk,
+		},
+	}
+	err = cluster.GetOwnerInfo().SetControllerReference(secret)
+	if err != nil {
+		return err
+	}
+
+	_, err = k8sutil.CreateSecretIfNotExist(cluster.GetContext().Clientset, secret)
+	return err
+}
+
+// getEmbeddedS3Env returns the env of the credentials of MinIO with the names specified
+func getEmbeddedS3Env(cluster clusterd.Clusterer, akName, skName string) []v1.EnvVar {
+	akRef, skRef := getEmbeddedS3CredentialRefs(cluster)
+	return []v1.EnvVar{
+		{Name: akName, ValueFrom: &v1.EnvVarSource{SecretKeyRef: akRef}},
+		{Name: skName, ValueFrom: &v1.EnvVarSource{SecretKeyRef: skRef}},
+	}
+}
+
+// startEmbeddedS3Deployment creates or updates the Deployment of MinIO on the host of embedded S3
+func startEmbeddedS3Deployment(cluster clusterd.Clusterer) (*appsv1.Deployment, error) {
+	embedded := cluster.GetS3Spec().Embedded
+	labels := k8sutil.ClusterLabels(cluster, map[string]string{"app": MINIO})
+	hostPathType := v1.HostPathDirectoryOrCreate
+	replicas := int32(1)
+
+	container := v1.Container{
+		Name:  MINIO,
+		Image: embedded.ContainerImage,
+		Args:  []string{"server", MINIO_DATA_DIR, "--address", fmt.Sprintf(":%d", embedded.ListenPort)},
+		Env:   getEmbeddedS3Env(cluster, "MINIO_ROOT_USER", "MINIO_ROOT_PASSWORD"),
+		VolumeMounts: []v1.VolumeMount{
+			{Name: "data", MountPath: MINIO_DATA_DIR},
+		},
+		Ports: []v1.ContainerPort{
+			{Name: "http", ContainerPort: int32(embedded.ListenPort), HostPort: int32(embedded.ListenPort)},
+		},
+		ReadinessProbe: &v1.Probe{
+			Handler: v1.Handler{
+				HTTPGet: &v1.HTTPGetAction{Path: "/minio/health/ready", Port: intstr.FromInt(embedded.ListenPort)},
+			},
+			InitialDelaySeconds: 5,
+			PeriodSeconds:       10,
+		},
+	}
+
+	d := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      clusterd.ResourceName(cluster, MINIO),
+			Namespace: cluster.GetNameSpace(),
+			Labels:    labels,
+		},
+		Spec: appsv1.DeploymentSpec{
+			Selector: &metav1.LabelSelector{
+				MatchLabels: k8sutil.ClusterSelectorLabels(cluster, map[string]string{"app": MINIO}),
+			},
+			Template: v1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: labels,
+				},
+				Spec: v1.PodSpec{
+					Containers:    []v1.Container{container},
+					RestartPolicy: v1.RestartPolicyAlways,
+					HostNetwork:   true,
+					DNSPolicy:     v1.DNSClusterFirstWithHostNet,
+					Volumes: []v1.Volume{
+						{
+							Name: "data",
+							VolumeSource: v1.VolumeSource{
+								HostPath: &v1.HostPathVolumeSource{Path: embedded.DataDir, Type: &hostPathType},
+							},
+						},
+					},
+				},
+			},
+			Replicas: &replicas,
+			// the data directory can't be shared by two pods
+			Strategy: appsv1.DeploymentStrategy{
+				Type: appsv1.RecreateDeploymentStrategyType,
+			},
+		},
+	}
+	err := ApplyPlacement(cluster, &d.Spec.Template.Spec, embedded.Host, embedded.Placement)
+	if err != nil {
+		return nil, err
+	}
+
+	err = cluster.GetOwnerInfo().SetControllerReference(d)
+	if err != nil {
+		return nil, err
+	}
+
+	newDeploy, err := k8sutil.CreateOrUpdateDeployment(cluster.GetContext().Clientset, d)
+	if err != nil {
+		return nil, err
+	}
+
+	if newDeploy.Generation > newDeploy.Status.ObservedGeneration {
+		logger.Infof("Apply %s Deployment in namespace %s successed", MINIO, cluster.GetNameSpace())
+		k8sutil.RecordEvent(cluster, v1.EventTypeNormal, k8sutil.ReasonEmbeddedS3Started,
+			"Deployment %q of embedded S3 is started on node %q", d.GetName(), embedded.Host)
+	}
+	return newDeploy, nil
+}
+
+// startJobCreateBucket creates the bucket in MinIO, the job is replaced once the bucket is changed
+func startJobCreateBucket(cluster clusterd.Clusterer, address string) (*batchv1.Job, error) {
+	s3 := cluster.GetS3Spec()
+	labels := k8sutil.ClusterLabels(cluster, map[string]string{"app": MINIO_CREATE_BUCKET})
+
+	container := v1.Container{
+		Name:    MINIO_CREATE_BUCKET,
+		Command: []string{"sh", "-c", create_bucket},
+		Image:   s3.Embedded.ClientImage,
+		Env: append(getEmbeddedS3Env(cluster, "S3_ACCESS_KEY", "S3_SECRET_KEY"),
+			v1.EnvVar{Name: "S3_ADDRESS", Value: address},
+			v1.EnvVar{Name: "S3_BUCKET", Value: s3.SnapShotBucketName},
+		),
+	}
+
+	job := &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        clusterd.ResourceName(cluster, MINIO_CREATE_BUCKET),
+			Namespace:   cluster.GetNameSpace(),
+			Labels:      labels,
+			Annotations: map[string]string{BUCKET_ANNOTATION: s3.SnapShotBucketName},
+		},
+		Spec: batchv1.JobSpec{
+			Template: v1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: labels,
+				},
+				Spec: v1.PodSpec{
+					Containers: []v1.Container{
+						container,
+					},
+					RestartPolicy: v1.RestartPolicyOnFailure,
+					HostNetwork:   true,
+					DNSPolicy:     v1.DNSClusterFirstWithHostNet,
+				},
+			},
+		},
+	}
+	applyJobResources(cluster, &job.Spec.Template.Spec)
+
+	err := cluster.GetOwnerInfo().SetControllerReference(job)
+	if err != nil {
+		return nil, err
+	}
+
+	return k8sutil.CreateOrReplaceJob(cluster.GetContext().Clientset, job, BUCKET_ANNOTATION)
+}
+
+// StartEmbeddedS3 creates or updates the MinIO of cluster and creates the bucket in it once it's
+// ready, it returns false and records the progress if the bucket is not created yet.
+func StartEmbeddedS3(cluster clusterd.Clusterer) (bool, error) {
+	if err := createEmbeddedS3Credentials(cluster); err != nil {
+		return false, err
+	}
+
+	d, err := startEmbeddedS3Deployment(cluster)
+	if err != nil {
+		return false, err
+	}
+	if k8sutil.IsDeploymentProgressDeadlineExceeded(d) {
+		return false, errors.Errorf("Deployment %q of embedded S3 failed to progress", d.Name)
+	}
+	if !k8sutil.IsDeploymentReady(d) {
+		cluster.GetProgress().Message = fmt.Sprintf("waiting for embedded S3 %q to be ready", d.Name)
+		return false, nil
+	}
+
+	address, err := getEmbeddedS3Address(cluster)
+	if err != nil {
+		return false, err
+	}
+	job, err := startJobCreateBucket(cluster, address)
+	if err != nil {
+		return false, err
+	}
+	if k8sutil.IsJobFailed(job) {
+		return false, errors.Errorf("job %q to create the bucket of embedded S3 failed", job.Name)
+	}
+	if !k8sutil.IsJobCompleted(job) {
+		cluster.GetProgress().Message = fmt.Sprintf("waiting for job %q to create the bucket", job.Name)
+		return false, nil
+	}
+	return true, nil
+}
+
+// StopEmbeddedS3 deletes the MinIO of cluster and its bucket job, the objects on host and the
+// credentials are kept so that they're accessible if it's enabled again.
+func StopEmbeddedS3(cluster clusterd.Clusterer) error {
+	clientset := cluster.GetContext().Clientset
+	d := &appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{
+		Name:      clusterd.ResourceName(cluster, MINIO),
+		Namespace: cluster.GetNameSpace(),
+	}}
+	exist, err := k8sutil.IsDeploymentExist(clientset, d)
+	if err != nil {
+		return err
+	}
+	if !exist {
+		return nil
+	}
+
+	if err := k8sutil.DeleteDeployment(clientset, d); err != nil {
+		return err
+	}
+	jobName := clusterd.ResourceName(cluster, MINIO_CREATE_BUCKET)
+	if err := k8sutil.DeleteBatchJob(clientset, cluster.GetNameSpace(), jobName, false); err != nil {
+		logger.Warningf("failed to delete job %q of embedded S3. %v", jobName, err)
+	}
+	k8sutil.RecordEvent(cluster, v1.EventTypeNormal, k8sutil.ReasonEmbeddedS3Stopped, "embedded S3 is stopped")
+	return nil
+}
@@ -15,7 +15,7 @@ import (
 )
 
 const (
-	CURVE_TOPOLOGY_CONFIGMAP = "curve-cluster-pool"
+	CURVE_TOPOLOGY_CONFIGMAP = "cluster-pool"
 	TOPO_JSON_FILE_NAME      = "topology.json"
 )
 
@@ -100,17 +100,17 @@ type (
 	}
 )
 
-// prepare get cluster pool or create new cluster pool
-func createOrUpdatePoolConfigMap(cluster clusterd.Clusterer, dcs []*topology.DeployConfig) error {
+// createOrUpdatePoolConfigMap get cluster pool or create new cluster pool, it returns the cluster pool json
+func createOrUpdatePoolConfigMap(cluster clusterd.Clusterer, dcs []*topology.DeployConfig) (string, error) {
 	clusterPool, err := getClusterPool(cluster, dcs)
 	if err != nil {
-		return err
+		return "", err
 	}
 
 	var bytes []byte
 	bytes, err = json.Marshal(clusterPool)
 	if err != nil {
-		return err
+		return "", err
 	}
 	clusterPoolJson := string(bytes)
 	data := map[string]string{
@@ -119,51 +119,144 @@ func createOrUpdatePoolConfigMap(cluster clusterd.Clusterer, dcs []*topology.Dep
 
 	cm := &corev1.ConfigMap{
 		ObjectMeta: metav1.ObjectMeta{
-			Name:      CURVE_TOPOLOGY_CONFIGMAP,
+			Name:      clusterd.ResourceName(cluster, CURVE_TOPOLOGY_CONFIGMAP),
 			Namespace: cluster.GetNameSpace(),
+			Labels:    k8sutil.ClusterLabels(cluster, nil),
 		},
 		Data: data,
 	}
 
+	err = cluster.GetOwnerInfo().SetControllerReference(cm)
+	if err != nil {
+		return "", err
+	}
+
 	_, err = k8sutil.CreateOrUpdateConfigMap(cluster.GetContext().Clientset, cm)
 	if err != nil {
-		return nil
+		return "", err
 	}
 
-	return nil
+	return clusterPoolJson, nil
 }
 
-func getClusterPool(cluster clusterd.Clusterer, dcs []*topology.DeployConfig) (CurveClusterTopo, error) {
-	cm, err := k8sutil.GetConfigMapByName(cluster.GetContext().Clientset, cluster.GetNameSpace(), CURVE_TOPOLOGY_CONFIGMAP)
+// getRegisteredClusterPool returns the cluster pool that has been registered to MDS, it returns nil if not found
+func getRegisteredClusterPool(cluster clusterd.Clusterer) (*CurveClusterTopo, error) {
+	cm, err := k8sutil.GetConfigMapByName(cluster.GetContext().Clientset, cluster.GetNameSpace(),
+		clusterd.ResourceName(cluster, CURVE_TOPOLOGY_CONFIGMAP))
 	if err != nil {
 		if apierrors.IsNotFound(err) {
-			return generateDefaultClusterPool(dcs)
+			return nil, nil
 		}
-		return CurveClusterTopo{}, err
+		return nil, err
 	}
 
-	oldPool := CurveClusterTopo{}
-	oldPoolStrData := cm.Data[TOPO_JSON_FILE_NAME]
-	err = json.Unmarshal([]byte(oldPoolStrData), &oldPool)
-	pool, err := generateDefaultClusterPool(dcs)
+	oldPool := &CurveClusterTopo{}
+	if err := json.Unmarshal([]byte(cm.Data[TOPO_JSON_FILE_NAME]), oldPool); err != nil {
+		return nil, err
+	}
+	return oldPool, nil
+}
+
+func getClusterPool(cluster clusterd.Clusterer, dcs []*topology.DeployConfig) (CurveClusterTopo, error) {
+	oldPool, err := getRegisteredClusterPool(cluster)
 	if err != nil {
+		return CurveClusterTopo{}, err
+	}
+	pool, err := generateDefaultClusterPool(dcs)
+	if err != nil || oldPool == nil {
 		return pool, err
 	}
 
-	// gurantee oldPool and pool has same servers
-	for i, server := range pool.Servers {
-		oldPool.Servers[i].InternalIp = server.InternalIp
-		oldPool.Servers[i].InternalPort = server.InternalPort
-		oldPool.Servers[i].ExternalIp = server.ExternalIp
-		oldPool.Servers[i].ExternalPort = server.ExternalPort
-	}
+	// keep the zones of the registered servers and put the new servers into zones
+	pool.Servers = mergeServerZones(oldPool.Servers, pool.Servers)
 	if dcs[0].GetKind() == topology.KIND_CURVEBS {
-		for i, pool := range pool.LogicalPools {
-			oldPool.LogicalPools[i].Copysets = pool.Copysets
+		for i := range oldPool.LogicalPools {
+			if i < len(pool.LogicalPools) {
+				oldPool.LogicalPools[i].Copysets = pool.LogicalPools[i].Copysets
+			}
 		}
+		pool.LogicalPools = oldPool.LogicalPools
+	} else {
+		pool.Pools = oldPool.Pools
 	}
+	pool.NPools = oldPool.NPools
 
-	return oldPool, nil
+	return pool, nil
+}
+
+// mergeServerZones keeps the zone of the servers that have been registered, a new server is put
+// into the zone of the other servers on the same host or into the zone with the fewest hosts.
+func mergeServerZones(oldServers, servers []Server) []Server {
+	serverZones, hostZones := map[string]string{}, map[string]string{}
+	zoneHosts := map[string]int{}
+	for _, server := range oldServers {
+		serverZones[server.Name] = server.Zone
+		if _, ok := hostZones[server.InternalIp]; !ok {
+			hostZones[server.InternalIp] = server.Zone
+			zoneHosts[server.Zone]++
+		}
+	}
+	nextZone := genNextZone(DEFAULT_ZONES_PER_POOL)
+	for i := 0; i < DEFAULT_ZONES_PER_POOL; i++ {
+		zone := nextZone()
+		if _, ok := zoneHosts[zone]; !ok {
+			zoneHosts[zone] = 0
+		}
+	}
+	zones := []string{}
+	for zone := range zoneHosts {
+		zones = append(zones, zone)
+	}
+	sort.Strings(zones)
+
+	for i := range servers {
+		server := &servers[i]
+		if zone, ok := serverZones[server.Name]; ok {
+			server.Zone = zone
+			continue
+		}
+		if zone, ok := hostZones[server.InternalIp]; ok {
+			server.Zone = zone
+			continue
+		}
+
+		server.Zone = zones[0]
+		for _, zone := range zones {
+			if zoneHosts[zone] < zoneHosts[server.Zone] {
+				server.Zone = zone
+			}
+		}
+		hostZones[server.InternalIp] = server.Zone
+		zoneHosts[server.Zone]++
+	}
+	return servers
+}
+
+// GetScaledOutServices returns the chunkservers or metaservers that are not registered in the cluster pool
+func GetScaledOutServices(cluster clusterd.Clusterer, dcs []*topology.DeployConfig) ([]*topology.DeployConfig, error) {
+	oldPool, err := getRegisteredClusterPool(cluster)
+	if err != nil || oldPool == nil {
+		return nil, err
+	}
+
+	registered := map[string]bool{}
+	for _, server := range oldPool.Servers {
+		registered[server.Name] = true
+	}
+	added := []*topology.DeployConfig{}
+	for _, dc := range dcs {
+		if isPoolServerRole(dc) && !registered[formatName(dc)] {
+			added = append(added, dc)
+		}
+	}
+	return added, nil
+}
+
+// isPoolServerRole returns true if the service is registered as a server in the cluster pool
+func isPoolServerRole(dc *topology.DeployConfig) bool {
+	role, kind := dc.GetRole(), dc.GetKind()
+	return (role == ROLE_CHUNKSERVER && kind == KIND_CURVEBS) ||
+		(role == ROLE_METASERVER && kind == KIND_CURVEFS)
 }
 
 func generateDefaultClusterPool(dcs []*topology.DeployConfig) (topo CurveClusterTopo, err error) {
@@ -192,9 +285,7 @@ func createLogicalPool(dcs []*topology.DeployConfig, logicalPool string) (Logica
 	kind := dcs[0].GetKind()
 	SortDeployConfigs(dcs)
 	for _, dc := range dcs {
-		role := dc.GetRole()
-		if (role == ROLE_CHUNKSERVER && kind == KIND_CURVEBS) ||
-			(role == ROLE_METASERVER && kind == KIND_CURVEFS) {
+		if isPoolServerRole(dc) {
 			if dc.GetParentId() == dc.GetId() {
 				zone = nextZone()
 			}
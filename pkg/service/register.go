@@ -13,6 +13,7 @@ import (
 	"github.com/opencurve/curve-operator/pkg/clusterd"
 	"github.com/opencurve/curve-operator/pkg/k8sutil"
 	"github.com/opencurve/curve-operator/pkg/topology"
+	"github.com/opencurve/curve-operator/pkg/utils"
 )
 
 const (
@@ -21,18 +22,23 @@ const (
 
 	WAIT_MDS_ELECTION_CONTAINER      = "wait-mds-election-container"
 	WAIT_CHUNKSERVER_START_CONTAINER = "wait-chunkserver-start-container"
+
+	// TOPOLOGY_HASH_ANNOTATION is the annotation of the pool job that records the hash of the cluster pool
+	TOPOLOGY_HASH_ANNOTATION = "operator.curve.io/topology-hash"
 )
 
 var (
 	CURVE_CREATE_POOL_JOB = "curve-create-%s"
+	CREATE_POOL_JOB_NAME  = "create-%s-pool"
 )
 
-// StartJobCreatePool create job to create physicalpool or logicalpool
-func StartJobCreatePool(cluster clusterd.Clusterer, dc *topology.DeployConfig, dcs []*topology.DeployConfig, poolType string) error {
+// StartJobCreatePool create job to create physicalpool or logicalpool if it doesn't exist,
+// the caller should check whether the returned job is completed
+func StartJobCreatePool(cluster clusterd.Clusterer, dc *topology.DeployConfig, dcs []*topology.DeployConfig, poolType string) (*batchv1.Job, error) {
 	// create or update CURVE_TOPOLOGY_CONFIGMAP configmap that store cluster pool json
-	err := createOrUpdatePoolConfigMap(cluster, dcs)
+	clusterPoolJson, err := createOrUpdatePoolConfigMap(cluster, dcs)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	// security context
@@ -41,7 +47,7 @@ func StartJobCreatePool(cluster clusterd.Clusterer, dc *topology.DeployConfig, d
 	runAsNonRoot := false
 	readOnlyRootFilesystem := false
 
-	vols, volMounts := getToolsAndTopoVolumeAndMount(dc)
+	vols, volMounts := getToolsAndTopoVolumeAndMount(cluster, dc)
 	poolJsonPath := path.Join(dc.GetProjectLayout().ToolsConfDir, TOPO_JSON_FILE_NAME)
 	container := v1.Container{
 		Name: fmt.Sprintf(CURVE_CREATE_POOL_JOB, poolType),
@@ -49,7 +55,7 @@ func StartJobCreatePool(cluster clusterd.Clusterer, dc *topology.DeployConfig, d
 			genCreatePoolCommand(dc, poolType, poolJsonPath),
 		},
 		Image:           cluster.GetContainerImage(),
-		ImagePullPolicy: v1.PullIfNotPresent,
+		ImagePullPolicy: cluster.GetImagePullPolicy(),
 		VolumeMounts:    volMounts,
 		SecurityContext: &v1.SecurityContext{
 			Privileged:             &privileged,
@@ -61,13 +67,13 @@ func StartJobCreatePool(cluster clusterd.Clusterer, dc *topology.DeployConfig, d
 
 	initContianers, err := makeCreatePoolJobInitContainers(cluster, dcs, poolType)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	podSpec := v1.PodTemplateSpec{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:   fmt.Sprintf(CURVE_CREATE_POOL_JOB, poolType),
-			Labels: getCreatePoolJobLabel(poolType),
+			Labels: k8sutil.ClusterLabels(cluster, getCreatePoolJobLabel(poolType)),
 		},
 		Spec: v1.PodSpec{
 			InitContainers: initContianers,
@@ -83,22 +89,33 @@ func StartJobCreatePool(cluster clusterd.Clusterer, dc *topology.DeployConfig, d
 
 	job := &batchv1.Job{
 		ObjectMeta: metav1.ObjectMeta{
-			Name:      fmt.Sprintf(CURVE_CREATE_POOL_JOB, poolType),
+			Name:      clusterd.ResourceName(cluster, fmt.Sprintf(CREATE_POOL_JOB_NAME, poolType)),
 			Namespace: cluster.GetNameSpace(),
-			Labels:    getCreatePoolJobLabel(poolType),
+			Labels:    k8sutil.ClusterLabels(cluster, getCreatePoolJobLabel(poolType)),
+			// the job is run again to register the servers once the cluster pool is changed
+			Annotations: map[string]string{TOPOLOGY_HASH_ANNOTATION: utils.Hash(clusterPoolJson)},
 		},
 		Spec: batchv1.JobSpec{
 			Template: podSpec,
 		},
 	}
+	applyJobResources(cluster, &job.Spec.Template.Spec)
 
 	err = cluster.GetOwnerInfo().SetControllerReference(job)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	err = k8sutil.RunReplaceableJob(cluster.GetContext().Clientset, job, true)
-	return err
+	currentJob, err := k8sutil.CreateOrReplaceJob(cluster.GetContext().Clientset, job, TOPOLOGY_HASH_ANNOTATION)
+	if err != nil {
+		return nil, err
+	}
+
+	if currentJob.Status.StartTime == nil && currentJob.Status.Active == 0 {
+		k8sutil.RecordEvent(cluster, v1.EventTypeNormal, k8sutil.ReasonPoolJobStarted,
+			"Job %q to create %s pool is started", job.GetName(), poolType)
+	}
+	return currentJob, nil
 }
 
 // getCreatePoolJobLabel return curve-create-pool Pod and Deployment label
@@ -141,7 +158,7 @@ func makeCreatePoolJobInitContainers(cluster clusterd.Clusterer, dcs []*topology
 			wait_mds_election,
 		},
 		Image:           cluster.GetContainerImage(),
-		ImagePullPolicy: v1.PullIfNotPresent,
+		ImagePullPolicy: cluster.GetImagePullPolicy(),
 		Env: []v1.EnvVar{
 			{
 				Name:  "CLUSTER_MDS_ADDR",
@@ -162,7 +179,7 @@ func makeCreatePoolJobInitContainers(cluster clusterd.Clusterer, dcs []*topology
 				wait_chunkserver_start,
 			},
 			Image:           cluster.GetContainerImage(),
-			ImagePullPolicy: v1.PullIfNotPresent,
+			ImagePullPolicy: cluster.GetImagePullPolicy(),
 			Env: []v1.EnvVar{
 				{
 					Name:  "CHUNKSERVER_NUMS",
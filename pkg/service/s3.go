@@ -0,0 +1,100 @@
+package service
+
+import (
+	"github.com/opencurve/curve-operator/pkg/clusterd"
+	"github.com/opencurve/curve-operator/pkg/k8sutil"
+	"github.com/opencurve/curve-operator/pkg/topology"
+	"github.com/opencurve/curve-operator/pkg/utils"
+)
+
+const (
+	S3_CONFIG_FILE = "s3.conf"
+
+	// SECRET_HASH_ANNOTATION is the annotation of the pod template that records the hash of the
+	// credentials rendered into its config, the pods are restarted once the Secrets are changed.
+	SECRET_HASH_ANNOTATION = "operator.curve.io/secret-hash"
+)
+
+// GetS3Config returns the items of s3.conf of curvebs or the configs of curvefs that are specified
+// in the spec of cluster, the credentials are read from the Secrets they refer to so that they're
+// never stored in the CR. The address and the credentials of the embedded MinIO are used if it's enabled.
+func GetS3Config(cluster clusterd.Clusterer) (map[string]string, error) {
+	config := map[string]string{}
+	s3 := cluster.GetS3Spec()
+	if s3 == nil {
+		return config, nil
+	}
+
+	address := s3.NosAddress
+	akRef, skRef := s3.AccessKeySecretRef, s3.SecretKeySecretRef
+	if IsEmbeddedS3Enabled(cluster) {
+		var err error
+		address, err = getEmbeddedS3Address(cluster)
+		if err != nil {
+			return nil, err
+		}
+		akRef, skRef = getEmbeddedS3CredentialRefs(cluster)
+	}
+
+	clientset := cluster.GetContext().Clientset
+	if akRef != nil {
+		ak, err := k8sutil.GetSecretKey(clientset, cluster.GetNameSpace(), akRef)
+		if err != nil {
+			return nil, err
+		}
+		config[topology.CONFIG_S3_ACCESS_KEY.Key()] = ak
+	}
+	if skRef != nil {
+		sk, err := k8sutil.GetSecretKey(clientset, cluster.GetNameSpace(), skRef)
+		if err != nil {
+			return nil, err
+		}
+		config[topology.CONFIG_S3_SECRET_KEY.Key()] = sk
+	}
+
+	// curvebs and curvefs name the address and the bucket differently
+	addressKey, bucketKey := topology.CONFIG_S3_ADDRESS.Key(), topology.CONFIG_S3_BUCKET_NAME.Key()
+	if cluster.GetKind() == topology.KIND_CURVEFS {
+		addressKey, bucketKey = topology.CONFIG_S3_ENDPOINT.Key(), topology.CONFIG_S3_FS_BUCKET_NAME.Key()
+	}
+	if len(address) > 0 {
+		config[addressKey] = address
+	}
+	if len(s3.SnapShotBucketName) > 0 {
+		config[bucketKey] = s3.SnapShotBucketName
+	}
+	return config, nil
+}
+
+// useS3Config returns true if the config of the service contains the S3 config, they're in s3.conf
+// of curvebs and in metaserver.conf of curvefs
+func useS3Config(dc *topology.DeployConfig) bool {
+	if dc.GetKind() == topology.KIND_CURVEFS {
+		return dc.GetRole() == topology.ROLE_METASERVER
+	}
+	for _, name := range topology.ServiceConfigs[dc.GetRole()] {
+		if name == S3_CONFIG_FILE {
+			return true
+		}
+	}
+	return false
+}
+
+// GetSecretHash returns the hash of the credentials that are rendered into the config of the
+// service, it's empty if the service doesn't use any credentials.
+func GetSecretHash(cluster clusterd.Clusterer, dc *topology.DeployConfig) (string, error) {
+	if !useS3Config(dc) {
+		return "", nil
+	}
+
+	config, err := GetS3Config(cluster)
+	if err != nil {
+		return "", err
+	}
+	ak, hasAK := config[topology.CONFIG_S3_ACCESS_KEY.Key()]
+	sk, hasSK := config[topology.CONFIG_S3_SECRET_KEY.Key()]
+	if !hasAK && !hasSK {
+		return "", nil
+	}
+	return utils.Hash(ak + "\n" + sk), nil
+}
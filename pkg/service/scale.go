@@ -0,0 +1,211 @@
+package service
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/pkg/errors"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/opencurve/curve-operator/pkg/clusterd"
+	"github.com/opencurve/curve-operator/pkg/k8sutil"
+	"github.com/opencurve/curve-operator/pkg/topology"
+)
+
+const (
+	DRAIN_CHUNKSERVER_CONTAINER  = "drain-chunkserver"
+	DRAIN_CHUNKSERVER_JOB_NAME   = "drain-%s"
+	RETIRE_CHUNKSERVER_CONTAINER = "retire-chunkserver"
+	RETIRE_CHUNKSERVER_JOB_NAME  = "retire-%s"
+
+	// DRAIN_CHUNKSERVER_TIMEOUT is the time in seconds that the copysets of a chunkserver are migrated in,
+	// the drain job fails after it so that a chunkserver that can never be drained doesn't block scaling
+	DRAIN_CHUNKSERVER_TIMEOUT = 6 * 60 * 60
+	// RETIRE_CHUNKSERVER_TIMEOUT is the time in seconds that a stopped chunkserver is retired in
+	RETIRE_CHUNKSERVER_TIMEOUT = 10 * 60
+	// chunkserverJobStartTimeout is the extra time in seconds for the pod of a chunkserver job to start
+	chunkserverJobStartTimeout = 5 * 60
+)
+
+// GetPoolServerRole returns the role of the services that are registered as servers in the cluster pool
+func GetPoolServerRole(cluster clusterd.Clusterer) string {
+	if cluster.GetKind() == KIND_CURVEFS {
+		return ROLE_METASERVER
+	}
+	return ROLE_CHUNKSERVER
+}
+
+// GetScaledInDeployments returns the Deployments of chunkservers or metaservers that are not in dcs anymore
+func GetScaledInDeployments(cluster clusterd.Clusterer, dcs []*topology.DeployConfig) ([]appsv1.Deployment, error) {
+	role := GetPoolServerRole(cluster)
+	selector := k8sutil.GetLabelSelector(k8sutil.ClusterSelectorLabels(cluster, map[string]string{"role": role}))
+	deployments, err := k8sutil.GetDeploymentsByLabelSelector(cluster.GetContext().Clientset, cluster.GetNameSpace(), selector)
+	if err != nil {
+		return nil, err
+	}
+
+	names := map[string]bool{}
+	for _, dc := range topology.FilterDeployConfigByRole(dcs, role) {
+		names[dc.GetName()] = true
+	}
+	removed := []appsv1.Deployment{}
+	for _, d := range deployments.Items {
+		if !names[d.Labels["name"]] {
+			removed = append(removed, d)
+		}
+	}
+	sort.Slice(removed, func(i, j int) bool { return removed[i].Name < removed[j].Name })
+	return removed, nil
+}
+
+// StartJobDrainChunkserver create job to migrate the copysets of the chunkserver run by the Deployment
+// to other chunkservers through MDS, the dc is used to get the tools config of cluster. The job fails
+// if the copysets are not migrated in DRAIN_CHUNKSERVER_TIMEOUT.
+func StartJobDrainChunkserver(cluster clusterd.Clusterer, dc *topology.DeployConfig, d *appsv1.Deployment) (*batchv1.Job, error) {
+	job, err := startChunkserverJob(cluster, dc, d, DRAIN_CHUNKSERVER_CONTAINER, getDrainJobName(cluster, d),
+		drain_chunkserver, DRAIN_CHUNKSERVER_TIMEOUT)
+	if err != nil {
+		return nil, err
+	}
+	if job.Status.StartTime == nil && job.Status.Active == 0 {
+		k8sutil.RecordEvent(cluster, v1.EventTypeNormal, k8sutil.ReasonDrainJobStarted,
+			"Job %q to drain the copysets of Deployment %q is started", job.GetName(), d.GetName())
+	}
+	return job, nil
+}
+
+// StartJobRetireChunkserver create job to retire the chunkserver run by the Deployment once it's drained
+// and stopped, so that it's deregistered from the topology of MDS rather than left offline.
+func StartJobRetireChunkserver(cluster clusterd.Clusterer, dc *topology.DeployConfig, d *appsv1.Deployment) (*batchv1.Job, error) {
+	job, err := startChunkserverJob(cluster, dc, d, RETIRE_CHUNKSERVER_CONTAINER, getRetireJobName(cluster, d),
+		retire_chunkserver, RETIRE_CHUNKSERVER_TIMEOUT)
+	if err != nil {
+		return nil, err
+	}
+	if job.Status.StartTime == nil && job.Status.Active == 0 {
+		k8sutil.RecordEvent(cluster, v1.EventTypeNormal, k8sutil.ReasonRetireJobStarted,
+			"Job %q to retire the chunkserver of Deployment %q is started", job.GetName(), d.GetName())
+	}
+	return job, nil
+}
+
+// startChunkserverJob creates the job that runs the script against the chunkserver of the Deployment
+// through MDS, the script is given up after timeout seconds.
+func startChunkserverJob(cluster clusterd.Clusterer, dc *topology.DeployConfig, d *appsv1.Deployment,
+	app, name, script string, timeout int64) (*batchv1.Job, error) {
+	host := GetServiceNode(d)
+	hostIp, err := k8sutil.GetNodeIpByName(host, cluster.GetContext().Clientset)
+	if err != nil {
+		return nil, err
+	}
+	port := int32(0)
+	for _, c := range d.Spec.Template.Spec.Containers {
+		for _, p := range c.Ports {
+			if p.Name == topology.CONFIG_LISTEN_PORT.Key() {
+				port = p.HostPort
+			}
+		}
+	}
+	if port == 0 {
+		return nil, errors.Errorf("failed to find the listen port of chunkserver in Deployment %q", d.Name)
+	}
+
+	vols, volMounts := getToolsAndTopoVolumeAndMount(cluster, dc)
+	container := v1.Container{
+		Name: app,
+		Command: []string{
+			"bash",
+			"-c",
+			script,
+		},
+		Image:           cluster.GetContainerImage(),
+		ImagePullPolicy: cluster.GetImagePullPolicy(),
+		VolumeMounts:    volMounts,
+		Env: []v1.EnvVar{
+			{Name: "CHUNKSERVER_IP", Value: hostIp},
+			{Name: "CHUNKSERVER_PORT", Value: fmt.Sprint(port)},
+			{Name: "TOOLS_BINARY_PATH", Value: dc.GetProjectLayout().ToolsBinaryPath},
+			{Name: "JOB_TIMEOUT", Value: fmt.Sprint(timeout)},
+		},
+	}
+
+	labels := k8sutil.ClusterLabels(cluster, map[string]string{"app": app, "name": d.Labels["name"]})
+	deadline := timeout + chunkserverJobStartTimeout
+	job := &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: cluster.GetNameSpace(),
+			Labels:    labels,
+		},
+		Spec: batchv1.JobSpec{
+			ActiveDeadlineSeconds: &deadline,
+			Template: v1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: labels,
+				},
+				Spec: v1.PodSpec{
+					Containers: []v1.Container{
+						container,
+					},
+					RestartPolicy: v1.RestartPolicyOnFailure,
+					HostNetwork:   true,
+					DNSPolicy:     v1.DNSClusterFirstWithHostNet,
+					Volumes:       vols,
+				},
+			},
+		},
+	}
+	applyJobResources(cluster, &job.Spec.Template.Spec)
+
+	err = cluster.GetOwnerInfo().SetControllerReference(job)
+	if err != nil {
+		return nil, err
+	}
+
+	return k8sutil.CreateJobIfNotExist(cluster.GetContext().Clientset, job)
+}
+
+// StopService scales the Deployment of a service that is removed from the cluster to zero, it returns
+// true once the pod of the service is gone.
+func StopService(cluster clusterd.Clusterer, d *appsv1.Deployment) (bool, error) {
+	stopped, err := k8sutil.ScaleDeployment(cluster.GetContext().Clientset, d, 0)
+	if err != nil {
+		return false, err
+	}
+	return stopped.Status.ObservedGeneration >= stopped.Generation && stopped.Status.Replicas == 0, nil
+}
+
+// RemoveService deletes the Deployment of a service that is removed from the cluster and its drain and retire jobs
+func RemoveService(cluster clusterd.Clusterer, d *appsv1.Deployment) error {
+	clientset := cluster.GetContext().Clientset
+	exist, err := k8sutil.IsDeploymentExist(clientset, d)
+	if err != nil {
+		return err
+	}
+	if exist {
+		if err := k8sutil.DeleteDeployment(clientset, d); err != nil {
+			return err
+		}
+		logger.Infof("Remove %s service Deployment in namespace %s successed", d.Labels["name"], cluster.GetNameSpace())
+		k8sutil.RecordEvent(cluster, v1.EventTypeNormal, k8sutil.ReasonServiceRemoved,
+			"Deployment %q of %s service is removed from node %q", d.GetName(), d.Labels["role"], GetServiceNode(d))
+	}
+
+	if err := k8sutil.DeleteBatchJob(clientset, cluster.GetNameSpace(), getDrainJobName(cluster, d), false); err != nil {
+		return err
+	}
+	return k8sutil.DeleteBatchJob(clientset, cluster.GetNameSpace(), getRetireJobName(cluster, d), false)
+}
+
+// getDrainJobName returns the name of the job to drain the service of the Deployment
+func getDrainJobName(cluster clusterd.Clusterer, d *appsv1.Deployment) string {
+	return clusterd.ResourceName(cluster, fmt.Sprintf(DRAIN_CHUNKSERVER_JOB_NAME, d.Labels["name"]))
+}
+
+// getRetireJobName returns the name of the job to retire the service of the Deployment
+func getRetireJobName(cluster clusterd.Clusterer, d *appsv1.Deployment) string {
+	return clusterd.ResourceName(cluster, fmt.Sprintf(RETIRE_CHUNKSERVER_JOB_NAME, d.Labels["name"]))
+}
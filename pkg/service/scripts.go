@@ -41,3 +41,134 @@ done
 
 exit 1
 `
+
+var drain_chunkserver string = `
+#!/usr/bin/env bash
+
+# the chunkserver has been removed from MDS if it isn't found by its address or it's retired
+line=$(curve_ops_tool chunkserver-list | grep "hostIP = ${CHUNKSERVER_IP}, port = ${CHUNKSERVER_PORT},")
+id=$(echo "$line" | sed -nr 's/.*chunkServerID = ([0-9]+),.*/\1/p')
+if [[ -z $id ]] || echo "$line" | grep -q RETIRED; then
+    exit 0
+fi
+
+# set the chunkserver pendding so that MDS migrates its copysets to other chunkservers
+${TOOLS_BINARY_PATH} -op=set_chunkserver -chunkserver_id=$id -chunkserver_status=pendding || exit 1
+
+start=$(date +%s)
+while (($(date +%s)-start<JOB_TIMEOUT))
+do
+    copysets=$(curve_ops_tool check-chunkserver -chunkserverId=$id | sed -nr 's/.*total copysets: ([0-9]+).*/\1/p')
+    if [[ -n $copysets && $copysets -eq 0 ]]; then
+        exit 0
+    fi
+
+    sleep 10s
+done
+
+echo "the copysets of chunkserver $id are not migrated in ${JOB_TIMEOUT}s, there may be too few chunkservers left for the replicas"
+exit 1
+`
+
+var retire_chunkserver string = `
+#!/usr/bin/env bash
+
+# the chunkserver has been removed from MDS if it isn't found by its address or it's retired
+line=$(curve_ops_tool chunkserver-list | grep "hostIP = ${CHUNKSERVER_IP}, port = ${CHUNKSERVER_PORT},")
+id=$(echo "$line" | sed -nr 's/.*chunkServerID = ([0-9]+),.*/\1/p')
+if [[ -z $id ]] || echo "$line" | grep -q RETIRED; then
+    exit 0
+fi
+
+# the drained chunkserver is stopped, retire it so that it's deregistered from the topology of MDS
+${TOOLS_BINARY_PATH} -op=set_chunkserver -chunkserver_id=$id -chunkserver_status=retired
+`
+
+var check_cluster_health string = `
+#!/usr/bin/env bash
+
+wait=0
+while ((wait<60))
+do
+    if ${CHECK_HEALTH_COMMAND}; then
+        exit 0
+    fi
+
+    sleep 10s
+    wait=$((wait+1))
+done
+
+exit 1
+`
+
+var prepare_disk string = `
+#!/usr/bin/env bash
+
+# run the command in the mount namespace of host so that the device is mounted on host
+function host() {
+    nsenter -t 1 -m -- "$@"
+}
+
+# DEVICES is the list of "<device>:<mountPath>" separated by space
+for item in ${DEVICES}
+do
+    device=${item%%:*}
+    mount_path=${item#*:}
+
+    # the device is prepared already if it's mounted to the path
+    source=$(host findmnt -n -o SOURCE --mountpoint ${mount_path})
+    if [[ $source == $device ]]; then
+        continue
+    elif [[ -n $source ]]; then
+        echo "${mount_path} is mounted by ${source} rather than ${device}"
+        exit 1
+    elif [[ -n $(host findmnt -n -o TARGET --source ${device}) ]]; then
+        echo "${device} is mounted to other path"
+        exit 1
+    fi
+
+    # the device is only formatted if it has no filesystem to not lose data of a prepared device
+    if [[ -z $(host blkid -o value -s TYPE ${device}) ]]; then
+        host mkfs.ext4 -F ${device} || exit 1
+    fi
+    host mkdir -p ${mount_path}
+    host mount ${device} ${mount_path} || exit 1
+
+    # the device is mounted again after host reboots
+    uuid=$(host blkid -o value -s UUID ${device})
+    if ! host grep -q "UUID=${uuid} " /etc/fstab; then
+        echo "UUID=${uuid} ${mount_path} ext4 defaults 0 0" | host tee -a /etc/fstab > /dev/null
+    fi
+done
+`
+
+var format_chunkfile_pool string = `
+#!/usr/bin/env bash
+
+# the chunkfilepool has been preallocated if its meta file exists
+if [[ -f ${CHUNKFILE_POOL_META_PATH} ]]; then
+    echo "FORMAT_PROGRESS=100"
+    exit 0
+fi
+
+mkdir -p ${CHUNKFILE_POOL_DIR}
+${FORMAT_BINARY_PATH} \
+    -allocatePercent=${ALLOCATE_PERCENT} \
+    -fileSize=${CHUNKFILE_SIZE} \
+    -filePoolDir=${CHUNKFILE_POOL_DIR} \
+    -filePoolMetaPath=${CHUNKFILE_POOL_META_PATH} \
+    -fileSystemPath=${CHUNKFILE_POOL_DIR} &
+pid=$!
+
+# the progress is reported by the space used in the filesystem, it's read from the logs by operator
+while kill -0 ${pid} 2> /dev/null
+do
+    read size used <<< $(df -k --output=size,used ${CHUNKFILE_POOL_DIR} | tail -n 1)
+    progress=$(( used * 100 * 100 / (size * ALLOCATE_PERCENT) ))
+    echo "FORMAT_PROGRESS=$(( progress > 99 ? 99 : progress ))"
+    sleep ${PROGRESS_INTERVAL}
+done
+
+wait ${pid} || exit 1
+echo "FORMAT_PROGRESS=100"
+`
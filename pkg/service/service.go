@@ -1,14 +1,17 @@
 package service
 
 import (
+	"context"
 	"fmt"
 	"strings"
 
 	"github.com/coreos/pkg/capnslog"
 	appsv1 "k8s.io/api/apps/v1"
 	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
+	curvev1 "github.com/opencurve/curve-operator/api/v1"
 	"github.com/opencurve/curve-operator/pkg/clusterd"
 	"github.com/opencurve/curve-operator/pkg/k8sutil"
 	"github.com/opencurve/curve-operator/pkg/topology"
@@ -16,44 +19,59 @@ import (
 
 var logger = capnslog.NewPackageLogger("github.com/opencurve/curve-operator", "service")
 
-// createService create specified service according to specified dc object
-// for example etcd, mds
-func StartService(cluster clusterd.Clusterer, dc *topology.DeployConfig) error {
+// NODE_ANNOTATION is the annotation of the Deployment of a service that records the node it's pinned on
+const NODE_ANNOTATION = "operator.curve.io/node"
+
+// StartService create or update specified service according to specified dc object
+// for example etcd, mds, the caller should check the readiness of the returned Deployment
+func StartService(cluster clusterd.Clusterer, dc *topology.DeployConfig) (*appsv1.Deployment, error) {
 	return makeServiceDeployment(cluster, dc)
 }
 
-// createServiceDeployment create service Deployment and wait it to start according to specified dc object
-func makeServiceDeployment(cluster clusterd.Clusterer, dc *topology.DeployConfig) error {
+// makeServiceDeployment create or update service Deployment according to specified dc object
+func makeServiceDeployment(cluster clusterd.Clusterer, dc *topology.DeployConfig) (*appsv1.Deployment, error) {
 	layout := dc.GetProjectLayout()
 	vols, volMounts := getServiceHostPathVolumeAndMount(dc)
 
 	// resolve configmap volume and volumeMount
 	for _, conf := range layout.ServiceConfFiles {
-		vm, vms := getServiceConfigMapVolumeAndMount(fmt.Sprintf("%s_%s", dc.GetName(), conf.Name),
+		vm, vms := getServiceConfigMapVolumeAndMount(cluster, fmt.Sprintf("%s_%s", dc.GetName(), conf.Name),
 			layout.ServiceConfDir)
 		vols = append(vols, vm)
 		volMounts = append(volMounts, vms)
 	}
 
 	container := v1.Container{
-		Name: getResourceName(dc),
+		Name: getContainerName(dc),
 		Command: []string{
 			fmt.Sprintf("--role %s --args='%s'", dc.GetRole(), getArguments(dc)),
 		},
 		Image:           cluster.GetContainerImage(),
-		ImagePullPolicy: v1.PullIfNotPresent,
+		ImagePullPolicy: cluster.GetImagePullPolicy(),
 		VolumeMounts:    volMounts,
 		Ports:           getContainerPorts(dc),
+		Resources:       GetServiceResources(cluster, dc),
 		Env: []v1.EnvVar{
 			{Name: "TZ", Value: "Asia/Hangzhou"},
 			{Name: "'LD_PRELOAD=%s'", Value: "/usr/local/lib/libjemalloc.so"},
 		},
 	}
 
+	// the service is restarted once the credentials in its config are changed
+	secretHash, err := GetSecretHash(cluster, dc)
+	if err != nil {
+		return nil, err
+	}
+	annotations := map[string]string{}
+	if len(secretHash) > 0 {
+		annotations[SECRET_HASH_ANNOTATION] = secretHash
+	}
+
 	podSpec := v1.PodTemplateSpec{
 		ObjectMeta: metav1.ObjectMeta{
-			Name:   getResourceName(dc),
-			Labels: getServiceLabel(dc),
+			Name:        getResourceName(cluster, dc),
+			Labels:      k8sutil.ClusterLabels(cluster, getServiceLabel(dc)),
+			Annotations: annotations,
 		},
 		Spec: v1.PodSpec{
 			InitContainers: []v1.Container{
@@ -64,24 +82,29 @@ func makeServiceDeployment(cluster clusterd.Clusterer, dc *topology.DeployConfig
 				// logrotate.MakeLogrotateContainer(),
 				container,
 			},
-			NodeName:      dc.GetHost(),
 			RestartPolicy: getRestartPolicy(dc),
 			HostNetwork:   true,
 			DNSPolicy:     v1.DNSClusterFirstWithHostNet,
 			Volumes:       vols,
 		},
 	}
+	// the service is pinned on its host by the scheduler rather than NodeName so that the placement is respected
+	err = ApplyPlacement(cluster, &podSpec.Spec, dc.GetHost(), GetServicePlacement(cluster, dc))
+	if err != nil {
+		return nil, err
+	}
 
 	replicas := int32(1)
 	d := &appsv1.Deployment{
 		ObjectMeta: metav1.ObjectMeta{
-			Name:      getResourceName(dc),
-			Namespace: cluster.GetNameSpace(),
-			Labels:    getServiceLabel(dc),
+			Name:        getResourceName(cluster, dc),
+			Namespace:   cluster.GetNameSpace(),
+			Labels:      k8sutil.ClusterLabels(cluster, getServiceLabel(dc)),
+			Annotations: map[string]string{NODE_ANNOTATION: dc.GetHost()},
 		},
 		Spec: appsv1.DeploymentSpec{
 			Selector: &metav1.LabelSelector{
-				MatchLabels: getServiceLabel(dc),
+				MatchLabels: k8sutil.ClusterSelectorLabels(cluster, getServiceLabel(dc)),
 			},
 			Template: podSpec,
 			Replicas: &replicas,
@@ -92,23 +115,32 @@ func makeServiceDeployment(cluster clusterd.Clusterer, dc *topology.DeployConfig
 	}
 
 	// set ownerReference
-	err := cluster.GetOwnerInfo().SetControllerReference(d)
+	err = cluster.GetOwnerInfo().SetControllerReference(d)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	_, err = k8sutil.CreateOrUpdateDeploymentAndWaitStart(cluster.GetContext().Clientset, d)
+	newDeploy, err := k8sutil.CreateOrUpdateDeployment(cluster.GetContext().Clientset, d)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	logger.Infof("Create %s service Deployment in namespace %s successed", dc.GetName(), cluster.GetNameSpace())
+	if newDeploy.Generation > newDeploy.Status.ObservedGeneration {
+		logger.Infof("Apply %s service Deployment in namespace %s successed", dc.GetName(), cluster.GetNameSpace())
+		k8sutil.RecordEvent(cluster, v1.EventTypeNormal, k8sutil.ReasonServiceStarted,
+			"Deployment %q of %s service is started on node %q", d.GetName(), dc.GetRole(), dc.GetHost())
+	}
 
-	return nil
+	return newDeploy, nil
 }
 
 // getResourceName get the name of k8s curve resource
-func getResourceName(dc *topology.DeployConfig) string {
+func getResourceName(cluster clusterd.Clusterer, dc *topology.DeployConfig) string {
+	return clusterd.ResourceName(cluster, dc.GetName())
+}
+
+// getContainerName get the name of service container
+func getContainerName(dc *topology.DeployConfig) string {
 	return fmt.Sprintf("%s-%s", "curve", dc.GetName())
 }
 
@@ -166,6 +198,120 @@ func getArguments(dc *topology.DeployConfig) string {
 	return strings.Join(arguments, " ")
 }
 
+// GetServiceResources returns the compute resources of the service that are specified in the spec of its role
+func GetServiceResources(cluster clusterd.Clusterer, dc *topology.DeployConfig) v1.ResourceRequirements {
+	switch dc.GetRole() {
+	case topology.ROLE_ETCD:
+		return cluster.GetEtcdSpec().Resources
+	case topology.ROLE_MDS:
+		return cluster.GetMdsSpec().Resources
+	case topology.ROLE_CHUNKSERVER:
+		return cluster.GetChunkserverSpec().Resources
+	case topology.ROLE_SNAPSHOTCLONE:
+		return cluster.GetSnapShotSpec().Resources
+	case topology.ROLE_METASERVER:
+		return cluster.GetMetaserverSpec().Resources
+	}
+	return v1.ResourceRequirements{}
+}
+
+// GetServicePlacement returns the placement of the service that is specified in the spec of its role
+func GetServicePlacement(cluster clusterd.Clusterer, dc *topology.DeployConfig) curvev1.PlacementSpec {
+	switch dc.GetRole() {
+	case topology.ROLE_ETCD:
+		return cluster.GetEtcdSpec().Placement
+	case topology.ROLE_MDS:
+		return cluster.GetMdsSpec().Placement
+	case topology.ROLE_CHUNKSERVER:
+		return cluster.GetChunkserverSpec().Placement
+	case topology.ROLE_SNAPSHOTCLONE:
+		return cluster.GetSnapShotSpec().Placement
+	case topology.ROLE_METASERVER:
+		return cluster.GetMetaserverSpec().Placement
+	}
+	return curvev1.PlacementSpec{}
+}
+
+// makeServiceAffinity returns the node affinity that pins the service on the node of hostname, the hostname
+// is ANDed with every required term of the node affinity of placement and the preferred terms are kept.
+func makeServiceAffinity(hostname string, nodeAffinity *v1.NodeAffinity) *v1.Affinity {
+	hostRequirement := v1.NodeSelectorRequirement{
+		Key:      v1.LabelHostname,
+		Operator: v1.NodeSelectorOpIn,
+		Values:   []string{hostname},
+	}
+	affinity := &v1.NodeAffinity{}
+	terms := []v1.NodeSelectorTerm{}
+	if nodeAffinity != nil {
+		affinity.PreferredDuringSchedulingIgnoredDuringExecution = nodeAffinity.PreferredDuringSchedulingIgnoredDuringExecution
+		if required := nodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution; required != nil {
+			for _, term := range required.NodeSelectorTerms {
+				term = *term.DeepCopy()
+				term.MatchExpressions = append(term.MatchExpressions, hostRequirement)
+				terms = append(terms, term)
+			}
+		}
+	}
+	if len(terms) == 0 {
+		terms = append(terms, v1.NodeSelectorTerm{MatchExpressions: []v1.NodeSelectorRequirement{hostRequirement}})
+	}
+	affinity.RequiredDuringSchedulingIgnoredDuringExecution = &v1.NodeSelector{NodeSelectorTerms: terms}
+	return &v1.Affinity{NodeAffinity: affinity}
+}
+
+// ApplyPlacement pins the pod on node by the node affinity and applies the tolerations and the priority
+// class of placement, it's used by the services and the helper workloads that run on their nodes.
+func ApplyPlacement(cluster clusterd.Clusterer, podSpec *v1.PodSpec, node string, placement curvev1.PlacementSpec) error {
+	hostname, err := k8sutil.GetNodeHostName(context.TODO(), cluster.GetContext().Clientset, node)
+	if err != nil {
+		return err
+	}
+	podSpec.Affinity = makeServiceAffinity(hostname, placement.NodeAffinity)
+	podSpec.Tolerations = placement.Tolerations
+	podSpec.PriorityClassName = placement.PriorityClassName
+	return nil
+}
+
+// GetServiceNode returns the node that the service of the Deployment is pinned on, the Deployments that
+// are created before the placement is supported are pinned by NodeName.
+func GetServiceNode(d *appsv1.Deployment) string {
+	if node, ok := d.Annotations[NODE_ANNOTATION]; ok {
+		return node
+	}
+	return d.Spec.Template.Spec.NodeName
+}
+
+// IsServicePlacementChanged returns true if the placement of the Deployment differs from the one of the
+// service. The Deployments that are still pinned by NodeName are kept after the operator is upgraded,
+// they're restarted one zone at a time only once the placement is specified for their role.
+func IsServicePlacementChanged(cluster clusterd.Clusterer, dc *topology.DeployConfig, d *appsv1.Deployment) (bool, error) {
+	placement := GetServicePlacement(cluster, dc)
+	podSpec := d.Spec.Template.Spec
+	if len(podSpec.NodeName) > 0 {
+		return !equality.Semantic.DeepEqual(placement, curvev1.PlacementSpec{}), nil
+	}
+
+	hostname, err := k8sutil.GetNodeHostName(context.TODO(), cluster.GetContext().Clientset, dc.GetHost())
+	if err != nil {
+		return false, err
+	}
+	return !equality.Semantic.DeepEqual(podSpec.Affinity, makeServiceAffinity(hostname, placement.NodeAffinity)) ||
+		!equality.Semantic.DeepEqual(podSpec.Tolerations, placement.Tolerations) ||
+		podSpec.PriorityClassName != placement.PriorityClassName, nil
+}
+
+// applyJobResources sets the compute resources of the jobs in the spec of cluster to the containers of
+// the pod of a job, the resources that are already set on a container take precedence.
+func applyJobResources(cluster clusterd.Clusterer, podSpec *v1.PodSpec) {
+	resources := cluster.GetJobResources()
+	for i := range podSpec.InitContainers {
+		podSpec.InitContainers[i].Resources = clusterd.MergeResourceRequirements(podSpec.InitContainers[i].Resources, resources)
+	}
+	for i := range podSpec.Containers {
+		podSpec.Containers[i].Resources = clusterd.MergeResourceRequirements(podSpec.Containers[i].Resources, resources)
+	}
+}
+
 // getRestartPolicy chunkserver and metaserver never restart and others always start
 func getRestartPolicy(dc *topology.DeployConfig) v1.RestartPolicy {
 	switch dc.GetRole() {
@@ -223,6 +369,12 @@ func getContainerPorts(dc *topology.DeployConfig) []v1.ContainerPort {
 			int32(dc.GetListenDummyPort()),
 			int32(dc.GetListenDummyPort()),
 		))
+		// the port of nginx that proxies the requests to snapshotclone servers
+		ports = append(ports, newContainerPort(
+			topology.CONFIG_LISTEN_PROXY_PORT.Key(),
+			int32(dc.GetListenProxyPort()),
+			int32(dc.GetListenProxyPort()),
+		))
 	case topology.ROLE_METASERVER:
 		if dc.GetEnableExternalServer() {
 			ports = append(ports, newContainerPort(
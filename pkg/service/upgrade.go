@@ -0,0 +1,135 @@
+package service
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	batchv1 "k8s.io/api/batch/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/opencurve/curve-operator/pkg/clusterd"
+	"github.com/opencurve/curve-operator/pkg/k8sutil"
+	"github.com/opencurve/curve-operator/pkg/topology"
+)
+
+const (
+	CHECK_HEALTH_CONTAINER = "check-health"
+	CHECK_HEALTH_JOB_NAME  = "check-%s"
+
+	// UPGRADE_IMAGE_ANNOTATION is the annotation of the health check job that records the image to upgrade to
+	UPGRADE_IMAGE_ANNOTATION = "operator.curve.io/upgrade-image"
+
+	mdsStatusTimeout = 3 * time.Second
+)
+
+// mdsStatusVars is the bvar of mds that shows whether it's the leader
+var mdsStatusVars = map[string]string{
+	KIND_CURVEBS: "mds_status",
+	KIND_CURVEFS: "curvefs_mds_status",
+}
+
+// GetMdsLeader returns the mds service that is the leader by its status on the dummy port,
+// it returns nil if no leader is found.
+func GetMdsLeader(dcs []*topology.DeployConfig) *topology.DeployConfig {
+	client := &http.Client{Timeout: mdsStatusTimeout}
+	for _, dc := range topology.FilterDeployConfigByRole(dcs, topology.ROLE_MDS) {
+		url := fmt.Sprintf("http://%s:%d/vars/%s", dc.GetHostIp(), dc.GetListenDummyPort(), mdsStatusVars[dc.GetKind()])
+		resp, err := client.Get(url)
+		if err != nil {
+			logger.Warningf("failed to get the status of mds %s. %v", dc.GetName(), err)
+			continue
+		}
+		body, err := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err == nil && strings.Contains(string(body), "leader") {
+			return dc
+		}
+	}
+	return nil
+}
+
+// GetServiceZones returns the zone of chunkservers or metaservers in the cluster pool by their name
+func GetServiceZones(cluster clusterd.Clusterer, dcs []*topology.DeployConfig) (map[string]string, error) {
+	pool, err := getRegisteredClusterPool(cluster)
+	if err != nil {
+		return nil, err
+	}
+
+	serverZones := map[string]string{}
+	if pool != nil {
+		for _, server := range pool.Servers {
+			serverZones[server.Name] = server.Zone
+		}
+	}
+	zones := map[string]string{}
+	for _, dc := range dcs {
+		if isPoolServerRole(dc) {
+			zones[dc.GetName()] = serverZones[formatName(dc)]
+		}
+	}
+	return zones, nil
+}
+
+// StartJobCheckHealth create job to wait the copysets of cluster to be healthy after a step of upgrade,
+// the dc is used to get the tools config of cluster.
+func StartJobCheckHealth(cluster clusterd.Clusterer, dc *topology.DeployConfig, name string) (*batchv1.Job, error) {
+	checkCommand := "curve_ops_tool copysets-status"
+	if dc.GetKind() == KIND_CURVEFS {
+		checkCommand = fmt.Sprintf("%s status-copyset", dc.GetProjectLayout().ToolsBinaryPath)
+	}
+
+	vols, volMounts := getToolsAndTopoVolumeAndMount(cluster, dc)
+	container := v1.Container{
+		Name: CHECK_HEALTH_CONTAINER,
+		Command: []string{
+			"bash",
+			"-c",
+			check_cluster_health,
+		},
+		Image:           cluster.GetContainerImage(),
+		ImagePullPolicy: cluster.GetImagePullPolicy(),
+		VolumeMounts:    volMounts,
+		Env: []v1.EnvVar{
+			{Name: "CHECK_HEALTH_COMMAND", Value: checkCommand},
+		},
+	}
+
+	labels := k8sutil.ClusterLabels(cluster, map[string]string{"app": CHECK_HEALTH_CONTAINER})
+	job := &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      clusterd.ResourceName(cluster, fmt.Sprintf(CHECK_HEALTH_JOB_NAME, name)),
+			Namespace: cluster.GetNameSpace(),
+			Labels:    labels,
+			// the job of a previous upgrade is replaced
+			Annotations: map[string]string{UPGRADE_IMAGE_ANNOTATION: cluster.GetContainerImage()},
+		},
+		Spec: batchv1.JobSpec{
+			Template: v1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: labels,
+				},
+				Spec: v1.PodSpec{
+					Containers: []v1.Container{
+						container,
+					},
+					RestartPolicy: v1.RestartPolicyNever,
+					HostNetwork:   true,
+					DNSPolicy:     v1.DNSClusterFirstWithHostNet,
+					Volumes:       vols,
+				},
+			},
+		},
+	}
+	applyJobResources(cluster, &job.Spec.Template.Spec)
+
+	err := cluster.GetOwnerInfo().SetControllerReference(job)
+	if err != nil {
+		return nil, err
+	}
+
+	return k8sutil.CreateOrReplaceJob(cluster.GetContext().Clientset, job, UPGRADE_IMAGE_ANNOTATION)
+}
@@ -1,18 +1,22 @@
 package service
 
 import (
+	"fmt"
 	"path"
 	"strings"
 
 	v1 "k8s.io/api/core/v1"
 
+	"github.com/opencurve/curve-operator/pkg/clusterd"
 	"github.com/opencurve/curve-operator/pkg/topology"
 	"github.com/opencurve/curve-operator/pkg/utils"
 )
 
 const (
-	DATA_VOLUME = "data-volume"
-	LOG_VOLUME  = "log-volume"
+	DATA_VOLUME       = "data-volume"
+	LOG_VOLUME        = "log-volume"
+	TOOLS_CONF_VOLUME = "tools-conf"
+	TOPOLOGY_VOLUME   = "cluster-pool"
 )
 
 // A DataPathMap is a struct which contains information about where Curve service data is stored in
@@ -34,6 +38,10 @@ type DataPathMap struct {
 	// ContainerDataDir should be set to the path in the container
 	// where the specific service's log is stored.
 	ContainerLogDir string
+
+	// HostDataOnDevice is true if HostDataDir is the mount path of a device on the host,
+	// the directory must be created by mounting the device rather than by the service.
+	HostDataOnDevice bool
 }
 
 // NewServiceDataPathMap returns a new DataPathMap for a service which does not utilize a data
@@ -56,12 +64,21 @@ func getServiceHostPathVolumeAndMount(dc *topology.DeployConfig) ([]v1.Volume, [
 		ContainerDataDir: layout.ServiceDataDir,
 		ContainerLogDir:  layout.ServiceLogDir,
 	}
+	// the chunkserver stores data in the device prepared by the disk-prepare job
+	if device := dc.GetDevice(); device != nil {
+		dataPaths.HostDataDir = device.MountPath
+		dataPaths.HostDataOnDevice = true
+	}
 
 	// create Data hostpath volume and log hostpath volume
 	vols, mounts := []v1.Volume{}, []v1.VolumeMount{}
 	hostPathType := v1.HostPathDirectoryOrCreate
 	if dataPaths != nil && dataPaths.HostDataDir != "" {
-		src := v1.VolumeSource{HostPath: &v1.HostPathVolumeSource{Path: dataPaths.HostDataDir, Type: &hostPathType}}
+		dataPathType := hostPathType
+		if dataPaths.HostDataOnDevice {
+			dataPathType = v1.HostPathDirectory
+		}
+		src := v1.VolumeSource{HostPath: &v1.HostPathVolumeSource{Path: dataPaths.HostDataDir, Type: &dataPathType}}
 		vols = append(vols, v1.Volume{Name: DATA_VOLUME, VolumeSource: src})
 	}
 
@@ -83,16 +100,17 @@ func getServiceHostPathVolumeAndMount(dc *topology.DeployConfig) ([]v1.Volume, [
 }
 
 // getVolumeAndMount Create configmap volume and volumeMount for specified key
-func getServiceConfigMapVolumeAndMount(dataKey, mountDir string) (v1.Volume, v1.VolumeMount) {
+func getServiceConfigMapVolumeAndMount(cluster clusterd.Clusterer, dataKey, mountDir string) (v1.Volume, v1.VolumeMount) {
 	configMapVolSource := &v1.ConfigMapVolumeSource{}
 	mode := int32(0644)
 	subPath := strings.Split(dataKey, "_")[1]
 	configMapVolSource = &v1.ConfigMapVolumeSource{
-		LocalObjectReference: v1.LocalObjectReference{Name: utils.AFTER_MUTATE_CONF},
+		LocalObjectReference: v1.LocalObjectReference{Name: clusterd.ResourceName(cluster, utils.AFTER_MUTATE_CONF)},
 		Items:                []v1.KeyToPath{{Key: dataKey, Path: subPath, Mode: &mode}},
 	}
 
-	volumeName := dataKey
+	// the volume name must be a DNS label, e.g. 'etcd00_etcd.conf' -> 'etcd00-etcd-conf'
+	volumeName := strings.NewReplacer("_", "-", ".", "-").Replace(dataKey)
 	vol := v1.Volume{
 		Name: volumeName,
 		VolumeSource: v1.VolumeSource{
@@ -111,32 +129,32 @@ func getServiceConfigMapVolumeAndMount(dataKey, mountDir string) (v1.Volume, v1.
 }
 
 // getToolsAndTopoVolumeAndMount for create-pool job using
-func getToolsAndTopoVolumeAndMount(dc *topology.DeployConfig) ([]v1.Volume, []v1.VolumeMount) {
+func getToolsAndTopoVolumeAndMount(cluster clusterd.Clusterer, dc *topology.DeployConfig) ([]v1.Volume, []v1.VolumeMount) {
 	vols, volMounts := []v1.Volume{}, []v1.VolumeMount{}
 	mode := int32(0644)
 	subPath := topology.LAYOUT_TOOLS_NAME
 
 	toolVolSource := &v1.ConfigMapVolumeSource{
 		LocalObjectReference: v1.LocalObjectReference{
-			Name: utils.AFTER_MUTATE_CONF,
+			Name: clusterd.ResourceName(cluster, utils.AFTER_MUTATE_CONF),
 		},
 		Items: []v1.KeyToPath{
 			{
-				Key:  topology.LAYOUT_TOOLS_NAME,
+				Key:  fmt.Sprintf("%s_%s", dc.GetName(), topology.LAYOUT_TOOLS_NAME),
 				Path: subPath,
 				Mode: &mode,
 			},
 		},
 	}
 	toolConfigVol := v1.Volume{
-		Name: utils.AFTER_MUTATE_CONF,
+		Name: TOOLS_CONF_VOLUME,
 		VolumeSource: v1.VolumeSource{
 			ConfigMap: toolVolSource,
 		},
 	}
 
 	toolVolMount := v1.VolumeMount{
-		Name:      utils.AFTER_MUTATE_CONF,
+		Name:      TOOLS_CONF_VOLUME,
 		ReadOnly:  true, // should be no reason to write to the config in pods, so enforce this
 		MountPath: dc.GetProjectLayout().ToolsConfSystemPath,
 		SubPath:   subPath,
@@ -146,17 +164,17 @@ func getToolsAndTopoVolumeAndMount(dc *topology.DeployConfig) ([]v1.Volume, []v1
 
 	topoVolSource := &v1.ConfigMapVolumeSource{
 		LocalObjectReference: v1.LocalObjectReference{
-			Name: CURVE_TOPOLOGY_CONFIGMAP,
+			Name: clusterd.ResourceName(cluster, CURVE_TOPOLOGY_CONFIGMAP),
 		},
 	}
 	topoVol := v1.Volume{
-		Name: CURVE_TOPOLOGY_CONFIGMAP,
+		Name: TOPOLOGY_VOLUME,
 		VolumeSource: v1.VolumeSource{
 			ConfigMap: topoVolSource,
 		},
 	}
 	topoVolMount := v1.VolumeMount{
-		Name:      CURVE_TOPOLOGY_CONFIGMAP,
+		Name:      TOPOLOGY_VOLUME,
 		ReadOnly:  true,
 		MountPath: dc.GetProjectLayout().ToolsConfDir,
 	}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"path"
 
+	curvev1 "github.com/opencurve/curve-operator/api/v1"
 	"github.com/opencurve/curve-operator/pkg/utils"
 )
 
@@ -27,10 +28,14 @@ const (
 	LAYOUT_CURVE_TOOLS_V2_CONFIG_SYSTEM_PATH = "/etc/curve/curve.yaml"
 	LAYOUT_CORE_SYSTEM_DIR                   = "/core"
 	LAYOUT_TOOLS_NAME                        = "tools.conf"
+	LAYOUT_CLIENT_NAME                       = "client.conf"
+	LAYOUT_CURVEFS_CLIENT_DIR                = "/curvefs/client"
+	LAYOUT_CLIENT_MOUNT_DIR                  = "/mnt"
 
 	BINARY_CURVEBS_TOOL     = "curvebs-tool"
 	BINARY_CURVEBS_FORMAT   = "curve_format"
 	BINARY_CURVEFS_TOOL     = "curvefs_tool"
+	BINARY_CURVEFS_FUSE     = "curve-fuse"
 	BINARY_CURVE_TOOL_V2    = "curve"
 	METAFILE_CHUNKFILE_POOL = "chunkfilepool.meta"
 	METAFILE_CHUNKSERVER_ID = "chunkserver.dat"
@@ -65,6 +70,7 @@ type DeployConfig struct {
 	hostSequence      int
 	instances         int // replicas number
 	instancesSequence int
+	device            *curvev1.DeviceSpec // the device to store data, nil if data is stored in dataDir
 
 	variables     *Variables
 	config        map[string]string
@@ -92,7 +98,8 @@ func (dc *DeployConfig) getInt(i *item) int {
 	if v == nil {
 		return 0
 	}
-	return v.(int)
+	intv, _ := utils.Str2Int(utils.Atoa(v))
+	return intv
 }
 
 func (dc *DeployConfig) getBool(i *item) bool {
@@ -100,7 +107,8 @@ func (dc *DeployConfig) getBool(i *item) bool {
 	if v == nil {
 		return false
 	}
-	return v.(bool)
+	boolv, _ := utils.Str2Bool(utils.Atoa(v))
+	return boolv
 }
 
 // 1. config property
@@ -114,6 +122,7 @@ func (dc *DeployConfig) GetHostIp() string                   { return dc.hostIp
 func (dc *DeployConfig) GetInstances() int                   { return dc.instances }
 func (dc *DeployConfig) GetHostSequence() int                { return dc.hostSequence }
 func (dc *DeployConfig) GetInstancesSequence() int           { return dc.instancesSequence }
+func (dc *DeployConfig) GetDevice() *curvev1.DeviceSpec      { return dc.device }
 func (dc *DeployConfig) GetVariables() *Variables            { return dc.variables }
 func (dc *DeployConfig) GetConfig() map[string]string        { return dc.config }
 func (dc *DeployConfig) GetServiceConfig() map[string]string { return dc.serviceConfig }
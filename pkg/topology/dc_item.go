@@ -215,6 +215,20 @@ var (
 		false,
 		nil,
 	)
+
+	CONFIG_S3_ENDPOINT = itemset.insert(
+		"s3.endpoint",
+		REQUIRE_STRING,
+		false,
+		nil,
+	)
+
+	CONFIG_S3_FS_BUCKET_NAME = itemset.insert(
+		"s3.bucket_name",
+		REQUIRE_STRING,
+		false,
+		nil,
+	)
 )
 
 func (i *item) Key() string {
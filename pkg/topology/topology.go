@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"strconv"
 
+	curvev1 "github.com/opencurve/curve-operator/api/v1"
 	"github.com/opencurve/curve-operator/pkg/clusterd"
 	"github.com/opencurve/curve-operator/pkg/k8sutil"
 	"github.com/opencurve/curve-operator/pkg/utils"
@@ -25,8 +26,13 @@ func ParseTopology(cluster clusterd.Clusterer) ([]*DeployConfig, error) {
 
 	dcs := []*DeployConfig{}
 	for _, role := range roles {
-		for hostSequence, host := range cluster.GetNodes() {
+		for hostSequence, host := range getRoleNodes(cluster, role) {
 			instances := cluster.GetRoleInstances(role)
+			// one chunkserver is deployed on each device of the node
+			devices := getNodeDevices(cluster, role, hostSequence)
+			if len(devices) > 0 {
+				instances = len(devices)
+			}
 			// deploy etcd || mds || snapshotclone service using first three nodes
 			if role == ROLE_ETCD || role == ROLE_MDS || role == ROLE_SNAPSHOTCLONE {
 				if hostSequence > 2 {
@@ -38,15 +44,26 @@ func ParseTopology(cluster clusterd.Clusterer) ([]*DeployConfig, error) {
 				return nil, err
 			}
 			for instancesSequence := 0; instancesSequence < instances; instancesSequence++ {
-				config := cluster.GetRoleConfigs(role)
+				// copy the configs of role, it's shared by all services of the role
+				config := map[string]string{}
+				for k, v := range cluster.GetRoleConfigs(role) {
+					config[k] = v
+				}
 				// merge port config and global config to configs of each service
 				mergePortConfig(cluster, role, instancesSequence, config)
 				mergeGlobalConfig(cluster, role, instancesSequence, config)
+				if len(devices) > 0 {
+					// the chunkserver stores data in the device mounted on host
+					config[CONFIG_DATA_DIR.key] = trimString(devices[instancesSequence].MountPath)
+				}
 				dc, err := NewDeployConfig(kind, role, host, hostIp, instances,
 					instancesSequence, hostSequence, config)
 				if err != nil {
 					return nil, err
 				}
+				if len(devices) > 0 {
+					dc.device = &devices[instancesSequence]
+				}
 				dcs = append(dcs, dc)
 			}
 		}
@@ -109,6 +126,9 @@ func mergePortConfig(cluster clusterd.Clusterer, role string,
 	if isEmptyString(configs[CONFIG_LISTEN_EXTERNAL_PORT.key]) {
 		configs[CONFIG_LISTEN_EXTERNAL_PORT.key] = strconv.Itoa(cluster.GetRoleExternalPort(role) + instanceSequence)
 	}
+	if isEmptyString(configs[CONFIG_LISTEN_PROXY_PORT.key]) {
+		configs[CONFIG_LISTEN_PROXY_PORT.key] = strconv.Itoa(cluster.GetRoleProxyPort(role) + instanceSequence)
+	}
 }
 
 // mergeGlobalConfig handle global config, such as
@@ -124,8 +144,8 @@ func mergeGlobalConfig(cluster clusterd.Clusterer, role string,
 	if isEmptyString(configs[CONFIG_DATA_DIR.key]) {
 		configs[CONFIG_DATA_DIR.key] = fmt.Sprint(trimString(cluster.GetDataDir()), "/", role, instanceSequence)
 	} else {
-		dataDir := configs[CONFIG_LOG_DIR.key]
-		configs[CONFIG_LOG_DIR.key] = fmt.Sprint(trimString(dataDir), "/", role, instanceSequence)
+		dataDir := configs[CONFIG_DATA_DIR.key]
+		configs[CONFIG_DATA_DIR.key] = fmt.Sprint(trimString(dataDir), "/", role, instanceSequence)
 	}
 	if isEmptyString(configs[CONFIG_LOG_DIR.key]) {
 		configs[CONFIG_LOG_DIR.key] = fmt.Sprint(trimString(cluster.GetLogDir()), "/", role, instanceSequence)
@@ -134,3 +154,31 @@ func mergeGlobalConfig(cluster clusterd.Clusterer, role string,
 		configs[CONFIG_LOG_DIR.key] = fmt.Sprint(trimString(logDir), "/", role, instanceSequence)
 	}
 }
+
+// getRoleNodes returns the nodes to deploy the services of role, chunkservers are deployed
+// on the selected nodes if useSelectedNodes is true.
+func getRoleNodes(cluster clusterd.Clusterer, role string) []string {
+	chunkserver := cluster.GetChunkserverSpec()
+	if role != ROLE_CHUNKSERVER || chunkserver == nil || !chunkserver.UseSelectedNodes {
+		return cluster.GetNodes()
+	}
+
+	nodes := []string{}
+	for _, selected := range chunkserver.SelectedNodes {
+		nodes = append(nodes, selected.Node)
+	}
+	return nodes
+}
+
+// getNodeDevices returns the devices that the services of role on the node store data in,
+// only chunkservers use devices.
+func getNodeDevices(cluster clusterd.Clusterer, role string, hostSequence int) []curvev1.DeviceSpec {
+	chunkserver := cluster.GetChunkserverSpec()
+	if role != ROLE_CHUNKSERVER || chunkserver == nil {
+		return nil
+	}
+	if chunkserver.UseSelectedNodes {
+		return chunkserver.SelectedNodes[hostSequence].Devices
+	}
+	return chunkserver.Devices
+}
@@ -1,6 +1,8 @@
 package utils
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"reflect"
 	"strconv"
 )
@@ -80,3 +82,9 @@ func IsTrueStr(s string) bool {
 	v, yes := Str2Bool(s)
 	return yes && v == true
 }
+
+// Hash returns the hex encoded sha256 checksum of the string
+func Hash(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:])
+}